@@ -1,25 +1,168 @@
 package main
 
 import (
+	"crypto/tls"
+	"errors"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/app"
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
 )
 
 func main() {
-	app := app.NewApp()
-	l, err := net.Listen("tcp", "0.0.0.0:6379")
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalln("Failed to load config", err)
+	}
+
+	server := app.NewApp(cfg)
+	if err := server.LoadACLFile(); err != nil {
+		log.Fatalln("Failed to load ACL file", err)
+	}
+	listeners, err := listen(cfg)
 	if err != nil {
 		log.Fatalln("Failed to bind", err)
 	}
 
-	for {
-		conn, err := l.Accept()
+	for _, l := range listeners {
+		go func() {
+			if err := server.Serve(l); err != nil {
+				log.Println("Failed to serve", err)
+			}
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, scheduling shutdown...", sig)
+		if err := server.Shutdown(app.ShutdownOptions{}); err != nil {
+			log.Println("Failed to shutdown", err)
+		}
+
+		// a second signal does not wait for the clients anymore
+		sig = <-signals
+		log.Printf("Received %s again, exiting now", sig)
+		os.Exit(1)
+	}()
+
+	<-server.Done()
+	os.Exit(server.ExitCode())
+}
+
+// listen opens the listeners of the plain TCP port, of the TLS port and of
+// the unix socket, a port set to 0 is not listened on
+func listen(cfg *config.Config) (listeners []net.Listener, err error) {
+	defer func() {
 		if err != nil {
-			log.Fatalln("Failed to accept new connection", err)
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+		}
+	}()
+
+	if port := int(cfg.Int("port")); port != 0 {
+		tcpListeners, err := listenTCP(cfg.Strings("bind"), port)
+		if err != nil {
+			return listeners, err
+		}
+		listeners = append(listeners, tcpListeners...)
+	}
+
+	if port := int(cfg.Int("tls-port")); port != 0 {
+		tlsListeners, err := listenTLS(cfg, port)
+		if err != nil {
+			return listeners, err
+		}
+		listeners = append(listeners, tlsListeners...)
+	}
+
+	if path := cfg.String("unixsocket"); path != "" {
+		l, err := listenUnix(path, cfg.String("unixsocketperm"))
+		if err != nil {
+			return listeners, err
+		}
+		listeners = append(listeners, l)
+	}
+
+	if len(listeners) == 0 {
+		return nil, errors.New("port, tls-port and unixsocket are all disabled")
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on a unix socket, a file left at path by a previous run
+// is replaced. perm are octal permissions, 0 keeps the ones from the umask.
+func listenUnix(path, perm string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	mode, _ := strconv.ParseUint(perm, 8, 32)
+	if mode != 0 {
+		if err := os.Chmod(path, os.FileMode(mode)); err != nil {
+			_ = l.Close()
+			return nil, err
 		}
+	}
+	return l, nil
+}
 
-		go app.HandleConnection(conn)
+func listenTLS(cfg *config.Config, port int) ([]net.Listener, error) {
+	tlsConfig, err := app.NewTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	listeners, err := listenTCP(cfg.Strings("bind"), port)
+	if err != nil {
+		return nil, err
+	}
+	for idx, l := range listeners {
+		listeners[idx] = tls.NewListener(l, tlsConfig)
+	}
+	return listeners, nil
+}
+
+// listenTCP listens on every bind address like Redis: `*` is every IPv4
+// address, `::*` every IPv6 address, and addresses prefixed by `-` are skipped
+// when they are not available
+func listenTCP(addresses []string, port int) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, address := range addresses {
+		address, optional := strings.CutPrefix(address, "-")
+
+		network := "tcp4"
+		switch address {
+		case "*":
+			address = "0.0.0.0"
+		case "::*":
+			address = "::"
+		}
+		if strings.Contains(address, ":") {
+			network = "tcp6"
+		}
+
+		l, err := net.Listen(network, net.JoinHostPort(address, strconv.Itoa(port)))
+		if err != nil {
+			if optional {
+				continue
+			}
+			for _, opened := range listeners {
+				_ = opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
 	}
+	return listeners, nil
 }
@@ -0,0 +1,633 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+)
+
+// aclUser holds the credentials and permissions of an ACL user. Clients keep
+// a pointer to their user so ACL SETUSER changes apply to them right away.
+type aclUser struct {
+	name    string
+	enabled bool
+	// any password is accepted
+	nopass bool
+	// hex encoded SHA-256 of the passwords
+	passwords []string
+	// in the order they were set, the last one matching a command decides
+	commandRules []commandRule
+	keys         []keyPattern
+	channels     []string
+}
+
+type commandRule struct {
+	allow bool
+	// either a category or a command (`parent|subcommand` for a subcommand)
+	category string
+	command  string
+}
+
+func (r commandRule) String() string {
+	sign := "-"
+	if r.allow {
+		sign = "+"
+	}
+	if r.category != "" {
+		return sign + "@" + r.category
+	}
+	return sign + r.command
+}
+
+func (r commandRule) matches(spec *commandSpec) bool {
+	if r.category != "" {
+		return r.category == "all" || slices.Contains(spec.categories, r.category)
+	}
+	// a rule on a container command applies to all its subcommands
+	return r.command == spec.name || (spec.parent != nil && r.command == spec.parent.name)
+}
+
+type keyPattern struct {
+	pattern string
+	access  keyAccess
+}
+
+func (p keyPattern) String() string {
+	switch p.access {
+	case keyRead:
+		return "%R~" + p.pattern
+	case keyWrite:
+		return "%W~" + p.pattern
+	default:
+		return "~" + p.pattern
+	}
+}
+
+// newACLUser returns a user as created by ACL SETUSER: disabled and without
+// any permission
+func newACLUser(name string) *aclUser {
+	return &aclUser{
+		name:         name,
+		commandRules: []commandRule{{allow: false, category: "all"}},
+	}
+}
+
+// newDefaultACLUser returns the user new connections are authenticated as,
+// it can do everything
+func newDefaultACLUser() *aclUser {
+	return &aclUser{
+		name:         defaultUser,
+		enabled:      true,
+		nopass:       true,
+		commandRules: []commandRule{{allow: true, category: "all"}},
+		keys:         []keyPattern{{"*", keyRead | keyWrite}},
+		channels:     []string{"*"},
+	}
+}
+
+func (user *aclUser) clone() *aclUser {
+	cloned := *user
+	cloned.passwords = slices.Clone(user.passwords)
+	cloned.commandRules = slices.Clone(user.commandRules)
+	cloned.keys = slices.Clone(user.keys)
+	cloned.channels = slices.Clone(user.channels)
+	return &cloned
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (user *aclUser) checkPassword(password string) bool {
+	if !user.enabled {
+		return false
+	}
+	return user.nopass || slices.Contains(user.passwords, hashPassword(password))
+}
+
+// Errors of the ACL rules, same as Redis
+var (
+	errACLSyntax          = errors.New("Syntax error")
+	errACLUnknownCommand  = errors.New("Unknown command or category name in ACL")
+	errACLKeyAfterAll     = errors.New("Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns")
+	errACLChannelAfterAll = errors.New("Adding a pattern after the * pattern (or the 'allchannels' flag) is not valid and does not have any effect. Try 'resetchannels' to start with an empty list of channels")
+	errACLNoSuchPassword  = errors.New("The password you are trying to remove from the user does not exist")
+	errACLInvalidHash     = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	errACLSelectors       = errors.New("Selectors are not supported")
+)
+
+// setRules applies the rules of ACL SETUSER in order
+func (user *aclUser) setRules(rules []string) error {
+	for _, rule := range rules {
+		if err := user.setRule(rule); err != nil {
+			return fmt.Errorf("Error in ACL SETUSER modifier '%s': %w", rule, err)
+		}
+	}
+	return nil
+}
+
+func (user *aclUser) setRule(rule string) error {
+	switch strings.ToLower(rule) {
+	case "on":
+		user.enabled = true
+		return nil
+	case "off":
+		user.enabled = false
+		return nil
+	case "nopass":
+		user.nopass, user.passwords = true, nil
+		return nil
+	case "resetpass":
+		user.nopass, user.passwords = false, nil
+		return nil
+	case "allkeys":
+		user.keys = []keyPattern{{"*", keyRead | keyWrite}}
+		return nil
+	case "resetkeys":
+		user.keys = nil
+		return nil
+	case "allchannels":
+		user.channels = []string{"*"}
+		return nil
+	case "resetchannels":
+		user.channels = nil
+		return nil
+	case "allcommands":
+		return user.setRule("+@all")
+	case "nocommands":
+		return user.setRule("-@all")
+	case "reset":
+		*user = *newACLUser(user.name)
+		return nil
+	case "sanitize-payload", "skip-sanitize-payload":
+		// there is no RESTORE to sanitize the payload of
+		return nil
+	}
+
+	if rule == "" {
+		return errACLSyntax
+	}
+	switch value := rule[1:]; rule[0] {
+	case '>':
+		if hash := hashPassword(value); !slices.Contains(user.passwords, hash) {
+			user.passwords = append(user.passwords, hash)
+		}
+		user.nopass = false
+	case '#':
+		if len(value) != sha256.Size*2 || strings.Trim(value, "0123456789abcdef") != "" {
+			return errACLInvalidHash
+		}
+		if !slices.Contains(user.passwords, value) {
+			user.passwords = append(user.passwords, value)
+		}
+		user.nopass = false
+	case '<':
+		return user.removePassword(hashPassword(value))
+	case '!':
+		return user.removePassword(value)
+	case '~', '%':
+		return user.addKeyPattern(rule)
+	case '&':
+		if value == "*" {
+			user.channels = []string{"*"}
+		} else if slices.Contains(user.channels, "*") {
+			return errACLChannelAfterAll
+		} else if !slices.Contains(user.channels, value) {
+			user.channels = append(user.channels, value)
+		}
+	case '+', '-':
+		return user.addCommandRule(rule[0] == '+', strings.ToLower(value))
+	case '(':
+		return errACLSelectors
+	default:
+		return errACLSyntax
+	}
+	return nil
+}
+
+func (user *aclUser) removePassword(hash string) error {
+	idx := slices.Index(user.passwords, hash)
+	if idx == -1 {
+		return errACLNoSuchPassword
+	}
+	user.passwords = slices.Delete(user.passwords, idx, idx+1)
+	return nil
+}
+
+// addKeyPattern handles `~pattern` and `%<R|W|RW>~pattern`
+func (user *aclUser) addKeyPattern(rule string) error {
+	access := keyRead | keyWrite
+	if flags, found := strings.CutPrefix(rule, "%"); found {
+		flags, _, found = strings.Cut(flags, "~")
+		if !found || flags == "" {
+			return errACLSyntax
+		}
+		access = 0
+		for _, flag := range strings.ToUpper(flags) {
+			switch flag {
+			case 'R':
+				access |= keyRead
+			case 'W':
+				access |= keyWrite
+			default:
+				return errACLSyntax
+			}
+		}
+	}
+	_, pattern, _ := strings.Cut(rule, "~")
+
+	if pattern == "*" && access == keyRead|keyWrite {
+		user.keys = []keyPattern{{"*", access}}
+		return nil
+	}
+	if slices.Contains(user.keys, keyPattern{"*", keyRead | keyWrite}) {
+		return errACLKeyAfterAll
+	}
+	if p := (keyPattern{pattern, access}); !slices.Contains(user.keys, p) {
+		user.keys = append(user.keys, p)
+	}
+	return nil
+}
+
+func (user *aclUser) addCommandRule(allow bool, name string) error {
+	rule := commandRule{allow: allow}
+	if category, found := strings.CutPrefix(name, "@"); found {
+		if category != "all" && !slices.Contains(aclCategories, category) {
+			return errACLUnknownCommand
+		}
+		rule.category = category
+	} else {
+		parent, sub, isSub := strings.Cut(name, "|")
+		spec, exists := commandTable[parent]
+		if !exists {
+			return errACLUnknownCommand
+		}
+		if isSub {
+			if _, exists := spec.subcommands[sub]; !exists {
+				return errACLUnknownCommand
+			}
+		}
+		rule.command = name
+	}
+
+	// +@all and -@all override every previous rule
+	if rule.category == "all" {
+		user.commandRules = nil
+	}
+	user.commandRules = append(user.commandRules, rule)
+	return nil
+}
+
+func (user *aclUser) allowsCommand(spec *commandSpec) bool {
+	allowed := false
+	for _, rule := range user.commandRules {
+		if rule.matches(spec) {
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// allowsKey tells whether a pattern gives the access to key, any matching
+// pattern is enough when the command does not access the value
+func (user *aclUser) allowsKey(key string, access keyAccess) bool {
+	for _, p := range user.keys {
+		if p.access&access == access && glob.Match(p.pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (user *aclUser) allowsChannel(channel string) bool {
+	for _, pattern := range user.channels {
+		if glob.Match(pattern, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reasons of the ACL denials, as shown by ACL LOG
+const (
+	aclDeniedAuth    = "auth"
+	aclDeniedCommand = "command"
+	aclDeniedKey     = "key"
+	aclDeniedChannel = "channel"
+)
+
+// aclDenial is why a user cannot run a command, object is the command, key
+// or channel that is not allowed
+type aclDenial struct {
+	reason string
+	object string
+}
+
+// checkPermissions returns why the user cannot run a command, nil when it can
+func (user *aclUser) checkPermissions(spec *commandSpec, args []string) *aclDenial {
+	if spec.has(flagNoAuth) {
+		return nil
+	}
+	if !user.allowsCommand(spec) {
+		return &aclDenial{reason: aclDeniedCommand, object: spec.name}
+	}
+	for _, keySpec := range spec.keys {
+		for _, idx := range keySpec.indexes(args) {
+			if !user.allowsKey(args[idx], keySpec.access) {
+				return &aclDenial{reason: aclDeniedKey, object: args[idx]}
+			}
+		}
+	}
+	if spec.channels != nil {
+		for _, idx := range spec.channels.indexes(args) {
+			if !user.allowsChannel(args[idx]) {
+				return &aclDenial{reason: aclDeniedChannel, object: args[idx]}
+			}
+		}
+	}
+	return nil
+}
+
+// error is the reply to the denied command
+func (d *aclDenial) error(user *aclUser) error {
+	switch d.reason {
+	case aclDeniedKey:
+		return NewCodedError(ErrorCodeNoPerm, "No permissions to access a key")
+	case aclDeniedChannel:
+		return NewCodedError(ErrorCodeNoPerm, "No permissions to access a channel")
+	default:
+		return NewCodedError(ErrorCodeNoPerm, fmt.Sprintf("User %s has no permissions to run the '%s' command", user.name, d.object))
+	}
+}
+
+// description is the message of ACL DRYRUN
+func (d *aclDenial) description(user *aclUser) string {
+	switch d.reason {
+	case aclDeniedKey:
+		return fmt.Sprintf("User %s has no permissions to access the '%s' key", user.name, d.object)
+	case aclDeniedChannel:
+		return fmt.Sprintf("User %s has no permissions to access the '%s' channel", user.name, d.object)
+	default:
+		return fmt.Sprintf("User %s has no permissions to run the '%s' command", user.name, d.object)
+	}
+}
+
+func (user *aclUser) flags() []string {
+	flags := []string{"off"}
+	if user.enabled {
+		flags[0] = "on"
+	}
+	if user.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+func (user *aclUser) describeKeys() string {
+	patterns := make([]string, 0, len(user.keys))
+	for _, p := range user.keys {
+		patterns = append(patterns, p.String())
+	}
+	return strings.Join(patterns, " ")
+}
+
+func (user *aclUser) describeChannels() string {
+	patterns := make([]string, 0, len(user.channels))
+	for _, channel := range user.channels {
+		patterns = append(patterns, "&"+channel)
+	}
+	return strings.Join(patterns, " ")
+}
+
+func (user *aclUser) describeCommands() string {
+	rules := make([]string, 0, len(user.commandRules))
+	for _, rule := range user.commandRules {
+		rules = append(rules, rule.String())
+	}
+	return strings.Join(rules, " ")
+}
+
+// describe returns the rules recreating the user, the line of ACL LIST and of
+// the ACL file
+func (user *aclUser) describe() string {
+	parts := append([]string{"user", user.name}, user.flags()...)
+	for _, hash := range user.passwords {
+		parts = append(parts, "#"+hash)
+	}
+	if len(user.keys) != 0 {
+		parts = append(parts, user.describeKeys())
+	}
+	if len(user.channels) == 0 {
+		parts = append(parts, "resetchannels")
+	} else {
+		parts = append(parts, user.describeChannels())
+	}
+	parts = append(parts, user.describeCommands())
+	return strings.Join(parts, " ")
+}
+
+// sortedUsers returns the users ordered by name
+func (app *App) sortedUsers() []*aclUser {
+	users := make([]*aclUser, 0, len(app.users))
+	for _, user := range app.users {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b *aclUser) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return users
+}
+
+// applyRequirepass sets the password of the default user from requirepass,
+// an empty one lets anyone in
+func (app *App) applyRequirepass() {
+	user := app.users[defaultUser]
+	if password := app.config.String("requirepass"); password == "" {
+		_ = user.setRule("nopass")
+	} else {
+		_ = user.setRules([]string{"resetpass", ">" + password})
+	}
+}
+
+// authenticate switches client to username, failures are logged in ACL LOG
+func (app *App) authenticate(client *Client, username, password string) error {
+	user, exists := app.users[username]
+	if !exists || !user.checkPassword(password) {
+		app.stats.aclAccessDeniedAuth += 1
+		app.logACLDenial(client, username, aclDenial{reason: aclDeniedAuth, object: "AUTH"})
+		return NewCodedError(ErrorCodeWrongPass, "invalid username-password pair or user is disabled.")
+	}
+	client.user, client.authenticated = user, true
+	return nil
+}
+
+// checkACL returns the error replied to client when it cannot run a command
+func (app *App) checkACL(client *Client, spec *commandSpec, args []string) error {
+	if !client.authenticated && !spec.has(flagNoAuth) {
+		return NewCodedError(ErrorCodeNoAuth, "Authentication required.")
+	}
+
+	denial := client.user.checkPermissions(spec, args)
+	if denial == nil {
+		return nil
+	}
+	switch denial.reason {
+	case aclDeniedCommand:
+		app.stats.aclAccessDeniedCmd += 1
+	case aclDeniedKey:
+		app.stats.aclAccessDeniedKey += 1
+	case aclDeniedChannel:
+		app.stats.aclAccessDeniedChannel += 1
+	}
+	app.logACLDenial(client, client.user.name, *denial)
+	return denial.error(client.user)
+}
+
+// replaceUsers installs a new set of users, clients authenticated as a user
+// that does not exist anymore are disconnected
+func (app *App) replaceUsers(ctx context.Context, users map[string]*aclUser) {
+	app.users = users
+	for _, client := range app.clients {
+		if user, exists := users[client.user.name]; exists {
+			client.user = user
+		} else {
+			app.killClient(ctx, client)
+		}
+	}
+}
+
+// loadACLFile parses an ACL file, made of ACL LIST lines. The default user is
+// created with its initial permissions when the file does not have it.
+func loadACLFile(path string) (map[string]*aclUser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading ACLs, opening file '%s': %w", path, err)
+	}
+	defer file.Close()
+
+	users := map[string]*aclUser{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] != "user" || len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d should start with user keyword", path, lineNumber)
+		}
+		name := fields[1]
+		if _, exists := users[name]; exists {
+			return nil, fmt.Errorf("%s:%d: Duplicate user '%s' found", path, lineNumber, name)
+		}
+		user := newACLUser(name)
+		if err := user.setRules(fields[2:]); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w. ", path, lineNumber, err)
+		}
+		users[name] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error loading ACLs, reading file '%s': %w", path, err)
+	}
+
+	if _, exists := users[defaultUser]; !exists {
+		users[defaultUser] = newDefaultACLUser()
+	}
+	return users, nil
+}
+
+// saveACLFile writes the users to path, the previous file is only replaced
+// once the new one is complete
+func (app *App) saveACLFile(path string) error {
+	var sb strings.Builder
+	for _, user := range app.sortedUsers() {
+		sb.WriteString(user.describe())
+		sb.WriteString("\n")
+	}
+
+	tempPath := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-%d.acl", os.Getpid()))
+	if err := os.WriteFile(tempPath, []byte(sb.String()), 0o644); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// LoadACLFile loads the users from the aclfile parameter, if set, it is
+// called once at startup
+func (app *App) LoadACLFile() error {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	path := app.config.String("aclfile")
+	if path == "" {
+		return nil
+	}
+	users, err := loadACLFile(path)
+	if err != nil {
+		return err
+	}
+	app.users = users
+	return nil
+}
+
+// aclLogGroupingInterval is how long similar denials are counted in the same
+// ACL LOG entry
+const aclLogGroupingInterval = 60 * time.Second
+
+type aclLogEntry struct {
+	id       int64
+	count    int64
+	denial   aclDenial
+	username string
+	// client-info of the last client denied
+	clientInfo string
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+// logACLDenial records a denial in ACL LOG, username is the user the client
+// tried to authenticate as for the auth failures
+func (app *App) logACLDenial(client *Client, username string, denial aclDenial) {
+	now := time.Now()
+	info := clientInfo(client, now)
+
+	for idx, entry := range app.aclLog {
+		if entry.denial == denial && entry.username == username && now.Sub(entry.updatedAt) < aclLogGroupingInterval {
+			entry.count += 1
+			entry.clientInfo, entry.updatedAt = info, now
+			// the most recent entries come first
+			app.aclLog = slices.Insert(slices.Delete(app.aclLog, idx, idx+1), 0, entry)
+			return
+		}
+	}
+
+	app.aclLogNextID += 1
+	entry := &aclLogEntry{
+		id:         app.aclLogNextID - 1,
+		count:      1,
+		denial:     denial,
+		username:   username,
+		clientInfo: info,
+		createdAt:  now,
+		updatedAt:  now,
+	}
+	app.aclLog = slices.Insert(app.aclLog, 0, entry)
+	if maxLen := int(app.config.Int("acllog-max-len")); len(app.aclLog) > maxLen {
+		app.aclLog = app.aclLog[:maxLen]
+	}
+}
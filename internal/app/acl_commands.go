@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+func (app *App) handleACL(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.ACL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.SETUSER != nil:
+		return app.handleACLSETUSER(*c.SETUSER)
+	case c.GETUSER != nil:
+		return app.handleACLGETUSER(*c.GETUSER)
+	case c.DELUSER != nil:
+		return app.handleACLDELUSER(ctx, *c.DELUSER)
+	case c.LIST != nil:
+		return app.handleACLLIST()
+	case c.USERS != nil:
+		return app.handleACLUSERS()
+	case c.WHOAMI != nil:
+		return app.handleACLWHOAMI(ctx)
+	case c.CAT != nil:
+		return app.handleACLCAT(*c.CAT)
+	case c.GENPASS != nil:
+		return app.handleACLGENPASS(*c.GENPASS)
+	case c.LOG != nil:
+		return app.handleACLLOG(*c.LOG)
+	case c.DRYRUN != nil:
+		return app.handleACLDRYRUN(*c.DRYRUN)
+	case c.LOAD != nil:
+		return app.handleACLLOAD(ctx)
+	case c.SAVE != nil:
+		return app.handleACLSAVE()
+	default: // HELP
+		return subcommandHelp[cmd.ACL]("acl")
+	}
+}
+
+func (app *App) handleACLSETUSER(c cmd.ACL_SETUSER) (types.RawCmd, error) {
+	if strings.ContainsAny(c.Username, " \x00") {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "Usernames can't contain spaces or null characters")
+	}
+
+	// the rules are applied to a copy so the user is unchanged when one fails
+	user, exists := app.users[c.Username]
+	if !exists {
+		user = newACLUser(c.Username)
+	}
+	updated := user.clone()
+	if err := updated.setRules(c.Rules); err != nil {
+		return types.RawCmd{}, err
+	}
+	*user = *updated
+	app.users[c.Username] = user
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleACLGETUSER(c cmd.ACL_GETUSER) (types.RawCmd, error) {
+	user, exists := app.users[c.Username]
+	if !exists {
+		return types.NewNullRawCmd(), nil
+	}
+
+	return types.NewMapRawCmd(map[string]types.RawCmd{
+		"flags":     types.NewBulkArrayBulkString(user.flags()),
+		"passwords": types.NewBulkArrayBulkString(user.passwords),
+		"commands":  types.NewBulkStringRawCmd(user.describeCommands()),
+		"keys":      types.NewBulkStringRawCmd(user.describeKeys()),
+		"channels":  types.NewBulkStringRawCmd(user.describeChannels()),
+		"selectors": types.NewArrayRawCmd(),
+	}), nil
+}
+
+func (app *App) handleACLDELUSER(ctx context.Context, c cmd.ACL_DELUSER) (types.RawCmd, error) {
+	deleted := 0
+	for _, name := range append([]string{c.Username}, c.UsernameRest...) {
+		if name == defaultUser {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "The 'default' user cannot be removed")
+		}
+		user, exists := app.users[name]
+		if !exists {
+			continue
+		}
+		delete(app.users, name)
+		deleted += 1
+
+		// the clients authenticated as the user cannot keep its permissions
+		for _, client := range app.clients {
+			if client.user == user {
+				app.killClient(ctx, client)
+			}
+		}
+	}
+	return types.NewIntegerRawCmd(int64(deleted)), nil
+}
+
+func (app *App) handleACLLIST() (types.RawCmd, error) {
+	var lines []string
+	for _, user := range app.sortedUsers() {
+		lines = append(lines, user.describe())
+	}
+	return types.NewBulkArrayBulkString(lines), nil
+}
+
+func (app *App) handleACLUSERS() (types.RawCmd, error) {
+	var names []string
+	for _, user := range app.sortedUsers() {
+		names = append(names, user.name)
+	}
+	return types.NewBulkArrayBulkString(names), nil
+}
+
+func (app *App) handleACLWHOAMI(ctx context.Context) (types.RawCmd, error) {
+	return types.NewBulkStringRawCmd(GetClientFromContext(ctx).user.name), nil
+}
+
+func (app *App) handleACLCAT(c cmd.ACL_CAT) (types.RawCmd, error) {
+	if c.Category == nil {
+		return types.NewBulkArrayBulkString(aclCategories), nil
+	}
+
+	category := strings.ToLower(*c.Category)
+	if !slices.Contains(aclCategories, category) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("Unknown category '%s'", *c.Category))
+	}
+	var names []string
+	for _, spec := range allCommandSpecs() {
+		if slices.Contains(spec.categories, category) {
+			names = append(names, spec.name)
+		}
+	}
+	return types.NewBulkArrayBulkString(names), nil
+}
+
+// maxGenpassBits is the longest password ACL GENPASS generates
+const maxGenpassBits = 4096
+
+func (app *App) handleACLGENPASS(c cmd.ACL_GENPASS) (types.RawCmd, error) {
+	if c.Bits <= 0 || c.Bits > maxGenpassBits {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("ACL GENPASS argument must be the number of bits for the output password, a positive number up to %d", maxGenpassBits))
+	}
+
+	// each hex character holds 4 bits
+	chars := (c.Bits + 3) / 4
+	random := make([]byte, (chars+1)/2)
+	_, _ = rand.Read(random)
+	return types.NewBulkStringRawCmd(hex.EncodeToString(random)[:chars]), nil
+}
+
+func (app *App) handleACLLOG(c cmd.ACL_LOG) (types.RawCmd, error) {
+	count := len(app.aclLog)
+	if c.CountOrReset != nil {
+		if strings.EqualFold(*c.CountOrReset, "RESET") {
+			app.aclLog = nil
+			return types.NewStringRawCmd("OK"), nil
+		}
+		value, err := strconv.ParseInt(*c.CountOrReset, 10, 64)
+		if err != nil || value < 0 {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "value is out of range, must be positive")
+		}
+		count = int(min(value, int64(count)))
+	}
+
+	now := time.Now()
+	entries := make([]types.RawCmd, 0, count)
+	for _, entry := range app.aclLog[:count] {
+		entries = append(entries, types.NewMapRawCmd(map[string]types.RawCmd{
+			"count":                  types.NewIntegerRawCmd(entry.count),
+			"reason":                 types.NewBulkStringRawCmd(entry.denial.reason),
+			"context":                types.NewBulkStringRawCmd("toplevel"),
+			"object":                 types.NewBulkStringRawCmd(entry.denial.object),
+			"username":               types.NewBulkStringRawCmd(entry.username),
+			"age-seconds":            types.NewBulkStringRawCmd(strconv.FormatFloat(now.Sub(entry.createdAt).Seconds(), 'f', 3, 64)),
+			"client-info":            types.NewBulkStringRawCmd(entry.clientInfo),
+			"entry-id":               types.NewIntegerRawCmd(entry.id),
+			"timestamp-created":      types.NewIntegerRawCmd(entry.createdAt.UnixMilli()),
+			"timestamp-last-updated": types.NewIntegerRawCmd(entry.updatedAt.UnixMilli()),
+		}))
+	}
+	return types.NewArrayRawCmd(entries...), nil
+}
+
+func (app *App) handleACLDRYRUN(c cmd.ACL_DRYRUN) (types.RawCmd, error) {
+	user, exists := app.users[c.Username]
+	if !exists {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("User '%s' not found", c.Username))
+	}
+	commandArgs := append([]string{c.Command}, c.Args...)
+	spec := lookupCommand(commandArgs)
+	if spec == nil {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("Command '%s' not found", c.Command))
+	}
+
+	if denial := user.checkPermissions(spec, commandArgs); denial != nil {
+		return types.NewBulkStringRawCmd(denial.description(user)), nil
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func newNoACLFileError() CodedError {
+	return NewCodedError(ErrorCodeERR, "This Redis instance is not configured to use an ACL file. You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE (assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+}
+
+func (app *App) handleACLLOAD(ctx context.Context) (types.RawCmd, error) {
+	path := app.config.String("aclfile")
+	if path == "" {
+		return types.RawCmd{}, newNoACLFileError()
+	}
+	// the current users are kept when the file is invalid
+	users, err := loadACLFile(path)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	app.replaceUsers(ctx, users)
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleACLSAVE() (types.RawCmd, error) {
+	path := app.config.String("aclfile")
+	if path == "" {
+		return types.RawCmd{}, newNoACLFileError()
+	}
+	if err := app.saveACLFile(path); err != nil {
+		return types.RawCmd{}, fmt.Errorf("There was an error trying to save the ACLs. Please check the server logs for more information: %w", err)
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+// flatMap reads a RESP2 map reply, sent as a flat array of keys and values
+func flatMap(reply types.RawCmd) map[string]types.RawCmd {
+	result := map[string]types.RawCmd{}
+	for idx := 0; idx+1 < len(reply.Array); idx += 2 {
+		result[reply.Array[idx].BulkString] = reply.Array[idx+1]
+	}
+	return result
+}
+
+func Test_AUTH(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	expectEqual(t, "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?", admin.do("AUTH", "secret").Error)
+	admin.do("CONFIG", "SET", "requirepass", "secret")
+
+	// the clients connected before keep their session
+	expectEqual(t, "PONG", admin.do("PING").String)
+
+	client := newTestClient(t, addr)
+	expectEqual(t, "NOAUTH Authentication required.", client.do("GET", "foo").Error)
+	expectEqual(t, "WRONGPASS invalid username-password pair or user is disabled.", client.do("AUTH", "wrong").Error)
+	expectEqual(t, "OK", client.do("AUTH", "secret").String)
+	expectEqual(t, "default", client.do("ACL", "WHOAMI").BulkString)
+
+	hello := newTestClient(t, addr)
+	expectEqual(t, types.SymError, hello.do("HELLO", "2").Sym)
+	expectEqual(t, "ERR syntax error", hello.do("HELLO", "2", "AUTH", "default").Error)
+	reply := flatMap(hello.do("HELLO", "2", "AUTH", "default", "secret", "SETNAME", "greeter"))
+	expectEqual(t, int64(2), reply["proto"].Integer)
+	expectEqual(t, "greeter", hello.do("CLIENT", "GETNAME").BulkString)
+
+	expectEqual(t, "1", parseInfo(admin.do("INFO", "stats").BulkString)["acl_access_denied_auth"])
+	entry := flatMap(admin.do("ACL", "LOG").Array[0])
+	expectEqual(t, "auth", entry["reason"].BulkString)
+	expectEqual(t, "AUTH", entry["object"].BulkString)
+	expectEqual(t, "default", entry["username"].BulkString)
+}
+
+func Test_ACLPermissions(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	expectEqual(t, "OK", admin.do("ACL", "SETUSER", "alice", "on", ">pw", "~app:*", "%R~shared:*", "&news.*", "+@read", "+set", "+publish", "-ttl").String)
+	expectEqual(t, "user alice on #30c952fab122c3f9759f02a6d95c3758b246b4fee239957b2d4fee46e26170c4 ~app:* %R~shared:* &news.* -@all +@read +set +publish -ttl", admin.do("ACL", "LIST").Array[0].BulkString)
+
+	alice := newTestClient(t, addr)
+	expectEqual(t, "OK", alice.do("AUTH", "alice", "pw").String)
+	expectEqual(t, "OK", alice.do("SET", "app:1", "x").String)
+	expectEqual(t, "x", alice.do("GET", "app:1").BulkString)
+	expectEqual(t, types.SymNull, alice.do("GET", "shared:1").Sym)
+	expectEqual(t, "NOPERM No permissions to access a key", alice.do("SET", "shared:1", "x").Error)
+	expectEqual(t, "NOPERM No permissions to access a key", alice.do("GET", "other").Error)
+	expectEqual(t, "NOPERM User alice has no permissions to run the 'ttl' command", alice.do("TTL", "app:1").Error)
+	expectEqual(t, "NOPERM User alice has no permissions to run the 'config|get' command", alice.do("CONFIG", "GET", "port").Error)
+	expectEqual(t, int64(0), alice.do("PUBLISH", "news.sport", "goal").Integer)
+	expectEqual(t, "NOPERM No permissions to access a channel", alice.do("PUBLISH", "weather", "rain").Error)
+
+	expectEqual(t, "OK", admin.do("ACL", "DRYRUN", "alice", "GET", "app:1").String)
+	expectEqual(t, "User alice has no permissions to access the 'other' key", admin.do("ACL", "DRYRUN", "alice", "GET", "other").BulkString)
+
+	// the denials of the same kind are counted in one entry
+	alice.do("GET", "other")
+	entry := flatMap(admin.do("ACL", "LOG", "1").Array[0])
+	expectEqual(t, "key", entry["reason"].BulkString)
+	expectEqual(t, "other", entry["object"].BulkString)
+	expectEqual(t, int64(2), entry["count"].Integer)
+	expectEqual(t, "OK", admin.do("ACL", "LOG", "RESET").String)
+	expectEqual(t, 0, len(admin.do("ACL", "LOG").Array))
+
+	// changes apply to the authenticated clients, and deleting the user
+	// disconnects them
+	admin.do("ACL", "SETUSER", "alice", "+ttl")
+	expectEqual(t, int64(-1), alice.do("TTL", "app:1").Integer)
+	expectEqual(t, int64(1), admin.do("ACL", "DELUSER", "alice", "bob").Integer)
+	if _, err := alice.reader.ReadByte(); err == nil {
+		t.Error("expect the connection of a deleted user to be closed")
+	}
+}
+
+func Test_ACLSETUSERErrors(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	for _, c := range []struct {
+		rule     string
+		expected string
+	}{
+		{"+unknown", "Unknown command or category name in ACL"},
+		{"+@unknown", "Unknown command or category name in ACL"},
+		{"+client|unknown", "Unknown command or category name in ACL"},
+		{"#abc", "The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters"},
+		{"<missing", "The password you are trying to remove from the user does not exist"},
+		{"%X~key", "Syntax error"},
+		{"bogus", "Syntax error"},
+	} {
+		expectEqual(t, "ERR Error in ACL SETUSER modifier '"+c.rule+"': "+c.expected, client.do("ACL", "SETUSER", "bob", "on", c.rule).Error)
+	}
+	// a failed SETUSER does not create the user
+	expectEqual(t, types.SymNull, client.do("ACL", "GETUSER", "bob").Sym)
+
+	client.do("ACL", "SETUSER", "bob", "allkeys")
+	expectEqual(t, "ERR Error in ACL SETUSER modifier '~foo': Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns", client.do("ACL", "SETUSER", "bob", "~foo").Error)
+	expectEqual(t, "ERR The 'default' user cannot be removed", client.do("ACL", "DELUSER", "default").Error)
+}
+
+func Test_ACLCAT(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	expectEqual(t, len(aclCategories), len(client.do("ACL", "CAT").Array))
+
+	var names []string
+	for _, name := range client.do("ACL", "CAT", "blocking").Array {
+		names = append(names, name.BulkString)
+	}
+	expectEqual(t, "blpop", strings.Join(names, " "))
+	expectEqual(t, "ERR Unknown category 'nope'", client.do("ACL", "CAT", "nope").Error)
+}
+
+func Test_ACLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.acl")
+	if err := os.WriteFile(path, []byte("user alice on nopass ~* +get\n"), 0o644); err != nil {
+		t.Fatal("write acl file failed:", err)
+	}
+	cfg, err := config.Load([]string{"--aclfile", path})
+	if err != nil {
+		t.Fatal("load config failed:", err)
+	}
+	app := NewApp(cfg)
+	if err := app.LoadACLFile(); err != nil {
+		t.Fatal("load acl file failed:", err)
+	}
+	client := newTestClient(t, startTestApp(t, app))
+
+	// the default user is created when the file does not have it
+	expectEqual(t, "alice default", joinBulkStrings(client.do("ACL", "USERS")))
+	client.do("ACL", "SETUSER", "bob", "on", ">pw", "%W~logs:*", "&*", "+@write")
+	expectEqual(t, "OK", client.do("ACL", "SAVE").String)
+
+	client.do("ACL", "DELUSER", "bob")
+	expectEqual(t, "OK", client.do("ACL", "LOAD").String)
+	expectEqual(t, "user bob on #30c952fab122c3f9759f02a6d95c3758b246b4fee239957b2d4fee46e26170c4 %W~logs:* &* -@all +@write", client.do("ACL", "LIST").Array[1].BulkString)
+
+	// an invalid file leaves the users unchanged
+	if err := os.WriteFile(path, []byte("user carol on +nope\n"), 0o644); err != nil {
+		t.Fatal("write acl file failed:", err)
+	}
+	expectEqual(t, types.SymError, client.do("ACL", "LOAD").Sym)
+	expectEqual(t, "alice bob default", joinBulkStrings(client.do("ACL", "USERS")))
+}
+
+func joinBulkStrings(reply types.RawCmd) string {
+	var values []string
+	for _, elem := range reply.Array {
+		values = append(values, elem.BulkString)
+	}
+	return strings.Join(values, " ")
+}
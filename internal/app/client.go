@@ -0,0 +1,308 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/ulid"
+)
+
+// Client is the state of a connection that lives across its commands.
+// Fields other than the output are only accessed while holding App.mutex,
+// or by the connection goroutine for the ones only it changes.
+type Client struct {
+	id int64
+	// identifies the connection in the blocking operations
+	connID   ulid.ID
+	conn     net.Conn
+	protocol encoding.Protocol
+	// addresses shown by CLIENT LIST, `<path>:0` for a unix socket
+	addr       string
+	localAddr  string
+	unixSocket bool
+	// index of the database selected with SELECT
+	db int
+
+	// set by CLIENT SETNAME
+	name      string
+	createdAt time.Time
+	// start or end of the last command, for the idle time
+	lastInteraction time.Time
+	// full name of the last command, e.g. client|list
+	lastCommand string
+	// set while waiting in a blocking command, CLIENT UNBLOCK sends the
+	// error to reply with on it (nil for a timeout)
+	unblock chan error
+	noEvict bool
+
+	// ACL user of the client, authenticated is false until AUTH succeeds
+	// when the default user requires a password
+	user          *aclUser
+	authenticated bool
+
+	// CLIENT REPLY state, replySkipNext is set by SKIP for the next command and
+	// noReply tells the connection whether to send the reply of the last one
+	replyOff      bool
+	replySkipNext bool
+	noReply       bool
+
+	// set by CLIENT KILL on the client itself, the connection is closed once
+	// the reply is sent
+	closeAfterReply bool
+	// set when the server closes the connection: CLIENT KILL on another
+	// client, idle timeout, output buffer limit or write error
+	killed atomic.Bool
+	// running a command, including waiting in a blocking command or a pause
+	inCommand bool
+
+	// only used by the connection goroutine, and by watchDisconnect while it
+	// waits in a blocking command
+	reader *bufio.Reader
+
+	// replies are queued by the connection goroutine while pushes (pub/sub
+	// messages, invalidations) can come from any other command, the writer
+	// goroutine sends them to writer
+	output clientOutput
+	writer io.Writer
+	config *config.Config
+
+	tracking      clientTracking
+	subscriptions map[string]struct{}
+}
+
+func newClient(id int64, connID ulid.ID, conn net.Conn, cfg *config.Config, stats *netStats) *Client {
+	now := time.Now()
+	client := &Client{
+		id:        id,
+		connID:    connID,
+		conn:      conn,
+		protocol:  encoding.ProtocolRESP2,
+		addr:      conn.RemoteAddr().String(),
+		localAddr: conn.LocalAddr().String(),
+		reader:    bufio.NewReader(countingReader{conn, &stats.inputBytes}),
+		output:    newClientOutput(),
+		writer:    countingWriter{conn, &stats.outputBytes},
+		config:    cfg,
+
+		createdAt:       now,
+		lastInteraction: now,
+
+		subscriptions: map[string]struct{}{},
+	}
+	// the peer of a unix socket has no address, Redis shows the socket path
+	if socket, isUnix := conn.LocalAddr().(*net.UnixAddr); isUnix {
+		client.addr = socket.Name + ":0"
+		client.localAddr = client.addr
+		client.unixSocket = true
+	}
+	return client
+}
+
+func (app *App) registerClient(conn net.Conn) *Client {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	app.lastClientID += 1
+	app.stats.totalConnectionsReceived += 1
+	client := newClient(app.lastClientID, app.idGenerator.MustNew(), conn, app.config, &app.net)
+	client.user = app.users[defaultUser]
+	client.authenticated = client.user.enabled && client.user.nopass
+	app.clients[client.id] = client
+	go client.writeLoop()
+	return client
+}
+
+func (app *App) unregisterClient(client *Client) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	app.disableTracking(client)
+	app.unsubscribeAll(client)
+	delete(app.clients, client.id)
+	_ = client.conn.Close()
+}
+
+const clientKey ctxKey = 2
+
+func NewClientContext(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientKey, client)
+}
+
+// GetClientFromContext returns the client issuing the command, it is nil for
+// work that does not come from a connection (e.g. background jobs)
+func GetClientFromContext(ctx context.Context) *Client {
+	client, _ := ctx.Value(clientKey).(*Client)
+	return client
+}
+
+func (app *App) clientsPaused() bool {
+	return time.Now().Before(app.pauseEnd)
+}
+
+// pausedByWrites tells whether CLIENT PAUSE WRITE delays a command, the ones
+// that may change the keyspace
+func pausedByWrites(spec *commandSpec) bool {
+	return spec != nil && spec.has(flagWrite|flagMayReplicate)
+}
+
+// waitClientPause delays command while the clients are paused for it, the
+// mutex is released meanwhile
+func (app *App) waitClientPause(client *Client, spec *commandSpec) error {
+	for app.clientsPaused() && (!app.pauseWrites || pausedByWrites(spec)) {
+		unpaused, timeout := app.pauseCh, time.Until(app.pauseEnd)
+
+		// the replies of the commands pipelined before are not held back
+		if client != nil {
+			client.flush()
+		}
+
+		app.mutex.Unlock()
+		select {
+		case <-unpaused:
+		case <-time.After(timeout):
+		case <-app.shutdownCh:
+		}
+		app.mutex.Lock()
+
+		if app.shuttingDown.Load() {
+			return errShuttingDown
+		}
+	}
+	return nil
+}
+
+func (app *App) pauseClients(end time.Time, writes bool) {
+	// a new pause cannot shorten the current one nor restrict what it pauses
+	if app.clientsPaused() {
+		if app.pauseEnd.After(end) {
+			end = app.pauseEnd
+		}
+		writes = writes && app.pauseWrites
+	} else {
+		app.pauseCh = make(chan struct{})
+	}
+	app.pauseEnd, app.pauseWrites = end, writes
+}
+
+func (app *App) unpauseClients() {
+	if !app.clientsPaused() {
+		return
+	}
+	app.pauseEnd = time.Time{}
+	close(app.pauseCh)
+}
+
+// clientType is the class of client used by the TYPE filters
+func clientType(client *Client) string {
+	if len(client.subscriptions) != 0 {
+		return "pubsub"
+	}
+	return "normal"
+}
+
+// clientFlags returns the flags shown by CLIENT LIST, N when none applies
+func clientFlags(client *Client) string {
+	var flags strings.Builder
+	if client.unblock != nil {
+		flags.WriteByte('b')
+	}
+	if client.tracking.enabled {
+		flags.WriteByte('t')
+		if client.tracking.bcast {
+			flags.WriteByte('B')
+		}
+	}
+	if client.closeAfterReply {
+		flags.WriteByte('c')
+	}
+	if client.noEvict {
+		flags.WriteByte('e')
+	}
+	if client.unixSocket {
+		flags.WriteByte('U')
+	}
+	if len(client.subscriptions) != 0 {
+		flags.WriteByte('P')
+	}
+	if flags.Len() == 0 {
+		return "N"
+	}
+	return flags.String()
+}
+
+// clientInfo is the line describing client in CLIENT LIST and CLIENT INFO
+func clientInfo(client *Client, now time.Time) string {
+	redirect := int64(-1)
+	if client.tracking.enabled {
+		redirect = client.tracking.redirect
+	}
+	outputLength := client.outputLength()
+
+	return fmt.Sprintf(
+		"id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d sub=%d psub=0 ssub=0 multi=-1 watch=0 obl=0 omem=%d cmd=%s user=%s redir=%d resp=%d",
+		client.id, client.addr, client.localAddr, client.name,
+		int64(now.Sub(client.createdAt).Seconds()), int64(now.Sub(client.lastInteraction).Seconds()),
+		clientFlags(client), client.db, len(client.subscriptions),
+		outputLength, client.lastCommand, client.user.name, redirect, client.protocol,
+	)
+}
+
+// killClient closes the connection of client, the one issuing the command
+// still gets its reply
+func (app *App) killClient(ctx context.Context, client *Client) {
+	if client == GetClientFromContext(ctx) {
+		client.closeAfterReply = true
+		return
+	}
+	client.close()
+}
+
+// watchDisconnect reports on the returned channel when the connection of a
+// client waiting in a blocking command is closed. Pipelined commands are left
+// in the read buffer. stop must be called with the mutex held before the
+// connection reads again.
+func (app *App) watchDisconnect(client *Client) (disconnected <-chan struct{}, stop func()) {
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := client.reader.Peek(1); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			close(closed)
+		}
+	}()
+
+	return closed, func() {
+		// interrupt the peek, the shutdown sets its own deadline on idle
+		// connections which must be kept
+		_ = client.conn.SetReadDeadline(time.Now())
+		<-done
+		if !app.shuttingDown.Load() {
+			_ = client.conn.SetReadDeadline(time.Time{})
+		}
+	}
+}
+
+// clientsCron closes the clients idle for longer than the timeout parameter,
+// clients running a command (e.g. blocked) or subscribed are not idle
+func (app *App) clientsCron(now time.Time) {
+	timeout := time.Duration(app.config.Int("timeout")) * time.Second
+	if timeout == 0 {
+		return
+	}
+	for _, client := range app.clients {
+		if client.inCommand || len(client.subscriptions) != 0 || now.Sub(client.lastInteraction) <= timeout {
+			continue
+		}
+		client.close()
+	}
+}
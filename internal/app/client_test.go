@@ -0,0 +1,239 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_CLIENTRegistry(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	first := newTestClient(t, addr)
+	second := newTestClient(t, addr)
+
+	firstID := first.do("CLIENT", "ID").Integer
+	secondID := second.do("CLIENT", "ID").Integer
+	if firstID == 0 || secondID <= firstID {
+		t.Fatalf("unexpected ids %d and %d", firstID, secondID)
+	}
+
+	expectEqual(t, types.SymNull, first.do("CLIENT", "GETNAME").Sym)
+	expectEqual(t, "OK", first.do("CLIENT", "SETNAME", "worker").String)
+	expectEqual(t, "worker", first.do("CLIENT", "GETNAME").BulkString)
+	expectEqual(t, types.SymError, first.do("CLIENT", "SETNAME", "bad name").Sym)
+	first.do("SELECT", "2")
+
+	info := first.do("CLIENT", "INFO").BulkString
+	for _, field := range []string{"id=" + strconv.FormatInt(firstID, 10) + " ", " name=worker ", " db=2 ", " flags=N ", " cmd=client|info "} {
+		if !strings.Contains(info, field) {
+			t.Errorf("expect %q in %q", field, info)
+		}
+	}
+
+	list := second.do("CLIENT", "LIST").BulkString
+	expectEqual(t, 2, strings.Count(list, "\n"))
+	if !strings.Contains(list, " cmd=client|info ") {
+		t.Errorf("expect the last command of the first client in %q", list)
+	}
+	list = second.do("CLIENT", "LIST", "ID", strconv.FormatInt(secondID, 10)).BulkString
+	expectEqual(t, 1, strings.Count(list, "\n"))
+	list = second.do("CLIENT", "LIST", "ID", strconv.FormatInt(firstID, 10), strconv.FormatInt(secondID, 10), "1000").BulkString
+	expectEqual(t, 2, strings.Count(list, "\n"))
+	expectEqual(t, "ERR Invalid client ID", second.do("CLIENT", "LIST", "ID", "0").Error)
+	expectEqual(t, "ERR syntax error", second.do("CLIENT", "LIST", "TYPE", "normal", "ID", "1").Error)
+	expectEqual(t, "", second.do("CLIENT", "LIST", "TYPE", "pubsub").BulkString)
+	expectEqual(t, "ERR Unknown client type 'foo'", second.do("CLIENT", "LIST", "TYPE", "foo").Error)
+
+	// by default a client does not kill itself
+	expectEqual(t, int64(0), second.do("CLIENT", "KILL", "ID", strconv.FormatInt(secondID, 10)).Integer)
+	expectEqual(t, int64(1), second.do("CLIENT", "KILL", "ID", strconv.FormatInt(firstID, 10)).Integer)
+	_ = first.conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := first.reader.ReadByte(); !errors.Is(err, io.EOF) {
+		t.Errorf("expect the killed connection to be closed, got %v", err)
+	}
+	expectEqual(t, 1, strings.Count(second.do("CLIENT", "LIST").BulkString, "\n"))
+}
+
+func Test_CLIENTREPLY(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	// neither OFF nor the commands after it are answered
+	if _, err := client.conn.Write(append(mustMarshal(t, "CLIENT", "REPLY", "OFF"), mustMarshal(t, "SET", "foo", "1")...)); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	expectEqual(t, "OK", client.do("CLIENT", "REPLY", "ON").String)
+
+	// SKIP only applies to the next command
+	if _, err := client.conn.Write(append(mustMarshal(t, "CLIENT", "REPLY", "SKIP"), mustMarshal(t, "SET", "foo", "2")...)); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	expectEqual(t, "2", client.do("GET", "foo").BulkString)
+}
+
+func Test_CLIENTPAUSE(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	client := newTestClient(t, addr)
+
+	expectEqual(t, "OK", admin.do("CLIENT", "PAUSE", "10000", "WRITE").String)
+	// reads are not paused
+	expectEqual(t, types.SymNull, client.do("GET", "foo").Sym)
+
+	if _, err := client.conn.Write(mustMarshal(t, "SET", "foo", "bar")); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	expectEqual(t, types.SymNull, admin.do("GET", "foo").Sym)
+
+	expectEqual(t, "OK", admin.do("CLIENT", "UNPAUSE").String)
+	expectEqual(t, "OK", client.read().String)
+	expectEqual(t, "bar", admin.do("GET", "foo").BulkString)
+
+	// the pause ends by itself after the timeout
+	admin.do("CLIENT", "PAUSE", "100")
+	start := time.Now()
+	expectEqual(t, "bar", client.do("GET", "foo").BulkString)
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expect the command to wait for the pause, took %s", elapsed)
+	}
+}
+
+func Test_CLIENTUNBLOCK(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	blocked := newTestClient(t, addr)
+	blockedID := strconv.FormatInt(blocked.do("CLIENT", "ID").Integer, 10)
+
+	expectEqual(t, int64(0), admin.do("CLIENT", "UNBLOCK", blockedID).Integer)
+
+	for _, c := range []struct {
+		mode  string
+		reply types.RawCmd
+	}{
+		{"TIMEOUT", types.NewNullRawCmd()},
+		{"ERROR", types.NewErrorRawCmd("UNBLOCKED client unblocked via CLIENT UNBLOCK")},
+	} {
+		// the reply of a command pipelined before BLPOP is not held back
+		batch := append(mustMarshal(t, "SET", "key", c.mode), mustMarshal(t, "BLPOP", "list", "0")...)
+		if _, err := blocked.conn.Write(batch); err != nil {
+			t.Fatal("write failed:", err)
+		}
+		expectEqual(t, "OK", blocked.read().String)
+		waitForBlockedClients(t, admin, 1)
+		expectEqual(t, int64(1), admin.do("CLIENT", "UNBLOCK", blockedID, c.mode).Integer)
+		reply := blocked.read()
+		expectEqual(t, c.reply.Sym, reply.Sym)
+		expectEqual(t, c.reply.Error, reply.Error)
+	}
+	waitForBlockedClients(t, admin, 0)
+}
+
+// waitForBlockedClients waits until count clients are blocked
+func waitForBlockedClients(t *testing.T, client *testClient, count int) {
+	t.Helper()
+	want := strconv.Itoa(count)
+	for range 100 {
+		if parseInfo(client.do("INFO", "clients").BulkString)["blocked_clients"] == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expect %d blocked clients", count)
+}
+
+func Test_BLPOPDisconnectedWaiter(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	blocked := newTestClient(t, addr)
+
+	batch := append(mustMarshal(t, "SET", "key", "value"), mustMarshal(t, "BLPOP", "list", "0")...)
+	if _, err := blocked.conn.Write(batch); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	expectEqual(t, "OK", blocked.read().String)
+	waitForBlockedClients(t, admin, 1)
+	_ = blocked.conn.Close()
+	waitForBlockedClients(t, admin, 0)
+
+	// the element is not handed to the closed connection
+	admin.do("RPUSH", "list", "a")
+	expectEqual(t, int64(1), admin.do("LLEN", "list").Integer)
+}
+
+func Test_BLPOPNoLostElement(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	const elements = 200
+
+	// pipelined waiters keep timing out while the elements are pushed
+	var waiters []*testClient
+	for range 8 {
+		waiter := newTestClient(t, addr)
+		var pipeline []byte
+		for range elements {
+			pipeline = append(pipeline, mustMarshal(t, "BLPOP", "list", "0.001")...)
+		}
+		if _, err := waiter.conn.Write(pipeline); err != nil {
+			t.Fatal("write failed:", err)
+		}
+		waiters = append(waiters, waiter)
+	}
+
+	pusher := newTestClient(t, addr)
+	for i := range elements {
+		pusher.do("RPUSH", "list", strconv.Itoa(i))
+	}
+
+	total := 0
+	for _, waiter := range waiters {
+		for range elements {
+			if waiter.read().Sym == types.SymArray {
+				total += 1
+			}
+		}
+	}
+	total += int(pusher.do("LLEN", "list").Integer)
+	expectEqual(t, elements, total)
+}
+
+func Test_ClientIdleTimeout(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	admin := newTestClient(t, addr)
+	idle := newTestClient(t, addr)
+	subscriber := newTestClient(t, addr)
+	subscriber.do("SUBSCRIBE", "channel")
+
+	admin.do("CONFIG", "SET", "timeout", "1")
+	_ = idle.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := idle.reader.ReadByte(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expect the idle connection to be closed, got %v", err)
+	}
+
+	// subscribed clients are not idle, unlike admin
+	publisher := newTestClient(t, addr)
+	expectEqual(t, int64(1), publisher.do("PUBLISH", "channel", "message").Integer)
+	expectEqual(t, "message", subscriber.read().Array[2].BulkString)
+}
+
+func Test_ClientOutputBufferLimit(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	publisher := newTestClient(t, addr)
+	subscriber := newTestClient(t, addr)
+	subscriber.do("SUBSCRIBE", "channel")
+
+	publisher.do("CONFIG", "SET", "client-output-buffer-limit", "pubsub 64kb 0 0")
+	// the subscriber does not read, its messages pile up on the server
+	message := strings.Repeat("x", 32*1024)
+	for range 1000 {
+		if publisher.do("PUBLISH", "channel", message).Integer == 0 {
+			expectEqual(t, "1", parseInfo(publisher.do("INFO", "clients").BulkString)["connected_clients"])
+			return
+		}
+	}
+	t.Fatal("expect the subscriber to be disconnected")
+}
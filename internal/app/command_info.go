@@ -0,0 +1,285 @@
+package app
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+func (app *App) handleCOMMAND(args []string) (types.RawCmd, error) {
+	if len(args) == 1 {
+		return commandInfos(sortedSpecs(commandTable)), nil
+	}
+
+	c, err := argsparser.Parse[cmd.COMMAND](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.COUNT != nil:
+		return app.handleCOMMANDCOUNT()
+	case c.LIST != nil:
+		return app.handleCOMMANDLIST(*c.LIST)
+	case c.INFO != nil:
+		return app.handleCOMMANDINFO(*c.INFO)
+	case c.DOCS != nil:
+		return app.handleCOMMANDDOCS(*c.DOCS)
+	case c.GETKEYS != nil:
+		return app.handleCOMMANDGETKEYS(*c.GETKEYS)
+	default: // HELP
+		return subcommandHelp[cmd.COMMAND]("command")
+	}
+}
+
+func (app *App) handleCOMMANDCOUNT() (types.RawCmd, error) {
+	return types.NewIntegerRawCmd(int64(len(commandTable))), nil
+}
+
+func (app *App) handleCOMMANDLIST(c cmd.COMMAND_LIST) (types.RawCmd, error) {
+	var filter func(spec *commandSpec) bool
+	if c.FILTERBY != nil {
+		value := c.FILTERBY.Value
+		switch strings.ToUpper(c.FILTERBY.Filter) {
+		case "MODULE":
+			// there are no modules
+			filter = func(*commandSpec) bool { return false }
+		case "ACLCAT":
+			filter = func(spec *commandSpec) bool { return slices.Contains(spec.categories, strings.ToLower(value)) }
+		case "PATTERN":
+			filter = func(spec *commandSpec) bool { return glob.MatchNoCase(value, spec.name) }
+		default:
+			return types.RawCmd{}, NewSyntaxError()
+		}
+	}
+
+	var names []string
+	for _, spec := range sortedSpecs(commandTable) {
+		for _, spec := range append([]*commandSpec{spec}, sortedSpecs(spec.subcommands)...) {
+			if filter == nil || filter(spec) {
+				names = append(names, spec.name)
+			}
+		}
+	}
+	return types.NewBulkArrayBulkString(names), nil
+}
+
+func (app *App) handleCOMMANDINFO(c cmd.COMMAND_INFO) (types.RawCmd, error) {
+	if len(c.CommandNames) == 0 {
+		return commandInfos(sortedSpecs(commandTable)), nil
+	}
+
+	infos := make([]types.RawCmd, 0, len(c.CommandNames))
+	for _, name := range c.CommandNames {
+		if spec := lookupCommandByName(name); spec != nil {
+			infos = append(infos, commandInfo(spec))
+		} else {
+			infos = append(infos, types.NewNullRawCmd())
+		}
+	}
+	return types.NewArrayRawCmd(infos...), nil
+}
+
+func (app *App) handleCOMMANDDOCS(c cmd.COMMAND_DOCS) (types.RawCmd, error) {
+	specs := sortedSpecs(commandTable)
+	if len(c.CommandNames) != 0 {
+		specs = nil
+		for _, name := range c.CommandNames {
+			// unknown commands are left out of the reply
+			if spec := lookupCommandByName(name); spec != nil {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	docs := make(map[string]types.RawCmd, len(specs))
+	for _, spec := range specs {
+		docs[spec.name] = commandDocs(spec)
+	}
+	return types.NewMapRawCmd(docs), nil
+}
+
+func (app *App) handleCOMMANDGETKEYS(c cmd.COMMAND_GETKEYS) (types.RawCmd, error) {
+	commandArgs := append([]string{c.Command}, c.Args...)
+	spec := lookupCommand(commandArgs)
+	if spec == nil || (spec.subcommands != nil && len(commandArgs) > 1) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "Invalid command specified")
+	}
+	if !spec.checkArity(len(commandArgs)) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "Invalid number of arguments specified for command")
+	}
+
+	var keys []string
+	for _, keySpec := range spec.keys {
+		for _, idx := range keySpec.indexes(commandArgs) {
+			keys = append(keys, commandArgs[idx])
+		}
+	}
+	if len(keys) == 0 {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "The command has no key arguments")
+	}
+	return types.NewBulkArrayBulkString(keys), nil
+}
+
+func commandInfos(specs []*commandSpec) types.RawCmd {
+	infos := make([]types.RawCmd, 0, len(specs))
+	for _, spec := range specs {
+		infos = append(infos, commandInfo(spec))
+	}
+	return types.NewArrayRawCmd(infos...)
+}
+
+// commandInfo is the reply of COMMAND INFO for a command: name, arity, flags,
+// first key, last key, key step, ACL categories, tips, key specs and
+// subcommands
+func commandInfo(spec *commandSpec) types.RawCmd {
+	var categories []types.RawCmd
+	for _, category := range aclCategories {
+		if slices.Contains(spec.categories, category) {
+			categories = append(categories, types.NewStringRawCmd("@"+category))
+		}
+	}
+	keySpecs := make([]types.RawCmd, 0, len(spec.keys))
+	for _, keySpec := range spec.keys {
+		keySpecs = append(keySpecs, keySpec.reply())
+	}
+
+	// the legacy key range spans all the keys, with the step of the first ones
+	var firstKey, lastKey, keyStep int
+	if len(spec.keys) != 0 {
+		firstKey, lastKey, keyStep = spec.keys[0].first, spec.keys[len(spec.keys)-1].last, max(spec.keys[0].step, 1)
+	}
+
+	return types.NewArrayRawCmd(
+		types.NewBulkStringRawCmd(spec.name),
+		types.NewIntegerRawCmd(int64(spec.arity)),
+		simpleStrings(spec.flags.names()),
+		types.NewIntegerRawCmd(int64(firstKey)),
+		types.NewIntegerRawCmd(int64(lastKey)),
+		types.NewIntegerRawCmd(int64(keyStep)),
+		types.NewArrayRawCmd(categories...),
+		types.NewArrayRawCmd(),
+		types.NewArrayRawCmd(keySpecs...),
+		commandInfos(sortedSpecs(spec.subcommands)),
+	)
+}
+
+// reply describes the key spec like Redis does, the keys are found by index
+// then by range. The last key of the range is relative to the first one
+// unless it counts from the end.
+func (k keySpec) reply() types.RawCmd {
+	last := k.last
+	if last >= 0 {
+		last -= k.first
+	}
+	return types.NewMapRawCmd(map[string]types.RawCmd{
+		"flags": simpleStrings(k.access.flags()),
+		"begin_search": types.NewMapRawCmd(map[string]types.RawCmd{
+			"type": types.NewBulkStringRawCmd("index"),
+			"spec": types.NewMapRawCmd(map[string]types.RawCmd{
+				"index": types.NewIntegerRawCmd(int64(k.first)),
+			}),
+		}),
+		"find_keys": types.NewMapRawCmd(map[string]types.RawCmd{
+			"type": types.NewBulkStringRawCmd("range"),
+			"spec": types.NewMapRawCmd(map[string]types.RawCmd{
+				"lastkey": types.NewIntegerRawCmd(int64(last)),
+				"keystep": types.NewIntegerRawCmd(int64(max(k.step, 1))),
+				"limit":   types.NewIntegerRawCmd(0),
+			}),
+		}),
+	})
+}
+
+// flags returns the key spec flags of Redis for the access
+func (a keyAccess) flags() []string {
+	switch a {
+	case keyRead:
+		return []string{"RO", "access"}
+	case keyWrite:
+		return []string{"OW", "update"}
+	case keyRead | keyWrite:
+		return []string{"RW", "access", "update"}
+	default:
+		return []string{"RO"}
+	}
+}
+
+// commandDocs is the reply of COMMAND DOCS for a command
+func commandDocs(spec *commandSpec) types.RawCmd {
+	docs := map[string]types.RawCmd{
+		"group": types.NewBulkStringRawCmd(spec.group),
+	}
+	// the arguments of containers are documented by their subcommands
+	if spec.args != nil && spec.subcommands == nil {
+		// the structs are checked by the tests, a broken one is left out
+		if arguments, err := argsparser.Schema(spec.args); err == nil {
+			docs["arguments"] = argumentDocs(arguments)
+		}
+	}
+	if spec.subcommands != nil {
+		subcommands := make(map[string]types.RawCmd, len(spec.subcommands))
+		for _, sub := range spec.subcommands {
+			subcommands[sub.name] = commandDocs(sub)
+		}
+		docs["subcommands"] = types.NewMapRawCmd(subcommands)
+	}
+	return types.NewMapRawCmd(docs)
+}
+
+func argumentDocs(arguments []argsparser.Argument) types.RawCmd {
+	docs := make([]types.RawCmd, 0, len(arguments))
+	for _, argument := range arguments {
+		doc := map[string]types.RawCmd{
+			"name": types.NewBulkStringRawCmd(argument.Name),
+			"type": types.NewBulkStringRawCmd(string(argument.Type)),
+		}
+		// only the arguments holding a value are displayed
+		switch argument.Type {
+		case argsparser.ArgumentTypeString, argsparser.ArgumentTypeInteger, argsparser.ArgumentTypeDouble:
+			doc["display_text"] = types.NewBulkStringRawCmd(argument.Name)
+		}
+		if argument.Token != "" {
+			doc["token"] = types.NewBulkStringRawCmd(argument.Token)
+		}
+		var flags []string
+		if argument.Optional {
+			flags = append(flags, "optional")
+		}
+		if argument.Multiple {
+			flags = append(flags, "multiple")
+		}
+		if argument.MultipleToken {
+			flags = append(flags, "multiple_token")
+		}
+		if flags != nil {
+			doc["flags"] = simpleStrings(flags)
+		}
+		if argument.Arguments != nil {
+			doc["arguments"] = argumentDocs(argument.Arguments)
+		}
+		docs = append(docs, types.NewMapRawCmd(doc))
+	}
+	return types.NewArrayRawCmd(docs...)
+}
+
+// subcommandHelp is the reply of the HELP subcommand of a container command
+func subcommandHelp[T any](command string) (types.RawCmd, error) {
+	lines, err := argsparser.Help(reflect.TypeFor[T](), command)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return simpleStrings(lines), nil
+}
+
+func simpleStrings(values []string) types.RawCmd {
+	array := make([]types.RawCmd, 0, len(values))
+	for _, value := range values {
+		array = append(array, types.NewStringRawCmd(value))
+	}
+	return types.NewArrayRawCmd(array...)
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func joinStrings(reply types.RawCmd) string {
+	var values []string
+	for _, elem := range reply.Array {
+		values = append(values, elem.String)
+	}
+	return strings.Join(values, " ")
+}
+
+func Test_CommandTable(t *testing.T) {
+	for _, spec := range commandTable {
+		if spec.handler == nil {
+			t.Errorf("expect command %s to have a handler", spec.name)
+		}
+		for _, spec := range append([]*commandSpec{spec}, sortedSpecs(spec.subcommands)...) {
+			if spec.arity == 0 {
+				t.Errorf("expect command %s to have an arity", spec.name)
+			}
+			if spec.has(flagWrite) && spec.has(flagReadonly) {
+				t.Errorf("expect command %s to not be both write and readonly", spec.name)
+			}
+			if spec.args != nil {
+				if _, err := argsparser.Schema(spec.args); err != nil {
+					t.Errorf("expect the arguments of command %s to be valid: %s", spec.name, err)
+				}
+			}
+		}
+	}
+}
+
+func Test_COMMANDINFO(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	expectEqual(t, int64(len(commandTable)), client.do("COMMAND", "COUNT").Integer)
+	expectEqual(t, len(commandTable), len(client.do("COMMAND").Array))
+
+	infos := client.do("COMMAND", "INFO", "SET", "blpop", "config|get", "nope").Array
+	expectEqual(t, 4, len(infos))
+
+	set := infos[0].Array
+	expectEqual(t, "set", set[0].BulkString)
+	expectEqual(t, int64(-3), set[1].Integer)
+	expectEqual(t, "write denyoom", joinStrings(set[2]))
+	expectEqual(t, int64(1), set[3].Integer)
+	expectEqual(t, int64(1), set[4].Integer)
+	expectEqual(t, int64(1), set[5].Integer)
+	expectEqual(t, "@write @string @slow", joinStrings(set[6]))
+	keySpec := flatMap(set[8].Array[0])
+	expectEqual(t, "OW update", joinStrings(keySpec["flags"]))
+	expectEqual(t, int64(1), flatMap(flatMap(keySpec["begin_search"])["spec"])["index"].Integer)
+
+	blpop := infos[1].Array
+	expectEqual(t, "write blocking", joinStrings(blpop[2]))
+	expectEqual(t, int64(-2), blpop[4].Integer)
+	expectEqual(t, int64(-2), flatMap(flatMap(flatMap(blpop[8].Array[0])["find_keys"])["spec"])["lastkey"].Integer)
+
+	configGet := infos[2].Array
+	expectEqual(t, "config|get", configGet[0].BulkString)
+	expectEqual(t, "@admin @slow @dangerous", joinStrings(configGet[6]))
+	expectEqual(t, types.SymNull, infos[3].Sym)
+
+	// the subcommands are part of the info of their container
+	container := client.do("COMMAND", "INFO", "config").Array[0].Array
+	expectEqual(t, 5, len(container[9].Array))
+}
+
+func Test_COMMANDLIST(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	names := joinBulkStrings(client.do("COMMAND", "LIST"))
+	for _, name := range []string{"get", "client", "client|list"} {
+		if !strings.Contains(" "+names+" ", " "+name+" ") {
+			t.Errorf("expect %s in %q", name, names)
+		}
+	}
+	expectEqual(t, "blpop", joinBulkStrings(client.do("COMMAND", "LIST", "FILTERBY", "ACLCAT", "blocking")))
+	expectEqual(t, "config config|get config|help config|resetstat config|rewrite config|set", joinBulkStrings(client.do("COMMAND", "LIST", "FILTERBY", "PATTERN", "CONFIG*")))
+	expectEqual(t, 0, len(client.do("COMMAND", "LIST", "FILTERBY", "MODULE", "json").Array))
+	expectEqual(t, "ERR syntax error", client.do("COMMAND", "LIST", "FILTERBY", "NAME", "get").Error)
+}
+
+func Test_COMMANDGETKEYS(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	expectEqual(t, "a b", joinBulkStrings(client.do("COMMAND", "GETKEYS", "BLPOP", "a", "b", "0")))
+	expectEqual(t, "foo", joinBulkStrings(client.do("COMMAND", "GETKEYS", "OBJECT", "ENCODING", "foo")))
+	expectEqual(t, "ERR Invalid command specified", client.do("COMMAND", "GETKEYS", "NOPE", "a").Error)
+	expectEqual(t, "ERR Invalid number of arguments specified for command", client.do("COMMAND", "GETKEYS", "GET", "a", "b").Error)
+	expectEqual(t, "ERR The command has no key arguments", client.do("COMMAND", "GETKEYS", "PING").Error)
+}
+
+func Test_COMMANDDOCS(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	docs := flatMap(client.do("COMMAND", "DOCS", "get", "client", "nope"))
+	expectEqual(t, 2, len(docs))
+	expectEqual(t, "string", flatMap(docs["get"])["group"].BulkString)
+	subcommands := flatMap(flatMap(docs["client"])["subcommands"])
+	expectEqual(t, "connection", flatMap(subcommands["client|list"])["group"].BulkString)
+
+	arguments := flatMap(client.do("COMMAND", "DOCS", "set"))["set"]
+	var syntax []string
+	for _, argument := range flatMap(arguments)["arguments"].Array {
+		argument := flatMap(argument)
+		syntax = append(syntax, argument["name"].BulkString+":"+argument["type"].BulkString+":"+argument["token"].BulkString+":"+joinStrings(argument["flags"]))
+	}
+	expectEqual(t, "key:string::,value:string::,get:pure-token:GET:optional,condition:oneof::optional,expiration:oneof::optional", strings.Join(syntax, ","))
+	expiration := flatMap(flatMap(arguments)["arguments"].Array[4])["arguments"].Array
+	expectEqual(t, "seconds", flatMap(expiration[0])["display_text"].BulkString)
+	expectEqual(t, "EX", flatMap(expiration[0])["token"].BulkString)
+}
+
+func Test_CommandArity(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	expectEqual(t, "ERR wrong number of arguments for 'get' command", client.do("GET", "a", "b").Error)
+	expectEqual(t, "ERR wrong number of arguments for 'config|get' command", client.do("CONFIG", "GET").Error)
+	expectEqual(t, "ERR wrong number of arguments for 'config|set' command", client.do("CONFIG", "SET", "maxmemory", "1kb", "timeout").Error)
+	expectEqual(t, "ERR unknown subcommand 'nope'. Try CONFIG HELP.", client.do("CONFIG", "nope").Error)
+}
+
+func Test_SubcommandHelp(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	help := client.do("CONFIG", "HELP").Array
+	expectEqual(t, 6, len(help))
+	expectEqual(t, "CONFIG <subcommand> [<arg> [value] [opt] ...]. Subcommands are:", help[0].String)
+	expectEqual(t, "GET pattern [pattern ...]", help[1].String)
+	expectEqual(t, "SET parameter value [parameter value ...]", help[2].String)
+	expectEqual(t, "HELP", help[5].String)
+	expectEqual(t, "NO-EVICT", strings.Fields(client.do("CLIENT", "HELP").Array[13].String)[0])
+	expectEqual(t, "ERR unknown subcommand 'nope'. Try CLIENT HELP.", client.do("client", "nope").Error)
+	expectEqual(t, "ERR wrong number of arguments for 'config|help' command", client.do("CONFIG", "HELP", "x").Error)
+}
@@ -0,0 +1,450 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// commandHandler runs a command once the checks common to all commands passed
+type commandHandler func(app *App, ctx context.Context, args []string) (types.RawCmd, error)
+
+// withoutContext adapts the handlers that do not need the context
+func withoutContext(handler func(app *App, args []string) (types.RawCmd, error)) commandHandler {
+	return func(app *App, _ context.Context, args []string) (types.RawCmd, error) {
+		return handler(app, args)
+	}
+}
+
+// commandSpec describes a command: how to run it, the checks done before and
+// what COMMAND replies about it
+type commandSpec struct {
+	// lower case name, `parent|subcommand` for subcommands
+	name string
+	// number of arguments including the command name (and the subcommand
+	// name), a negative arity is a minimum
+	arity int
+	flags commandFlag
+	// ACL categories, without the @, the ones implied by the flags are added
+	// when the table is built
+	categories []string
+	keys       []keySpec
+	// arguments that are pub/sub channels
+	channels *argRange
+	// group of the command documentation, e.g. `string`
+	group string
+	// struct of pkg/types/cmd the arguments are parsed into, nil when the
+	// handler parses them itself
+	args reflect.Type
+	// subcommands are run by the handler of their container
+	handler commandHandler
+
+	// set for container commands, keyed by lower case subcommand name
+	subcommands map[string]*commandSpec
+	parent      *commandSpec
+}
+
+func (spec *commandSpec) has(flag commandFlag) bool {
+	return spec.flags&flag != 0
+}
+
+func (spec *commandSpec) checkArity(argc int) bool {
+	if spec.arity < 0 {
+		return argc >= -spec.arity
+	}
+	return argc == spec.arity
+}
+
+type commandFlag int
+
+// command flags, in the order COMMAND INFO lists them
+const (
+	flagWrite commandFlag = 1 << iota
+	flagReadonly
+	// refused while the memory is over maxmemory
+	flagDenyOOM
+	flagAdmin
+	flagPubSub
+	flagBlocking
+	flagFast
+	// can be run by a client that is not authenticated
+	flagNoAuth
+	// does not write to the keyspace but is delayed by CLIENT PAUSE WRITE
+	// like writes are
+	flagMayReplicate
+)
+
+var commandFlagNames = []string{"write", "readonly", "denyoom", "admin", "pubsub", "blocking", "fast", "no_auth", "may_replicate"}
+
+func (f commandFlag) names() []string {
+	var names []string
+	for idx, name := range commandFlagNames {
+		if f&(1<<idx) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// impliedCategories returns the ACL categories that follow from the flags,
+// like Redis every command is either @fast or @slow
+func (f commandFlag) impliedCategories() []string {
+	var categories []string
+	if f&flagWrite != 0 {
+		categories = append(categories, categoryWrite)
+	}
+	if f&flagReadonly != 0 {
+		categories = append(categories, categoryRead)
+	}
+	if f&flagAdmin != 0 {
+		categories = append(categories, categoryAdmin, categoryDangerous)
+	}
+	if f&flagPubSub != 0 {
+		categories = append(categories, categoryPubSub)
+	}
+	if f&flagBlocking != 0 {
+		categories = append(categories, categoryBlocking)
+	}
+	if f&flagFast != 0 {
+		categories = append(categories, categoryFast)
+	} else {
+		categories = append(categories, categorySlow)
+	}
+	return categories
+}
+
+// argRange selects arguments by index, first to last by step. A negative last
+// counts from the end, -1 being the last argument.
+type argRange struct {
+	first, last, step int
+}
+
+func (r argRange) indexes(args []string) []int {
+	last := r.last
+	if last < 0 {
+		last += len(args)
+	}
+	step := max(r.step, 1)
+
+	var indexes []int
+	for idx := r.first; idx <= last && idx < len(args); idx += step {
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
+
+// keyAccess is what a command does with a key, commands that neither read
+// nor write its value (e.g. EXISTS) have no access
+type keyAccess int
+
+const (
+	keyRead keyAccess = 1 << iota
+	keyWrite
+)
+
+type keySpec struct {
+	argRange
+	access keyAccess
+}
+
+func keyAt(idx int, access keyAccess) keySpec {
+	return keySpec{argRange{idx, idx, 1}, access}
+}
+
+func keysFrom(first, last int, access keyAccess) keySpec {
+	return keySpec{argRange{first, last, 1}, access}
+}
+
+// ACL categories
+const (
+	categoryKeyspace    = "keyspace"
+	categoryRead        = "read"
+	categoryWrite       = "write"
+	categorySet         = "set"
+	categorySortedSet   = "sortedset"
+	categoryList        = "list"
+	categoryHash        = "hash"
+	categoryString      = "string"
+	categoryBitmap      = "bitmap"
+	categoryHyperLog    = "hyperloglog"
+	categoryGeo         = "geo"
+	categoryStream      = "stream"
+	categoryPubSub      = "pubsub"
+	categoryAdmin       = "admin"
+	categoryFast        = "fast"
+	categorySlow        = "slow"
+	categoryBlocking    = "blocking"
+	categoryDangerous   = "dangerous"
+	categoryConnection  = "connection"
+	categoryTransaction = "transaction"
+	categoryScripting   = "scripting"
+)
+
+// aclCategories are in the order of ACL CAT
+var aclCategories = []string{
+	categoryKeyspace, categoryRead, categoryWrite, categorySet, categorySortedSet,
+	categoryList, categoryHash, categoryString, categoryBitmap, categoryHyperLog,
+	categoryGeo, categoryStream, categoryPubSub, categoryAdmin, categoryFast,
+	categorySlow, categoryBlocking, categoryDangerous, categoryConnection,
+	categoryTransaction, categoryScripting,
+}
+
+func argsOf[T any]() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// commandTable has every command handled by HandleCommand, keyed by lower
+// case name. It is built in init since the handlers refer to it.
+var commandTable map[string]*commandSpec
+
+func init() {
+	commandTable = buildCommandTable(map[string][]*commandSpec{
+		"generic": {
+			{name: "type", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, 0)}, args: argsOf[cmd.TYPE](), handler: (*App).handleTYPE},
+			{name: "expire", arity: -3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.EXPIRE](), handler: (*App).handleEXPIRE},
+			{name: "pexpire", arity: -3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.PEXPIRE](), handler: (*App).handlePEXPIRE},
+			{name: "expireat", arity: -3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.EXPIREAT](), handler: (*App).handleEXPIREAT},
+			{name: "pexpireat", arity: -3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.PEXPIREAT](), handler: (*App).handlePEXPIREAT},
+			{name: "ttl", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.TTL](), handler: (*App).handleTTL},
+			{name: "pttl", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.PTTL](), handler: (*App).handlePTTL},
+			{name: "expiretime", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.EXPIRETIME](), handler: (*App).handleEXPIRETIME},
+			{name: "pexpiretime", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.PEXPIRETIME](), handler: (*App).handlePEXPIRETIME},
+			{name: "persist", arity: 2, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.PERSIST](), handler: (*App).handlePERSIST},
+			{name: "del", arity: -2, flags: flagWrite, categories: []string{categoryKeyspace}, keys: []keySpec{keysFrom(1, -1, keyWrite)}, args: argsOf[cmd.DEL](), handler: (*App).handleDEL},
+			{name: "unlink", arity: -2, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keysFrom(1, -1, keyWrite)}, args: argsOf[cmd.UNLINK](), handler: (*App).handleUNLINK},
+			{name: "exists", arity: -2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keysFrom(1, -1, 0)}, args: argsOf[cmd.EXISTS](), handler: (*App).handleEXISTS},
+			{name: "touch", arity: -2, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keysFrom(1, -1, 0)}, args: argsOf[cmd.TOUCH](), handler: (*App).handleTOUCH},
+			{name: "keys", arity: 2, flags: flagReadonly, categories: []string{categoryKeyspace, categoryDangerous}, args: argsOf[cmd.KEYS](), handler: (*App).handleKEYS},
+			{name: "rename", arity: 3, flags: flagWrite, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead|keyWrite), keyAt(2, keyWrite)}, args: argsOf[cmd.RENAME](), handler: (*App).handleRENAME},
+			{name: "renamenx", arity: 3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead|keyWrite), keyAt(2, keyWrite)}, args: argsOf[cmd.RENAMENX](), handler: (*App).handleRENAMENX},
+			{name: "copy", arity: -3, flags: flagWrite | flagDenyOOM, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyRead), keyAt(2, keyWrite)}, args: argsOf[cmd.COPY](), handler: (*App).handleCOPY},
+			{name: "randomkey", arity: 1, flags: flagReadonly, categories: []string{categoryKeyspace}, args: argsOf[cmd.RANDOMKEY](), handler: (*App).handleRANDOMKEY},
+			{name: "dbsize", arity: 1, flags: flagReadonly | flagFast, categories: []string{categoryKeyspace}, args: argsOf[cmd.DBSIZE](), handler: (*App).handleDBSIZE},
+			{name: "flushdb", arity: -1, flags: flagWrite, categories: []string{categoryKeyspace, categoryDangerous}, args: argsOf[cmd.FLUSHDB](), handler: (*App).handleFLUSHDB},
+			{name: "flushall", arity: -1, flags: flagWrite, categories: []string{categoryKeyspace, categoryDangerous}, args: argsOf[cmd.FLUSHALL](), handler: withoutContext((*App).handleFLUSHALL)},
+			{name: "scan", arity: -2, flags: flagReadonly, categories: []string{categoryKeyspace}, args: argsOf[cmd.SCAN](), handler: (*App).handleSCAN},
+			{name: "move", arity: 3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.MOVE](), handler: (*App).handleMOVE},
+			{name: "swapdb", arity: 3, flags: flagWrite | flagFast, categories: []string{categoryKeyspace, categoryDangerous}, args: argsOf[cmd.SWAPDB](), handler: (*App).handleSWAPDB},
+			{name: "object", arity: -2, args: argsOf[cmd.OBJECT](), handler: (*App).handleOBJECT, subcommands: subcommands(
+				&commandSpec{name: "encoding", arity: 3, flags: flagReadonly, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(2, 0)}, args: argsOf[cmd.OBJECT_ENCODING]()},
+				&commandSpec{name: "idletime", arity: 3, flags: flagReadonly, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(2, 0)}, args: argsOf[cmd.OBJECT_IDLETIME]()},
+				&commandSpec{name: "freq", arity: 3, flags: flagReadonly, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(2, 0)}, args: argsOf[cmd.OBJECT_FREQ]()},
+				&commandSpec{name: "refcount", arity: 3, flags: flagReadonly, categories: []string{categoryKeyspace}, keys: []keySpec{keyAt(2, 0)}, args: argsOf[cmd.OBJECT_REFCOUNT]()},
+				&commandSpec{name: "help", arity: 2, categories: []string{categoryKeyspace}, args: argsOf[cmd.HELP]()},
+			)},
+		},
+
+		"connection": {
+			{name: "ping", arity: -1, flags: flagFast, categories: []string{categoryConnection}, args: argsOf[cmd.PING](), handler: withoutContext((*App).handlePING)},
+			{name: "echo", arity: 2, flags: flagFast, categories: []string{categoryConnection}, args: argsOf[cmd.ECHO](), handler: withoutContext((*App).handleECHO)},
+			{name: "select", arity: 2, flags: flagFast, categories: []string{categoryConnection}, args: argsOf[cmd.SELECT](), handler: (*App).handleSELECT},
+			{name: "hello", arity: -1, flags: flagFast | flagNoAuth, categories: []string{categoryConnection}, args: argsOf[cmd.HELLO](), handler: (*App).handleHELLO},
+			{name: "auth", arity: -2, flags: flagFast | flagNoAuth, categories: []string{categoryConnection}, args: argsOf[cmd.AUTH](), handler: (*App).handleAUTH},
+			{name: "client", arity: -2, args: argsOf[cmd.CLIENT](), handler: (*App).handleCLIENT, subcommands: subcommands(
+				&commandSpec{name: "tracking", arity: -3, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_TRACKING]()},
+				&commandSpec{name: "caching", arity: 3, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_CACHING]()},
+				&commandSpec{name: "getredir", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_GETREDIR]()},
+				&commandSpec{name: "id", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_ID]()},
+				&commandSpec{name: "setname", arity: 3, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_SETNAME]()},
+				&commandSpec{name: "getname", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_GETNAME]()},
+				&commandSpec{name: "list", arity: -2, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_LIST]()},
+				&commandSpec{name: "info", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_INFO]()},
+				&commandSpec{name: "kill", arity: -3, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_KILL]()},
+				&commandSpec{name: "pause", arity: -3, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_PAUSE]()},
+				&commandSpec{name: "unpause", arity: 2, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_UNPAUSE]()},
+				&commandSpec{name: "unblock", arity: -3, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_UNBLOCK]()},
+				&commandSpec{name: "no-evict", arity: 3, flags: flagAdmin, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_NOEVICT]()},
+				&commandSpec{name: "reply", arity: 3, categories: []string{categoryConnection}, args: argsOf[cmd.CLIENT_REPLY]()},
+				&commandSpec{name: "help", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.HELP]()},
+			)},
+		},
+
+		"server": {
+			{name: "shutdown", arity: -1, flags: flagAdmin, args: argsOf[cmd.SHUTDOWN](), handler: withoutContext((*App).handleSHUTDOWN)},
+			{name: "config", arity: -2, args: argsOf[cmd.CONFIG](), handler: withoutContext((*App).handleCONFIG), subcommands: subcommands(
+				&commandSpec{name: "get", arity: -3, flags: flagAdmin, args: argsOf[cmd.CONFIG_GET]()},
+				&commandSpec{name: "set", arity: -4, flags: flagAdmin, args: argsOf[cmd.CONFIG_SET]()},
+				&commandSpec{name: "rewrite", arity: 2, flags: flagAdmin, args: argsOf[cmd.CONFIG_REWRITE]()},
+				&commandSpec{name: "resetstat", arity: 2, flags: flagAdmin, args: argsOf[cmd.CONFIG_RESETSTAT]()},
+				&commandSpec{name: "help", arity: 2, args: argsOf[cmd.HELP]()},
+			)},
+			{name: "memory", arity: -2, args: argsOf[cmd.MEMORY](), handler: (*App).handleMEMORY, subcommands: subcommands(
+				&commandSpec{name: "usage", arity: -3, flags: flagReadonly, keys: []keySpec{keyAt(2, 0)}, args: argsOf[cmd.MEMORY_USAGE]()},
+				&commandSpec{name: "stats", arity: 2, args: argsOf[cmd.MEMORY_STATS]()},
+				&commandSpec{name: "doctor", arity: 2, args: argsOf[cmd.MEMORY_DOCTOR]()},
+				&commandSpec{name: "purge", arity: 2, args: argsOf[cmd.MEMORY_PURGE]()},
+				&commandSpec{name: "help", arity: 2, args: argsOf[cmd.HELP]()},
+			)},
+			{name: "info", arity: -1, categories: []string{categoryDangerous}, args: argsOf[cmd.INFO](), handler: withoutContext((*App).handleINFO)},
+			{name: "acl", arity: -2, args: argsOf[cmd.ACL](), handler: (*App).handleACL, subcommands: subcommands(
+				&commandSpec{name: "setuser", arity: -3, flags: flagAdmin, args: argsOf[cmd.ACL_SETUSER]()},
+				&commandSpec{name: "getuser", arity: 3, flags: flagAdmin, args: argsOf[cmd.ACL_GETUSER]()},
+				&commandSpec{name: "deluser", arity: -3, flags: flagAdmin, args: argsOf[cmd.ACL_DELUSER]()},
+				&commandSpec{name: "list", arity: 2, flags: flagAdmin, args: argsOf[cmd.ACL_LIST]()},
+				&commandSpec{name: "users", arity: 2, flags: flagAdmin, args: argsOf[cmd.ACL_USERS]()},
+				&commandSpec{name: "whoami", arity: 2, args: argsOf[cmd.ACL_WHOAMI]()},
+				&commandSpec{name: "cat", arity: -2, args: argsOf[cmd.ACL_CAT]()},
+				&commandSpec{name: "genpass", arity: -2, args: argsOf[cmd.ACL_GENPASS]()},
+				&commandSpec{name: "log", arity: -2, flags: flagAdmin, args: argsOf[cmd.ACL_LOG]()},
+				&commandSpec{name: "dryrun", arity: -4, flags: flagAdmin, args: argsOf[cmd.ACL_DRYRUN]()},
+				&commandSpec{name: "load", arity: 2, flags: flagAdmin, args: argsOf[cmd.ACL_LOAD]()},
+				&commandSpec{name: "save", arity: 2, flags: flagAdmin, args: argsOf[cmd.ACL_SAVE]()},
+				&commandSpec{name: "help", arity: 2, args: argsOf[cmd.HELP]()},
+			)},
+			{name: "command", arity: -1, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND](), handler: withoutContext((*App).handleCOMMAND), subcommands: subcommands(
+				&commandSpec{name: "count", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND_COUNT]()},
+				&commandSpec{name: "list", arity: -2, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND_LIST]()},
+				&commandSpec{name: "info", arity: -2, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND_INFO]()},
+				&commandSpec{name: "docs", arity: -2, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND_DOCS]()},
+				&commandSpec{name: "getkeys", arity: -3, categories: []string{categoryConnection}, args: argsOf[cmd.COMMAND_GETKEYS]()},
+				&commandSpec{name: "help", arity: 2, categories: []string{categoryConnection}, args: argsOf[cmd.HELP]()},
+			)},
+		},
+
+		"pubsub": {
+			{name: "subscribe", arity: -2, flags: flagPubSub, channels: &argRange{1, -1, 1}, args: argsOf[cmd.SUBSCRIBE](), handler: (*App).handleSUBSCRIBE},
+			{name: "unsubscribe", arity: -1, flags: flagPubSub, args: argsOf[cmd.UNSUBSCRIBE](), handler: (*App).handleUNSUBSCRIBE},
+			{name: "publish", arity: 3, flags: flagPubSub | flagFast | flagMayReplicate, channels: &argRange{1, 1, 1}, args: argsOf[cmd.PUBLISH](), handler: withoutContext((*App).handlePUBLISH)},
+		},
+
+		"string": {
+			{name: "set", arity: -3, flags: flagWrite | flagDenyOOM, categories: []string{categoryString}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.SET](), handler: (*App).handleSET},
+			{name: "get", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryString}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.GET](), handler: (*App).handleGET},
+			{name: "append", arity: 3, flags: flagWrite | flagDenyOOM | flagFast, categories: []string{categoryString}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.APPEND](), handler: (*App).handleAPPEND},
+		},
+
+		"list": {
+			{name: "lpush", arity: -3, flags: flagWrite | flagDenyOOM | flagFast, categories: []string{categoryList}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.LPUSH](), handler: (*App).handleLPUSH},
+			{name: "rpush", arity: -3, flags: flagWrite | flagDenyOOM | flagFast, categories: []string{categoryList}, keys: []keySpec{keyAt(1, keyWrite)}, args: argsOf[cmd.RPUSH](), handler: (*App).handleRPUSH},
+			{name: "lrange", arity: 4, flags: flagReadonly, categories: []string{categoryList}, keys: []keySpec{keyAt(1, keyRead)}, args: argsOf[cmd.LRANGE](), handler: (*App).handleLRANGE},
+			{name: "llen", arity: 2, flags: flagReadonly | flagFast, categories: []string{categoryList}, keys: []keySpec{keyAt(1, 0)}, args: argsOf[cmd.LLEN](), handler: (*App).handleLLEN},
+			{name: "lpop", arity: -2, flags: flagWrite | flagFast, categories: []string{categoryList}, keys: []keySpec{keyAt(1, keyRead|keyWrite)}, args: argsOf[cmd.LPOP](), handler: (*App).handleLPOP},
+			{name: "rpop", arity: -2, flags: flagWrite | flagFast, categories: []string{categoryList}, keys: []keySpec{keyAt(1, keyRead|keyWrite)}, args: argsOf[cmd.RPOP](), handler: (*App).handleRPOP},
+			{name: "blpop", arity: -3, flags: flagWrite | flagBlocking, categories: []string{categoryList}, keys: []keySpec{keysFrom(1, -2, keyRead|keyWrite)}, args: argsOf[cmd.BLPOP](), handler: (*App).handleBLPOP},
+		},
+	})
+	if err := registerArgs(commandTable); err != nil {
+		panic(err)
+	}
+}
+
+func subcommands(specs ...*commandSpec) map[string]*commandSpec {
+	result := make(map[string]*commandSpec, len(specs))
+	for _, spec := range specs {
+		result[spec.name] = spec
+	}
+	return result
+}
+
+// buildCommandTable indexes the specs of each group by name and fills what
+// the specs get from their group, flags and container
+func buildCommandTable(groups map[string][]*commandSpec) map[string]*commandSpec {
+	table := map[string]*commandSpec{}
+	for group, specs := range groups {
+		for _, spec := range specs {
+			spec.group = group
+			spec.categories = append(spec.categories, spec.flags.impliedCategories()...)
+			for name, sub := range spec.subcommands {
+				sub.name = spec.name + "|" + name
+				sub.group = group
+				sub.categories = append(sub.categories, sub.flags.impliedCategories()...)
+				sub.handler = spec.handler
+				sub.parent = spec
+			}
+			table[spec.name] = spec
+		}
+	}
+	return table
+}
+
+// registerArgs compiles the argument structs of the commands so a broken one
+// stops the server at startup instead of failing its first call
+func registerArgs(table map[string]*commandSpec) error {
+	var argTypes []reflect.Type
+	for _, spec := range table {
+		if spec.args != nil {
+			argTypes = append(argTypes, spec.args)
+		}
+		for _, sub := range spec.subcommands {
+			if sub.args != nil {
+				argTypes = append(argTypes, sub.args)
+			}
+		}
+	}
+	return argsparser.Register(argTypes...)
+}
+
+// lookupCommand returns the spec of the command args run, the container spec
+// when the subcommand is unknown, or nil when the command is unknown
+func lookupCommand(args []string) *commandSpec {
+	spec, exists := commandTable[strings.ToLower(args[0])]
+	if !exists {
+		return nil
+	}
+	if spec.subcommands != nil && len(args) > 1 {
+		if sub, exists := spec.subcommands[strings.ToLower(args[1])]; exists {
+			return sub
+		}
+	}
+	return spec
+}
+
+// lookupCommandByName returns the spec of a command by its full name, e.g.
+// `config|get`, or nil when there is none
+func lookupCommandByName(name string) *commandSpec {
+	parent, sub, isSub := strings.Cut(strings.ToLower(name), "|")
+	spec := commandTable[parent]
+	if spec == nil || !isSub {
+		return spec
+	}
+	return spec.subcommands[sub]
+}
+
+// commandFullName returns the name of the command as shown by CLIENT LIST,
+// e.g. `client|list`
+func commandFullName(args []string) string {
+	spec := lookupCommand(args)
+	switch {
+	case spec == nil:
+		return strings.ToLower(args[0])
+	case spec.subcommands != nil && len(args) > 1:
+		return spec.name + "|" + strings.ToLower(args[1])
+	default:
+		return spec.name
+	}
+}
+
+// sortedSpecs returns the specs sorted by name
+func sortedSpecs(specs map[string]*commandSpec) []*commandSpec {
+	sorted := make([]*commandSpec, 0, len(specs))
+	for _, spec := range specs {
+		sorted = append(sorted, spec)
+	}
+	slices.SortFunc(sorted, func(a, b *commandSpec) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return sorted
+}
+
+// allCommandSpecs returns the specs of the commands and subcommands sorted by
+// name, container commands are replaced by their subcommands
+func allCommandSpecs() []*commandSpec {
+	var specs []*commandSpec
+	for _, spec := range commandTable {
+		if spec.subcommands == nil {
+			specs = append(specs, spec)
+		}
+		for _, sub := range spec.subcommands {
+			specs = append(specs, sub)
+		}
+	}
+	slices.SortFunc(specs, func(a, b *commandSpec) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return specs
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
 	"github.com/codecrafters-io/redis-starter-go/pkg/types"
 	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
 )
@@ -18,58 +20,90 @@ func (app *App) HandleCommand(ctx context.Context, cmd types.RawCmd) (result typ
 		return types.RawCmd{}, err
 	}
 
+	// commands are executed one at a time, like Redis does
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+
+	if app.shuttingDown.Load() {
+		return types.RawCmd{}, errShuttingDown
+	}
+
 	command := args[0]
-	switch strings.ToUpper(command) {
-	// generic
-	case "TYPE":
-		result, err = app.handleTYPE(args)
-
-	// connections
-	case "PING":
-		result, err = app.handlePING(args)
-	case "ECHO":
-		result, err = app.handleECHO(args)
-
-	// strings
-	case "SET":
-		result, err = app.handleSET(args)
-	case "GET":
-		result, err = app.handleGET(args)
-	case "APPEND":
-		result, err = app.handleAPPEND(args)
-
-	// list
-	case "LPUSH":
-		result, err = app.handleLPUSH(args)
-	case "RPUSH":
-		result, err = app.handleRPUSH(args)
-	case "LRANGE":
-		result, err = app.handleLRANGE(args)
-	case "LLEN":
-		result, err = app.handleLLEN(args)
-	case "LPOP":
-		result, err = app.handleLPOP(args)
-	case "RPOP":
-		result, err = app.handleRPOP(args)
-	case "BLPOP":
-		result, err = app.handleBLPOP(ctx, args)
-	default:
-		err = fmt.Errorf("unknown command `%s`", command)
+	upperCommand := strings.ToUpper(command)
+	spec := lookupCommand(args)
+
+	client := GetClientFromContext(ctx)
+	if client != nil {
+		// CLIENT REPLY SKIP only applies to the command that follows it
+		skipReply := client.replySkipNext
+		client.replySkipNext = false
+		client.lastCommand = commandFullName(args)
+		client.lastInteraction = time.Now()
+		client.inCommand = true
+		defer func() {
+			client.noReply = client.replyOff || skipReply || client.replySkipNext
+			client.lastInteraction = time.Now()
+			client.inCommand = false
+		}()
+
+		if spec != nil {
+			if err := app.checkACL(client, spec, args); err != nil {
+				return types.RawCmd{}, NewHandleCommandError(command, err)
+			}
+		}
+		if client.protocol == encoding.ProtocolRESP2 && len(client.subscriptions) != 0 && !slices.Contains(subscribedContextCommands, upperCommand) {
+			err = NewCodedError(ErrorCodeERR, fmt.Sprintf("Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", strings.ToLower(command)))
+			return types.RawCmd{}, NewHandleCommandError(command, err)
+		}
+		// CLIENT CACHING only applies to the command that follows it
+		defer func(caching bool) {
+			client.tracking.caching = caching
+		}(upperCommand == "CLIENT" && client.tracking.caching)
 	}
 
+	if err := app.waitClientPause(client, spec); err != nil {
+		return types.RawCmd{}, err
+	}
+
+	if !app.performEvictions() && spec != nil && spec.has(flagDenyOOM) {
+		return types.RawCmd{}, NewHandleCommandError(command, NewOOMError())
+	}
+
+	result, err = app.call(ctx, spec, args)
+
+	app.stats.totalCommandsProcessed += 1
+
 	if err != nil {
-		err = NewHandleCommandError(command, err)
+		app.stats.totalErrorReplies += 1
+		// argument errors of a subcommand are reported with its full name
+		name := command
+		if spec != nil {
+			name = spec.name
+		}
+		err = NewHandleCommandError(name, err)
 	}
 
 	return
 }
 
-func (app *App) handleTYPE(args []string) (types.RawCmd, error) {
+// call runs the handler of a command once its arguments are counted
+func (app *App) call(ctx context.Context, spec *commandSpec, args []string) (types.RawCmd, error) {
+	if spec == nil {
+		return types.RawCmd{}, NewUnknownCommandError(args[0], args[1:])
+	}
+	if !spec.checkArity(len(args)) {
+		return types.RawCmd{}, NewWrongNumberOfArgumentsError(spec.name)
+	}
+	return spec.handler(app, ctx, args)
+}
+
+func (app *App) handleTYPE(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.TYPE](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
-	value, exists := app.dict[c.Key]
+	db := app.selectedDB(ctx)
+	value, exists := app.lookupKeyRead(ctx, db, c.Key)
 	if !exists {
 		return types.NewStringRawCmd("none"), nil
 	}
@@ -92,27 +126,54 @@ func (app *App) handleECHO(args []string) (types.RawCmd, error) {
 	return types.NewStringRawCmd(c.Message), nil
 }
 
-func (app *App) handleAPPEND(args []string) (types.RawCmd, error) {
+func (app *App) handleAPPEND(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.APPEND](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
-	value := app.dict[c.Key]
+	value, exists := app.lookupKeyWrite(ctx, db, c.Key)
+	if exists && value.ValueType != ValueTypeString {
+		return types.RawCmd{}, NewWrongTypeError(ValueTypeString, value.ValueType)
+	}
+	value.ValueType = ValueTypeString
 	value.String += c.Value
-
-	// TODO: notify keyspace, check expiry and check type
+	app.setKey(ctx, db, c.Key, value)
+	app.notifyKeyspaceEvent(config.NotifyString, "append", c.Key, db.id)
 
 	return types.NewIntegerRawCmd(int64(len(value.String))), nil
 }
 
-func (app *App) handleSET(args []string) (types.RawCmd, error) {
+func (app *App) handleSET(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.SET](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
-	_, oldValueExists := app.dict[c.Key]
+	var expireAt time.Time
+	if c.Expire.Key != "" && c.Expire.Key != "KEEPTTL" {
+		var at int64
+		switch c.Expire.Key {
+		case "EX":
+			at, err = expireTimeInMilliseconds(time.Now().UnixMilli(), int64(c.Expire.EX), 1000, true)
+		case "PX":
+			at, err = expireTimeInMilliseconds(time.Now().UnixMilli(), int64(c.Expire.PX), 1, true)
+		case "EXAT":
+			at, err = expireTimeInMilliseconds(0, int64(c.Expire.EXAT), 1000, true)
+		case "PXAT":
+			at, err = expireTimeInMilliseconds(0, int64(c.Expire.PXAT), 1, true)
+		default:
+			panic("should not get to here")
+		}
+		if err != nil {
+			return types.RawCmd{}, NewInvalidExpireTimeError(args[0])
+		}
+		expireAt = time.UnixMilli(at)
+	}
+
+	_, oldValueExists := app.lookupKeyWrite(ctx, db, c.Key)
 
 	if c.SetKey.Key != "" {
 		if c.SetKey.NX && oldValueExists {
@@ -124,33 +185,17 @@ func (app *App) handleSET(args []string) (types.RawCmd, error) {
 	}
 
 	value := Value{
-		Key:       c.Key,
 		String:    c.Value,
 		ValueType: ValueTypeString,
 	}
-	app.dict[c.Key] = value
-	app.NotifyKeySpace(value)
-
-	if c.Expire.Key != "" {
-		now := time.Now()
-		switch c.Expire.Key {
-		case "EX":
-			expireAt := now.Add(time.Second * time.Duration(c.Expire.EX))
-			app.expiry[c.Key] = expireAt
-		case "PX":
-			expireAt := now.Add(time.Millisecond * time.Duration(c.Expire.PX))
-			app.expiry[c.Key] = expireAt
-		case "EXAT":
-			expireAt := time.Unix(int64(c.Expire.EXAT), 0)
-			app.expiry[c.Key] = expireAt
-		case "PXAT":
-			expireAt := time.UnixMilli(int64(c.Expire.PXAT))
-			app.expiry[c.Key] = expireAt
-		case "KEEPTTL":
-			// DO nothing
-		default:
-			panic("should not get to here")
-		}
+	app.setKey(ctx, db, c.Key, value)
+	if c.Expire.Key != "KEEPTTL" {
+		db.removeExpire(c.Key)
+	}
+	app.notifyKeyspaceEvent(config.NotifyString, "set", c.Key, db.id)
+	if !expireAt.IsZero() {
+		db.setExpire(c.Key, expireAt)
+		app.notifyKeyspaceEvent(config.NotifyGeneric, "expire", c.Key, db.id)
 	}
 
 	if c.GET {
@@ -159,77 +204,72 @@ func (app *App) handleSET(args []string) (types.RawCmd, error) {
 	return types.NewStringRawCmd("OK"), nil
 }
 
-func (app *App) handleGET(args []string) (types.RawCmd, error) {
+func (app *App) handleGET(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.GET](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
-	value, exists := app.dict[c.Key]
+	value, exists := app.lookupKeyRead(ctx, db, c.Key)
 	if !exists {
 		return types.NewNullRawCmd(), nil
 	}
 	if value.ValueType != ValueTypeString {
 		return types.RawCmd{}, NewWrongTypeError(ValueTypeString, value.ValueType)
 	}
-	if expireTime, expireExists := app.expiry[c.Key]; expireExists && time.Now().After(expireTime) {
-		delete(app.dict, c.Key)
-		delete(app.expiry, c.Key)
-		return types.NewNullRawCmd(), nil
-	}
 
 	return types.NewBulkStringRawCmd(value.String), nil
 }
 
-func (app *App) handleGenericPUSH(key string, newValues []string, fromLeft bool) (types.RawCmd, error) {
-	value, exists := app.dict[key]
+func (app *App) handleGenericPUSH(ctx context.Context, key string, newValues []string, fromLeft bool) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	value, exists := app.lookupKeyWrite(ctx, db, key)
 	if exists && value.ValueType != ValueTypeList {
 		return types.RawCmd{}, NewWrongTypeError(ValueTypeString, value.ValueType)
 	}
-	if expireTime, expireExists := app.expiry[key]; expireExists && time.Now().After(expireTime) {
-		delete(app.dict, key)
-		delete(app.expiry, key)
-		return types.NewNullRawCmd(), nil
+	if !exists {
+		// a new list starts as a listpack
+		value = Value{ValueType: ValueTypeList, encoding: EncodingListpack}
 	}
-	value.Key = key
-	value.ValueType = ValueTypeList
+	event := "rpush"
 	if fromLeft {
 		slices.Reverse(newValues)
-		value.List = append(newValues, value.List...)
-	} else {
-		value.List = append(value.List, newValues...)
+		event = "lpush"
 	}
-	app.dict[key] = value
-	app.NotifyKeySpace(value)
-	app.NotifyAndPopBLPOPConsumer(key)
+	value.listPush(newValues, fromLeft)
+	app.setKey(ctx, db, key, value)
+	app.notifyKeyspaceEvent(config.NotifyList, event, key, db.id)
+	app.NotifyAndPopBLPOPConsumer(ctx, db, key)
 
-	return types.NewIntegerRawCmd(int64(len(value.List))), nil
+	return types.NewIntegerRawCmd(int64(value.listLen())), nil
 }
 
-func (app *App) handleLPUSH(args []string) (types.RawCmd, error) {
+func (app *App) handleLPUSH(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.LPUSH](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
-	return app.handleGenericPUSH(c.Key, c.Values, true)
+	return app.handleGenericPUSH(ctx, c.Key, c.Values, true)
 }
 
-func (app *App) handleRPUSH(args []string) (types.RawCmd, error) {
+func (app *App) handleRPUSH(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.RPUSH](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
-	return app.handleGenericPUSH(c.Key, c.Values, false)
+	return app.handleGenericPUSH(ctx, c.Key, c.Values, false)
 }
 
-func (app *App) handleLRANGE(args []string) (types.RawCmd, error) {
+func (app *App) handleLRANGE(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.LRANGE](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
-	value, exists := app.dict[c.Key]
-	length := len(value.List)
+	value, exists := app.lookupKeyRead(ctx, db, c.Key)
+	length := value.listLen()
 
 	start := c.Start
 	if start < 0 {
@@ -246,70 +286,73 @@ func (app *App) handleLRANGE(args []string) (types.RawCmd, error) {
 		return types.NewBulkArrayBulkString(nil), nil
 	}
 
-	return types.NewBulkArrayBulkString(value.List[start:stop]), nil
+	return types.NewBulkArrayBulkString(value.listRange(start, stop)), nil
 }
 
-func (app *App) handleLLEN(args []string) (types.RawCmd, error) {
+func (app *App) handleLLEN(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.LLEN](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
-	value, exists := app.dict[c.Key]
+	value, exists := app.lookupKeyRead(ctx, db, c.Key)
 	if exists && value.ValueType != ValueTypeList {
 		return types.RawCmd{}, NewWrongTypeError(ValueTypeString, value.ValueType)
 	}
 
-	return types.NewIntegerRawCmd(int64(len(value.List))), nil
+	return types.NewIntegerRawCmd(int64(value.listLen())), nil
 }
 
-func (app *App) handleGenricPOP(key string, fromLeft bool, count *int) (types.RawCmd, error) {
-	value, exists := app.dict[key]
+func (app *App) handleGenricPOP(ctx context.Context, key string, fromLeft bool, count *int) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	value, exists := app.lookupKeyWrite(ctx, db, key)
 	if !exists {
 		return types.NewNullRawCmd(), nil
 	}
 	if exists && value.ValueType != ValueTypeList {
 		return types.RawCmd{}, NewWrongTypeError(ValueTypeString, value.ValueType)
 	}
-	if expireTime, expireExists := app.expiry[key]; expireExists && time.Now().After(expireTime) {
-		delete(app.dict, key)
-		delete(app.expiry, key)
-		return types.NewNullRawCmd(), nil
-	}
 
-	length := len(value.List)
+	length := value.listLen()
+	event := "rpop"
+	if fromLeft {
+		event = "lpop"
+	}
 
 	if count == nil {
 		if length == 0 {
 			return types.NewNullRawCmd(), nil
 		}
-		v := ""
-		v, value.List = splitListOne(value.List, fromLeft)
-		app.dict[key] = value
+		v := value.listPop(1, fromLeft)[0]
+		app.setKey(ctx, db, key, value)
+		app.notifyKeyspaceEvent(config.NotifyList, event, key, db.id)
+		app.deleteListIfEmpty(ctx, db, key, value)
 		return types.NewBulkStringRawCmd(v), nil
 	}
 
-	var vs []string
-	vs, value.List = splitList(value.List, fromLeft, *count)
-	app.dict[key] = value
+	vs := value.listPop(*count, fromLeft)
+	app.setKey(ctx, db, key, value)
+	app.notifyKeyspaceEvent(config.NotifyList, event, key, db.id)
+	app.deleteListIfEmpty(ctx, db, key, value)
 
 	return types.NewBulkArrayBulkString(vs), nil
 }
 
-func (app *App) handleLPOP(args []string) (types.RawCmd, error) {
+func (app *App) handleLPOP(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.LPOP](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
-	return app.handleGenricPOP(c.Key, true, c.Count)
+	return app.handleGenricPOP(ctx, c.Key, true, c.Count)
 }
 
-func (app *App) handleRPOP(args []string) (types.RawCmd, error) {
+func (app *App) handleRPOP(ctx context.Context, args []string) (types.RawCmd, error) {
 	c, err := argsparser.Parse[cmd.RPOP](args)
 	if err != nil {
 		return types.RawCmd{}, err
 	}
-	return app.handleGenricPOP(c.Key, false, c.Count)
+	return app.handleGenricPOP(ctx, c.Key, false, c.Count)
 }
 
 func (app *App) handleBLPOP(ctx context.Context, args []string) (types.RawCmd, error) {
@@ -317,6 +360,7 @@ func (app *App) handleBLPOP(ctx context.Context, args []string) (types.RawCmd, e
 	if err != nil {
 		return types.RawCmd{}, err
 	}
+	db := app.selectedDB(ctx)
 
 	// TODO: support multi list
 	if len(c.KeyRest) != 0 {
@@ -326,60 +370,70 @@ func (app *App) handleBLPOP(ctx context.Context, args []string) (types.RawCmd, e
 	v := ""
 
 	// non blocking
-	value, exists := app.dict[c.Key]
-	if exists && len(value.List) > 0 {
-		v, value.List = splitListOne(value.List, true)
-		app.dict[c.Key] = value
+	value, exists := app.lookupKeyWrite(ctx, db, c.Key)
+	if exists && value.listLen() > 0 {
+		v = value.listPop(1, true)[0]
+		app.setKey(ctx, db, c.Key, value)
+		app.notifyKeyspaceEvent(config.NotifyList, "lpop", c.Key, db.id)
+		app.deleteListIfEmpty(ctx, db, c.Key, value)
 		return types.NewBulkArrayBulkString([]string{c.Key, v}), nil
 	}
 
-	// TODO: implement true timeout infinite or some kind of max timeout of a transaction
-	// TODO: move to use transaction
-	timeoutDuration := time.Hour * 100
-	if c.TimeoutSecond != 0 {
-		timeoutDuration = time.Millisecond * time.Duration(int64(c.TimeoutSecond*1000))
+	// a timeout of 0 blocks forever
+	var timeout <-chan time.Time
+	if c.Timeout != 0 {
+		timer := time.NewTimer(time.Duration(c.Timeout))
+		defer timer.Stop()
+		timeout = timer.C
 	}
-	connId := GetIdFromContext(ctx)
-
-	consumer := app.SubscribeBLPOPConsumer(connId, c.Key)
-	defer app.UnsubscribeBLOPConsumer(connId, c.Key)
 
-	for {
-		select {
-		case <-ctx.Done():
-			// TODO: handle timeout error
-			return types.RawCmd{}, ctx.Err()
-		case <-time.After(timeoutDuration):
-			return types.NewNullRawCmd(), nil
-		case <-consumer:
-			cmd, err := app.handleGenricPOP(c.Key, true, nil)
-			if err != nil {
-				return types.RawCmd{}, err
-			}
-			return types.NewBulkArrayBulkString([]string{c.Key, cmd.BulkString}), nil
-		}
-	}
-}
-
-func splitList[T any](l []T, fromLeft bool, count int) ([]T, []T) {
-	n := len(l)
-	if n == 0 {
-		return nil, nil
+	connId := GetIdFromContext(ctx)
+	consumer := app.SubscribeBLPOPConsumer(db, connId, c.Key)
+	defer app.UnsubscribeBLOPConsumer(db, connId, c.Key)
+
+	// channels of a nil client are nil, they are never ready
+	var unblocked chan error
+	var disconnected <-chan struct{}
+	if client := GetClientFromContext(ctx); client != nil {
+		// the replies of the commands pipelined before are not held back
+		// while waiting
+		client.flush()
+		unblocked = make(chan error, 1)
+		client.unblock = unblocked
+		var stopWatching func()
+		disconnected, stopWatching = app.watchDisconnect(client)
+		defer func() {
+			client.unblock = nil
+			stopWatching()
+		}()
+	}
+
+	// let other clients run commands while waiting
+	app.mutex.Unlock()
+	select {
+	case v := <-consumer:
+		app.mutex.Lock()
+		return types.NewBulkArrayBulkString([]string{c.Key, v}), nil
+	case <-timeout:
+	case <-ctx.Done():
+	case <-disconnected:
+	case err = <-unblocked:
+	case <-app.shutdownCh:
+		err = NewCodedError(ErrorCodeUnblocked, "server is shutting down")
+	}
+	app.mutex.Lock()
+
+	// a push may have served the consumer before it got the mutex back, the
+	// element is already popped so it must be returned
+	select {
+	case v := <-consumer:
+		return types.NewBulkArrayBulkString([]string{c.Key, v}), nil
+	default:
 	}
-	if fromLeft {
-		count = max(count, 0)
-		count = min(count, n)
-		return l[:count], l[count:]
-	} else {
-		count = max(count, 0)
-		count = min(count, n)
-		return l[:n-count], l[n-count:]
+	if err != nil {
+		return types.RawCmd{}, err
 	}
-}
-
-func splitListOne[T any](l []T, fromLeft bool) (T, []T) {
-	a, b := splitList(l, fromLeft, 1)
-	return a[0], b
+	return types.NewNullRawCmd(), nil
 }
 
 func convertArgsCmdToString(cmd types.RawCmd) ([]string, error) {
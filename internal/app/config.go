@@ -0,0 +1,84 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// rdbPath is where snapshots are written, from the dir and dbfilename parameters
+func (app *App) rdbPath() string {
+	return filepath.Join(app.config.String("dir"), app.config.String("dbfilename"))
+}
+
+func (app *App) handleCONFIG(args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.CONFIG](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.GET != nil:
+		return app.handleCONFIGGET(*c.GET)
+	case c.SET != nil:
+		return app.handleCONFIGSET(*c.SET)
+	case c.REWRITE != nil:
+		return app.handleCONFIGREWRITE()
+	case c.RESETSTAT != nil:
+		return app.handleCONFIGRESETSTAT()
+	default: // HELP
+		return subcommandHelp[cmd.CONFIG]("config")
+	}
+}
+
+func (app *App) handleCONFIGGET(c cmd.CONFIG_GET) (types.RawCmd, error) {
+	values := map[string]string{}
+	for _, pattern := range append([]string{c.Pattern}, c.PatternRest...) {
+		maps.Copy(values, app.config.Match(pattern))
+	}
+
+	result := make(map[string]types.RawCmd, len(values))
+	for name, value := range values {
+		result[name] = types.NewBulkStringRawCmd(value)
+	}
+	return types.NewMapRawCmd(result), nil
+}
+
+func (app *App) handleCONFIGSET(c cmd.CONFIG_SET) (types.RawCmd, error) {
+	pairs := make([][2]string, 0, len(c.Pairs))
+	for _, pair := range c.Pairs {
+		pairs = append(pairs, [2]string{pair.Parameter, pair.Value})
+	}
+	if err := app.config.Set(pairs); err != nil {
+		return types.RawCmd{}, err
+	}
+	for _, pair := range pairs {
+		// requirepass is the password of the default user
+		if strings.EqualFold(pair[0], "requirepass") {
+			app.applyRequirepass()
+		}
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCONFIGREWRITE() (types.RawCmd, error) {
+	if err := app.config.Rewrite(); err != nil {
+		if errors.Is(err, config.ErrNoConfigFile) {
+			return types.RawCmd{}, err
+		}
+		return types.RawCmd{}, fmt.Errorf("Rewriting config file: %w", err)
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCONFIGRESETSTAT() (types.RawCmd, error) {
+	app.resetStats()
+	return types.NewStringRawCmd("OK"), nil
+}
@@ -1,14 +1,12 @@
 package app
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
 	"github.com/codecrafters-io/redis-starter-go/pkg/types"
@@ -29,49 +27,56 @@ func GetIdFromContext(ctx context.Context) ulid.ID {
 }
 
 func (app *App) HandleConnection(conn net.Conn) {
-	// TODO: timeout with SetWriteDeadline
-	bufReader := bufio.NewReader(conn)
-	for {
-		var err error
-		var res types.RawCmd
-
-		defer func() {
-			// TODO: only write if not a connection error
-			if err != nil {
-				_, _ = fmt.Fprintf(conn, "-%s\r\n", err.Error())
-			}
-		}()
+	client := app.registerClient(conn)
+	defer app.unregisterClient(client)
+	// send the replies still queued when leaving the loop
+	defer client.closeOutput()
 
-		res, err = encoding.UnmarshalCommand(bufReader)
+	for {
+		res, err := encoding.UnmarshalCommand(client.reader)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				err = nil
-			} else {
+			if !errors.Is(err, io.EOF) && !app.shuttingDown.Load() && !client.killed.Load() {
+				// the stream cannot be resynchronized after a protocol error,
+				// report it and close the connection like Redis does
 				log.Println("Failed to unmarshal data:", err)
+				_ = client.writePush(types.NewErrorRawCmd(fmt.Sprintf("%s Protocol error: %s", ErrorCodeERR, errorReplyReplacer.Replace(err.Error()))))
 			}
 			return
 		}
 
-		ctx, cancelFn := context.WithTimeout(context.Background(), time.Second*30)
-		defer cancelFn()
-		connId := app.idGenerator.MustNew()
-		ctx = NewContext(ctx, connId)
-
-		resp, err := app.HandleCommand(ctx, res)
-		if err != nil {
+		resp, err := app.handleClientCommand(client, res)
+		if errors.Is(err, errShuttingDown) {
 			return
 		}
-
-		respByte, err := encoding.MarshalCommand(resp)
 		if err != nil {
-			return
+			// a failed command does not end the session
+			resp = types.NewErrorRawCmd(ErrorReply(err))
 		}
 
-		// TODO: timeout with SetWriteDeadline
-		_, err = conn.Write(respByte)
-		if err != nil {
-			log.Println("Failed to response", err)
+		// noReply is set by CLIENT REPLY while handling the command
+		if !client.noReply {
+			// the only error is the client being closed by the server
+			if err = client.writeReply(resp); err != nil {
+				return
+			}
+		}
+		if client.closeAfterReply {
 			return
 		}
+
+		// more pipelined commands are already waiting, answer them in the same write
+		if client.reader.Buffered() > 0 {
+			continue
+		}
+		client.flush()
 	}
 }
+
+// handleClientCommand runs a command of client, there is no time limit so
+// blocking commands can wait as long as they are asked to
+func (app *App) handleClientCommand(client *Client, res types.RawCmd) (types.RawCmd, error) {
+	ctx := NewContext(context.Background(), client.connID)
+	ctx = NewClientContext(ctx, client)
+
+	return app.HandleCommand(ctx, res)
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func startTestServer(tb testing.TB) net.Conn {
+	tb.Helper()
+	return dialTestServer(tb, startTestApp(tb, NewApp(config.New())))
+}
+
+// startTestApp serves app on a random local port and returns its address
+func startTestApp(tb testing.TB, app *App) string {
+	tb.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal("listen failed:", err)
+	}
+	tb.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		_ = app.Serve(l)
+	}()
+
+	return l.Addr().String()
+}
+
+func dialTestServer(tb testing.TB, addr string) net.Conn {
+	tb.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		tb.Fatal("dial failed:", err)
+	}
+	tb.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+type testClient struct {
+	tb     testing.TB
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newTestClient(tb testing.TB, addr string) *testClient {
+	conn := dialTestServer(tb, addr)
+	return &testClient{tb: tb, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// do sends a command and returns its reply
+func (c *testClient) do(args ...string) types.RawCmd {
+	c.tb.Helper()
+	if _, err := c.conn.Write(mustMarshal(c.tb, args...)); err != nil {
+		c.tb.Fatal("write failed:", err)
+	}
+	return c.read()
+}
+
+func (c *testClient) read() types.RawCmd {
+	c.tb.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply, err := encoding.UnmarshalCommand(c.reader)
+	if err != nil {
+		c.tb.Fatal("read reply failed:", err)
+	}
+	return reply
+}
+
+func mustMarshal(tb testing.TB, args ...string) []byte {
+	tb.Helper()
+	data, err := encoding.MarshalCommand(types.NewBulkArrayBulkString(args))
+	if err != nil {
+		tb.Fatal("marshal failed:", err)
+	}
+	return data
+}
+
+func Test_HandleConnectionPipelineOrder(t *testing.T) {
+	conn := startTestServer(t)
+
+	var batch []byte
+	for i := range 100 {
+		batch = append(batch, mustMarshal(t, "ECHO", fmt.Sprint(i))...)
+	}
+	if _, err := conn.Write(batch); err != nil {
+		t.Fatal("write failed:", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	for i := range 100 {
+		reply, err := encoding.UnmarshalCommand(reader)
+		if err != nil {
+			t.Fatal("read reply failed:", err)
+		}
+		if reply.String != fmt.Sprint(i) {
+			t.Fatalf("expect reply %d, got %+v", i, reply)
+		}
+	}
+}
+
+func BenchmarkHandleConnectionPipelined(b *testing.B) {
+	for _, pipeline := range []int{1, 16} {
+		b.Run(fmt.Sprintf("P%d", pipeline), func(b *testing.B) {
+			conn := startTestServer(b)
+			reader := bufio.NewReader(conn)
+
+			var batch []byte
+			for range pipeline {
+				batch = append(batch, mustMarshal(b, "SET", "key", "value")...)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for sent := 0; sent < b.N; sent += pipeline {
+				if _, err := conn.Write(batch); err != nil {
+					b.Fatal("write failed:", err)
+				}
+				for range pipeline {
+					if _, err := encoding.UnmarshalCommand(reader); err != nil {
+						b.Fatal("read reply failed:", err)
+					}
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "ops/s")
+		})
+	}
+}
+
+func Test_HandleConnectionErrorKeepsSession(t *testing.T) {
+	conn := startTestServer(t)
+
+	var batch []byte
+	batch = append(batch, mustMarshal(t, "GET")...)
+	batch = append(batch, mustMarshal(t, "FOO", "a", "b")...)
+	batch = append(batch, mustMarshal(t, "LRANGE", "k", "a", "1")...)
+	batch = append(batch, mustMarshal(t, "SET", "k", "v", "NX", "XX", "BAD")...)
+	batch = append(batch, mustMarshal(t, "RPUSH", "k", "a")...)
+	batch = append(batch, mustMarshal(t, "GET", "k")...)
+	batch = append(batch, mustMarshal(t, "ECHO", "still alive")...)
+	if _, err := conn.Write(batch); err != nil {
+		t.Fatal("write failed:", err)
+	}
+
+	expected := []types.RawCmd{
+		types.NewErrorRawCmd("ERR wrong number of arguments for 'get' command"),
+		types.NewErrorRawCmd("ERR unknown command 'FOO', with args beginning with: 'a' 'b' "),
+		types.NewErrorRawCmd("ERR value is not an integer or out of range"),
+		types.NewErrorRawCmd("ERR syntax error"),
+		{Sym: types.SymInteger, Integer: 1},
+		types.NewErrorRawCmd("WRONGTYPE Operation against a key holding the wrong kind of value"),
+		types.NewStringRawCmd("still alive"),
+	}
+
+	reader := bufio.NewReader(conn)
+	for _, e := range expected {
+		reply, err := encoding.UnmarshalCommand(reader)
+		if err != nil {
+			t.Fatal("read reply failed:", err)
+		}
+		if reply.Sym != e.Sym || reply.Error != e.Error || reply.String != e.String || reply.Integer != e.Integer {
+			t.Errorf("expect reply %+v, got %+v", e, reply)
+		}
+	}
+}
+
+func Test_HandleConnectionPipelineBeforeBlocking(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	client := newTestClient(t, addr)
+
+	var batch []byte
+	batch = append(batch, mustMarshal(t, "SET", "pp", "1")...)
+	batch = append(batch, mustMarshal(t, "BLPOP", "list", "0")...)
+	if _, err := client.conn.Write(batch); err != nil {
+		t.Fatal("write failed:", err)
+	}
+
+	// the reply of SET is sent while BLPOP blocks
+	expectEqual(t, "OK", client.read().String)
+
+	newTestClient(t, addr).do("RPUSH", "list", "a")
+	expectEqual(t, "list a", joinBulkStrings(client.read()))
+
+	// the same goes for a write delayed by CLIENT PAUSE
+	admin := newTestClient(t, addr)
+	admin.do("CLIENT", "PAUSE", "10000", "WRITE")
+	batch = append(mustMarshal(t, "GET", "pp"), mustMarshal(t, "SET", "pp", "2")...)
+	if _, err := client.conn.Write(batch); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	expectEqual(t, "1", client.read().BulkString)
+	admin.do("CLIENT", "UNPAUSE")
+	expectEqual(t, "OK", client.read().String)
+}
+
+func Test_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		_ = NewApp(config.New()).Serve(l)
+	}()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal("dial failed:", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := &testClient{tb: t, conn: conn, reader: bufio.NewReader(conn)}
+
+	info := client.do("CLIENT", "INFO").BulkString
+	for _, field := range []string{"addr=" + path + ":0", "laddr=" + path + ":0", "flags=U"} {
+		if !strings.Contains(info, " "+field+" ") {
+			t.Errorf("expect %q in %q", field, info)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+const (
+	serverName    = "redis"
+	serverVersion = "7.4.0"
+
+	// the user new connections are authenticated as
+	defaultUser = "default"
+)
+
+func (app *App) handleSELECT(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.SELECT](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	if _, err := app.dbByIndex(c.Index); err != nil {
+		return types.RawCmd{}, err
+	}
+	if client := GetClientFromContext(ctx); client != nil {
+		client.db = c.Index
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleHELLO(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.HELLO](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	client := GetClientFromContext(ctx)
+
+	var protocol encoding.Protocol
+	if c.Protover != nil {
+		protocol = encoding.Protocol(*c.Protover)
+		if protocol != encoding.ProtocolRESP2 && protocol != encoding.ProtocolRESP3 {
+			return types.RawCmd{}, NewCodedError("NOPROTO", "unsupported protocol version")
+		}
+	}
+	if c.AUTH != nil {
+		if err := app.authenticate(client, c.AUTH.Username, c.AUTH.Password); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+	// HELLO is allowed before AUTH so it can authenticate
+	if !client.authenticated {
+		return types.RawCmd{}, NewCodedError(ErrorCodeNoAuth, "HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+	}
+	if c.SETNAME != nil {
+		if err := setClientName(client, *c.SETNAME); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+	if c.Protover != nil {
+		client.protocol = protocol
+	}
+
+	return types.NewMapRawCmd(map[string]types.RawCmd{
+		"server":  types.NewBulkStringRawCmd(serverName),
+		"version": types.NewBulkStringRawCmd(serverVersion),
+		"proto":   types.NewIntegerRawCmd(int64(client.protocol)),
+		"id":      types.NewIntegerRawCmd(client.id),
+		"mode":    types.NewBulkStringRawCmd("standalone"),
+		"role":    types.NewBulkStringRawCmd("master"),
+		"modules": types.NewArrayRawCmd(),
+	}), nil
+}
+
+func (app *App) handleAUTH(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.AUTH](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	client := GetClientFromContext(ctx)
+
+	username, password := defaultUser, c.UsernameOrPassword
+	if c.Password != nil {
+		username, password = c.UsernameOrPassword, *c.Password
+	} else if app.users[defaultUser].nopass {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	}
+	if err := app.authenticate(client, username, password); err != nil {
+		return types.RawCmd{}, err
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCLIENT(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.CLIENT](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.TRACKING != nil:
+		return app.handleCLIENTTRACKING(ctx, *c.TRACKING)
+	case c.CACHING != nil:
+		return app.handleCLIENTCACHING(ctx, *c.CACHING)
+	case c.GETREDIR != nil:
+		return app.handleCLIENTGETREDIR(ctx)
+	case c.ID != nil:
+		return app.handleCLIENTID(ctx)
+	case c.SETNAME != nil:
+		return app.handleCLIENTSETNAME(ctx, *c.SETNAME)
+	case c.GETNAME != nil:
+		return app.handleCLIENTGETNAME(ctx)
+	case c.LIST != nil:
+		return app.handleCLIENTLIST(*c.LIST)
+	case c.INFO != nil:
+		return app.handleCLIENTINFO(ctx)
+	case c.KILL != nil:
+		return app.handleCLIENTKILL(ctx, c.KILL)
+	case c.PAUSE != nil:
+		return app.handleCLIENTPAUSE(*c.PAUSE)
+	case c.UNPAUSE != nil:
+		return app.handleCLIENTUNPAUSE()
+	case c.UNBLOCK != nil:
+		return app.handleCLIENTUNBLOCK(*c.UNBLOCK)
+	case c.NOEVICT != nil:
+		return app.handleCLIENTNOEVICT(ctx, *c.NOEVICT)
+	case c.REPLY != nil:
+		return app.handleCLIENTREPLY(ctx, *c.REPLY)
+	default: // HELP
+		return subcommandHelp[cmd.CLIENT]("client")
+	}
+}
+
+func (app *App) handleCLIENTID(ctx context.Context) (types.RawCmd, error) {
+	return types.NewIntegerRawCmd(GetClientFromContext(ctx).id), nil
+}
+
+func (app *App) handleCLIENTSETNAME(ctx context.Context, c cmd.CLIENT_SETNAME) (types.RawCmd, error) {
+	if err := setClientName(GetClientFromContext(ctx), c.Name); err != nil {
+		return types.RawCmd{}, err
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func setClientName(client *Client, name string) error {
+	// names are shown in CLIENT LIST, they must be a single printable word
+	for _, char := range []byte(name) {
+		if char < '!' || char > '~' {
+			return NewCodedError(ErrorCodeERR, "Client names cannot contain spaces, newlines or special characters.")
+		}
+	}
+	client.name = name
+	return nil
+}
+
+func (app *App) handleCLIENTGETNAME(ctx context.Context) (types.RawCmd, error) {
+	client := GetClientFromContext(ctx)
+	if client.name == "" {
+		return types.NewNullRawCmd(), nil
+	}
+	return types.NewBulkStringRawCmd(client.name), nil
+}
+
+// parseClientType validates the TYPE filter of CLIENT LIST and CLIENT KILL,
+// there are no replicas so they never match
+func parseClientType(raw string) (string, error) {
+	switch clientType := strings.ToLower(raw); clientType {
+	case "normal", "pubsub", "master", "replica":
+		return clientType, nil
+	case "slave":
+		return "replica", nil
+	default:
+		return "", NewCodedError(ErrorCodeERR, fmt.Sprintf("Unknown client type '%s'", raw))
+	}
+}
+
+// sortedClients returns the clients ordered by id, i.e. by connection time
+func (app *App) sortedClients() []*Client {
+	clients := slices.Collect(maps.Values(app.clients))
+	slices.SortFunc(clients, func(a, b *Client) int {
+		return cmp.Compare(a.id, b.id)
+	})
+	return clients
+}
+
+func (app *App) handleCLIENTLIST(c cmd.CLIENT_LIST) (types.RawCmd, error) {
+	filter := func(*Client) bool { return true }
+	switch {
+	case c.TYPE != nil && c.ID != nil:
+		return types.RawCmd{}, NewSyntaxError()
+	case c.TYPE != nil:
+		wantedType, err := parseClientType(*c.TYPE)
+		if err != nil {
+			return types.RawCmd{}, err
+		}
+		filter = func(client *Client) bool { return clientType(client) == wantedType }
+	case c.ID != nil:
+		ids := map[int64]bool{}
+		for _, id := range c.ID {
+			if id <= 0 {
+				return types.RawCmd{}, NewCodedError(ErrorCodeERR, "Invalid client ID")
+			}
+			ids[id] = true
+		}
+		filter = func(client *Client) bool { return ids[client.id] }
+	}
+
+	var sb strings.Builder
+	now := time.Now()
+	for _, client := range app.sortedClients() {
+		if filter(client) {
+			sb.WriteString(clientInfo(client, now))
+			sb.WriteString("\n")
+		}
+	}
+	return types.NewBulkStringRawCmd(sb.String()), nil
+}
+
+func (app *App) handleCLIENTINFO(ctx context.Context) (types.RawCmd, error) {
+	return types.NewBulkStringRawCmd(clientInfo(GetClientFromContext(ctx), time.Now()) + "\n"), nil
+}
+
+func (app *App) handleCLIENTKILL(ctx context.Context, args []string) (types.RawCmd, error) {
+	// old form with the address as the only argument
+	if len(args) == 2 {
+		for _, client := range app.clients {
+			if client.addr == args[1] {
+				app.killClient(ctx, client)
+				return types.NewStringRawCmd("OK"), nil
+			}
+		}
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "No such client")
+	}
+
+	c, err := argsparser.Parse[cmd.CLIENT_KILL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	if c.ID != nil && *c.ID <= 0 {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "client-id should be greater than 0")
+	}
+	if c.USER != nil {
+		if _, exists := app.users[*c.USER]; !exists {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("No such user '%s'", *c.USER))
+		}
+	}
+	skipMe := true
+	if c.SKIPME != nil {
+		switch strings.ToLower(*c.SKIPME) {
+		case "yes":
+		case "no":
+			skipMe = false
+		default:
+			return types.RawCmd{}, NewSyntaxError()
+		}
+	}
+	var wantedType string
+	if c.TYPE != nil {
+		if wantedType, err = parseClientType(*c.TYPE); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+
+	self := GetClientFromContext(ctx)
+	now := time.Now()
+	killed := 0
+	for _, client := range app.sortedClients() {
+		switch {
+		case skipMe && client == self,
+			c.ID != nil && client.id != *c.ID,
+			c.ADDR != nil && client.addr != *c.ADDR,
+			c.LADDR != nil && client.localAddr != *c.LADDR,
+			c.USER != nil && client.user.name != *c.USER,
+			c.MAXAGE != nil && now.Sub(client.createdAt) < time.Duration(*c.MAXAGE)*time.Second,
+			c.TYPE != nil && clientType(client) != wantedType:
+			continue
+		}
+		app.killClient(ctx, client)
+		killed += 1
+	}
+	return types.NewIntegerRawCmd(int64(killed)), nil
+}
+
+func (app *App) handleCLIENTPAUSE(c cmd.CLIENT_PAUSE) (types.RawCmd, error) {
+	if c.TimeoutMillisecond < 0 {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "timeout is negative")
+	}
+	app.pauseClients(time.Now().Add(time.Duration(c.TimeoutMillisecond)*time.Millisecond), c.Mode.WRITE)
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCLIENTUNPAUSE() (types.RawCmd, error) {
+	app.unpauseClients()
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCLIENTUNBLOCK(c cmd.CLIENT_UNBLOCK) (types.RawCmd, error) {
+	client, exists := app.clients[c.ID]
+	if !exists || client.unblock == nil {
+		return types.NewIntegerRawCmd(0), nil
+	}
+
+	// the waiter replies as if it timed out unless ERROR is given
+	var reason error
+	if c.Mode.ERROR {
+		reason = NewCodedError(ErrorCodeUnblocked, "client unblocked via CLIENT UNBLOCK")
+	}
+	client.unblock <- reason
+	// the waiter may not run before another UNBLOCK
+	client.unblock = nil
+	return types.NewIntegerRawCmd(1), nil
+}
+
+func (app *App) handleCLIENTNOEVICT(ctx context.Context, c cmd.CLIENT_NOEVICT) (types.RawCmd, error) {
+	client := GetClientFromContext(ctx)
+	switch strings.ToUpper(c.Mode) {
+	case "ON":
+		client.noEvict = true
+	case "OFF":
+		client.noEvict = false
+	default:
+		return types.RawCmd{}, NewSyntaxError()
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCLIENTREPLY(ctx context.Context, c cmd.CLIENT_REPLY) (types.RawCmd, error) {
+	client := GetClientFromContext(ctx)
+	// OFF and SKIP are not answered, see HandleCommand
+	switch strings.ToUpper(c.Mode) {
+	case "ON":
+		client.replyOff = false
+	case "OFF":
+		client.replyOff = true
+	case "SKIP":
+		client.replySkipNext = true
+	default:
+		return types.RawCmd{}, NewSyntaxError()
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/dict"
+)
+
+// redisDB is one of the logical databases selected with SELECT, each has its
+// own keyspace
+type redisDB struct {
+	id     int
+	dict   *dict.Dict[Value]
+	expiry map[string]time.Time
+	// sum of the memory of the values
+	usedMemory int64
+	// estimate of the remaining time to live of the keys with a TTL, updated
+	// by the active expire cycle
+	avgTTL time.Duration
+
+	blpopConsumers map[string][]BLPOPConsumer
+}
+
+func newRedisDB(id int) *redisDB {
+	return &redisDB{
+		id:     id,
+		dict:   dict.New[Value](),
+		expiry: map[string]time.Time{},
+
+		blpopConsumers: map[string][]BLPOPConsumer{},
+	}
+}
+
+// selectedDB returns the database selected by the client issuing the command,
+// the first one for work that does not come from a connection
+func (app *App) selectedDB(ctx context.Context) *redisDB {
+	client := GetClientFromContext(ctx)
+	if client == nil {
+		return app.dbs[0]
+	}
+	return app.dbs[client.db]
+}
+
+// dbByIndex returns the database of a client provided index
+func (app *App) dbByIndex(index int) (*redisDB, error) {
+	if index < 0 || index >= len(app.dbs) {
+		return nil, NewCodedError(ErrorCodeERR, "DB index is out of range")
+	}
+	return app.dbs[index], nil
+}
+
+// Every access to the keyspace goes through the functions below so expired
+// keys are never visible, even before the active expire cycle removes them.
+
+// lookupKey returns the value of key and records the access for eviction
+func (app *App) lookupKey(ctx context.Context, db *redisDB, key string) (Value, bool) {
+	app.expireIfNeeded(ctx, db, key)
+	value := db.dict.Find(key)
+	if value == nil {
+		return Value{}, false
+	}
+	app.updateAccess(value)
+	return value.decoded(), true
+}
+
+// lookupKeyRead returns the value of key for commands that only read it
+func (app *App) lookupKeyRead(ctx context.Context, db *redisDB, key string) (Value, bool) {
+	value, exists := app.lookupKey(ctx, db, key)
+	if exists {
+		app.stats.keyspaceHits += 1
+	} else {
+		app.stats.keyspaceMisses += 1
+		app.notifyKeyspaceEvent(config.NotifyKeyMiss, "keymiss", key, db.id)
+	}
+	app.trackingRememberKey(ctx, key)
+	return value, exists
+}
+
+// lookupKeyWrite returns the value of key for commands about to modify it
+func (app *App) lookupKeyWrite(ctx context.Context, db *redisDB, key string) (Value, bool) {
+	return app.lookupKey(ctx, db, key)
+}
+
+// setKey stores value without touching the TTL of the key
+func (app *App) setKey(ctx context.Context, db *redisDB, key string, value Value) {
+	value.Key = key
+	if value.lru == 0 {
+		app.initAccess(&value)
+	}
+	app.updateEncoding(&value)
+	value.memory = valueMemoryUsage(key, value, memoryUsageSamples)
+	if old := db.dict.Find(key); old != nil {
+		db.usedMemory += value.memory - old.memory
+		*old = value
+	} else {
+		db.dict.Set(key, value)
+		db.usedMemory += value.memory
+		app.notifyKeyspaceEvent(config.NotifyNew, "new", key, db.id)
+	}
+	app.signalModifiedKey(ctx, key)
+}
+
+// deleteKey removes key and its TTL, it tells whether the key existed
+func (app *App) deleteKey(ctx context.Context, db *redisDB, key string) bool {
+	value, exists := db.dict.Delete(key)
+	if !exists {
+		return false
+	}
+	db.usedMemory -= value.memory
+	delete(db.expiry, key)
+	app.signalModifiedKey(ctx, key)
+	return true
+}
+
+// deleteListIfEmpty removes a list left empty by a pop, empty lists never
+// stay in the keyspace
+func (app *App) deleteListIfEmpty(ctx context.Context, db *redisDB, key string, value Value) {
+	if value.listLen() != 0 {
+		return
+	}
+	app.deleteKey(ctx, db, key)
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "del", key, db.id)
+}
+
+// getExpire returns the time key expires at, if it has a TTL
+func (db *redisDB) getExpire(key string) (time.Time, bool) {
+	expireAt, exists := db.expiry[key]
+	return expireAt, exists
+}
+
+// setExpire sets the TTL of an existing key
+func (db *redisDB) setExpire(key string, expireAt time.Time) {
+	db.expiry[key] = expireAt
+}
+
+// removeExpire makes key persistent, it tells whether the key had a TTL
+func (db *redisDB) removeExpire(key string) bool {
+	if _, exists := db.expiry[key]; !exists {
+		return false
+	}
+	delete(db.expiry, key)
+	return true
+}
+
+func (db *redisDB) isExpired(key string, now time.Time) bool {
+	expireAt, exists := db.expiry[key]
+	return exists && !now.Before(expireAt)
+}
+
+// expireIfNeeded deletes key when its TTL is over and tells whether it did
+func (app *App) expireIfNeeded(ctx context.Context, db *redisDB, key string) bool {
+	if !db.isExpired(key, time.Now()) {
+		return false
+	}
+	app.deleteExpiredKey(ctx, db, key)
+	return true
+}
+
+func (app *App) deleteExpiredKey(ctx context.Context, db *redisDB, key string) {
+	app.deleteKey(ctx, db, key)
+	app.stats.expiredKeys += 1
+	app.notifyKeyspaceEvent(config.NotifyExpired, "expired", key, db.id)
+}
+
+// signalModifiedKey must be called every time the value of key is changed,
+// client side caching does not tell databases apart like in Redis
+func (app *App) signalModifiedKey(ctx context.Context, key string) {
+	app.dirty += 1
+	app.trackingInvalidateKey(ctx, key)
+}
@@ -3,10 +3,122 @@ package app
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
 	"github.com/codecrafters-io/redis-starter-go/pkg/types"
 )
 
+// Error codes are the first word of an error reply, clients rely on them to
+// tell the kind of failure apart.
+const (
+	ErrorCodeERR       = "ERR"
+	ErrorCodeWrongType = "WRONGTYPE"
+	ErrorCodeUnblocked = "UNBLOCKED"
+	ErrorCodeOOM       = "OOM"
+	ErrorCodeNoAuth    = "NOAUTH"
+	ErrorCodeNoPerm    = "NOPERM"
+	ErrorCodeWrongPass = "WRONGPASS"
+)
+
+// ReplyError is an error whose message is already in the form expected by
+// clients: an error code followed by a human readable description.
+type ReplyError interface {
+	error
+	ErrorCode() string
+}
+
+// ErrorReply builds the content of the error reply sent to the client for an
+// error returned by HandleCommand.
+func ErrorReply(err error) string {
+	return errorReplyReplacer.Replace(buildErrorReply(err))
+}
+
+// error replies are simple strings, they cannot span multiple lines
+var errorReplyReplacer = strings.NewReplacer("\r", " ", "\n", " ")
+
+func buildErrorReply(err error) string {
+	var replyErr ReplyError
+	if errors.As(err, &replyErr) {
+		return replyErr.Error()
+	}
+
+	var commandErr HandleCommandError
+	if errors.As(err, &commandErr) {
+		if errors.Is(err, argsparser.ErrWrongNumberOfArguments) {
+			return NewWrongNumberOfArgumentsError(commandErr.Command).Error()
+		}
+		var subcommandErr argsparser.UnknownSubcommandError
+		if errors.As(err, &subcommandErr) {
+			return NewUnknownSubcommandError(commandErr.Command, subcommandErr.Subcommand).Error()
+		}
+		err = commandErr.Err
+	}
+
+	for _, parseErr := range []error{
+		argsparser.ErrSyntax, argsparser.ErrNotInteger, argsparser.ErrNotFloat,
+		argsparser.ErrNotTimeout, argsparser.ErrNegativeTimeout, argsparser.ErrNotScoreRange, argsparser.ErrNotLexRange,
+	} {
+		if errors.Is(err, parseErr) {
+			return ErrorCodeERR + " " + parseErr.Error()
+		}
+	}
+
+	return ErrorCodeERR + " " + err.Error()
+}
+
+type CodedError struct {
+	Code    string
+	Message string
+}
+
+func (e CodedError) Error() string {
+	return e.Code + " " + e.Message
+}
+
+func (e CodedError) ErrorCode() string {
+	return e.Code
+}
+
+func NewCodedError(code, message string) CodedError {
+	return CodedError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func NewWrongNumberOfArgumentsError(command string) CodedError {
+	return NewCodedError(ErrorCodeERR, fmt.Sprintf("wrong number of arguments for '%s' command", strings.ToLower(command)))
+}
+
+func NewUnknownSubcommandError(command, subcommand string) CodedError {
+	return NewCodedError(ErrorCodeERR, fmt.Sprintf("unknown subcommand '%s'. Try %s HELP.", subcommand, strings.ToUpper(command)))
+}
+
+func NewUnknownCommandError(command string, args []string) CodedError {
+	var sb strings.Builder
+	for _, arg := range args {
+		// same as Redis, only show the beginning of long arguments
+		if len(arg) > 128 {
+			arg = arg[:128]
+		}
+		fmt.Fprintf(&sb, "'%s' ", arg)
+	}
+	return NewCodedError(ErrorCodeERR, fmt.Sprintf("unknown command '%s', with args beginning with: %s", command, sb.String()))
+}
+
+func NewSyntaxError() CodedError {
+	return NewCodedError(ErrorCodeERR, argsparser.ErrSyntax.Error())
+}
+
+func NewInvalidExpireTimeError(command string) CodedError {
+	return NewCodedError(ErrorCodeERR, fmt.Sprintf("invalid expire time in '%s' command", strings.ToLower(command)))
+}
+
+func NewOOMError() CodedError {
+	return NewCodedError(ErrorCodeOOM, "command not allowed when used memory > 'maxmemory'.")
+}
+
 type HandleCommandError struct {
 	Command string
 	Err     error
@@ -20,6 +132,10 @@ func (e HandleCommandError) Is(target error) bool {
 	return errors.Is(e.Err, target)
 }
 
+func (e HandleCommandError) Unwrap() error {
+	return e.Err
+}
+
 func NewHandleCommandError(command string, err error) HandleCommandError {
 	return HandleCommandError{
 		Command: command,
@@ -84,6 +200,10 @@ func (e ArrayElementError) Is(target error) bool {
 	return errors.Is(e.Err, target)
 }
 
+func (e ArrayElementError) Unwrap() error {
+	return e.Err
+}
+
 func NewArrayElementError(index int, err error) ArrayElementError {
 	return ArrayElementError{
 		Index: index,
@@ -98,6 +218,10 @@ func (WrongTypeError) Error() string {
 	return "WRONGTYPE Operation against a key holding the wrong kind of value"
 }
 
+func (WrongTypeError) ErrorCode() string {
+	return ErrorCodeWrongType
+}
+
 func NewWrongTypeError(expected, actual ValueType) WrongTypeError {
 	_ = expected
 	_ = actual
@@ -0,0 +1,316 @@
+package app
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+)
+
+// Memory accounting is an estimate of what a value costs, computed when it is
+// stored: the bytes of the key and the strings plus a fixed overhead for the
+// dict entry and the Value, and an overhead per allocation that depends on the
+// encoding of the value.
+const (
+	keyMemoryOverhead = 64
+	// a string allocated apart from its object
+	rawStringMemoryOverhead   = 16
+	listElementMemoryOverhead = 16
+	// header and back length of a listpack entry
+	listpackEntryMemoryOverhead = 2
+	listpackMemoryOverhead      = 7
+	// list elements sampled to estimate the size of a list
+	memoryUsageSamples = 5
+)
+
+// valueMemoryUsage estimates the memory of key and a stored value, the size
+// of a quicklist is extrapolated from samples elements (0 to use them all) so
+// it does not cost a walk of the whole list
+func valueMemoryUsage(key string, value Value, samples int) int64 {
+	size := int64(keyMemoryOverhead + len(key))
+	switch value.encoding {
+	case EncodingInt:
+		// the number is stored in the Value
+	case EncodingEmbStr:
+		size += int64(len(value.String))
+	case EncodingRaw:
+		size += int64(rawStringMemoryOverhead + len(value.String))
+	case EncodingListpack:
+		size += listpackMemoryOverhead + int64(len(value.listpack.data))
+	case EncodingQuicklist:
+		size += int64(len(value.List)*listElementMemoryOverhead) + sampledListBytes(value.List, samples)
+	}
+	return size
+}
+
+// sampledListBytes extrapolates the bytes of the elements of list from
+// samples of them, all of them when samples is 0
+func sampledListBytes(list []string, samples int) int64 {
+	length := len(list)
+	if length == 0 {
+		return 0
+	}
+	if samples <= 0 || samples > length {
+		samples = length
+	}
+	sampledSize := 0
+	for idx := range samples {
+		sampledSize += len(list[idx*length/samples])
+	}
+	return int64(sampledSize) * int64(length) / int64(samples)
+}
+
+func (app *App) usedMemory() int64 {
+	var used int64
+	for _, db := range app.dbs {
+		used += db.usedMemory
+	}
+	return used
+}
+
+// The access time of a value is a 24 bits clock in seconds like Redis LRU
+// clock. With the LFU policies the same field holds the time of the last
+// decrement in minutes on 16 bits then a logarithmic access counter on 8 bits.
+const (
+	lruClockMax = 1<<24 - 1
+	lfuInitVal  = 5
+)
+
+func lruClock() uint32 {
+	return uint32(time.Now().Unix()) & lruClockMax
+}
+
+// estimateIdleTime returns how long ago value was accessed with the LRU clock
+func estimateIdleTime(value Value) time.Duration {
+	clock := lruClock()
+	if clock >= value.lru {
+		return time.Duration(clock-value.lru) * time.Second
+	}
+	return time.Duration(clock+(lruClockMax-value.lru)) * time.Second
+}
+
+// initAccess sets the access time or frequency of a new value
+func (app *App) initAccess(value *Value) {
+	if app.config.Eviction().UsesLFU() {
+		value.lru = lfuTimeInMinutes()<<8 | lfuInitVal
+	} else {
+		value.lru = lruClock()
+	}
+}
+
+// updateAccess records an access to value
+func (app *App) updateAccess(value *Value) {
+	eviction := app.config.Eviction()
+	if !eviction.UsesLFU() {
+		value.lru = lruClock()
+		return
+	}
+	counter := lfuDecrAndReturn(value.lru, eviction.LFUDecayTime)
+	counter = lfuLogIncr(counter, eviction.LFULogFactor)
+	value.lru = lfuTimeInMinutes()<<8 | counter
+}
+
+func lfuTimeInMinutes() uint32 {
+	return uint32(time.Now().Unix()/60) & math.MaxUint16
+}
+
+func lfuTimeElapsed(lastDecrement uint32) uint32 {
+	now := lfuTimeInMinutes()
+	if now >= lastDecrement {
+		return now - lastDecrement
+	}
+	return math.MaxUint16 - lastDecrement + now
+}
+
+// lfuDecrAndReturn returns the access counter decremented by one for each
+// decayTime minutes elapsed since the last decrement
+func lfuDecrAndReturn(lru uint32, decayTime int64) uint32 {
+	lastDecrement, counter := lru>>8, lru&math.MaxUint8
+	if decayTime == 0 {
+		return counter
+	}
+	periods := lfuTimeElapsed(lastDecrement) / uint32(decayTime)
+	if periods >= counter {
+		return 0
+	}
+	return counter - periods
+}
+
+// lfuLogIncr increments the counter with a probability that gets lower as it
+// grows, so 8 bits count up to millions of accesses
+func lfuLogIncr(counter uint32, logFactor int64) uint32 {
+	if counter == math.MaxUint8 {
+		return counter
+	}
+	base := max(float64(counter)-lfuInitVal, 0)
+	if rand.Float64() < 1/(base*float64(logFactor)+1) {
+		counter += 1
+	}
+	return counter
+}
+
+const evictionPoolSize = 16
+
+type evictionCandidate struct {
+	// the higher the better to evict
+	idle uint64
+	key  string
+	db   *redisDB
+}
+
+// performEvictions evicts keys until the memory used is under maxmemory, it
+// tells whether it succeeded
+func (app *App) performEvictions() bool {
+	eviction := app.config.Eviction()
+	if eviction.MaxMemory == 0 {
+		return true
+	}
+	used := app.usedMemory()
+	if used <= eviction.MaxMemory {
+		return true
+	}
+	if eviction.Policy == "noeviction" {
+		return false
+	}
+
+	ctx := context.Background()
+	for toFree := used - eviction.MaxMemory; toFree > 0; {
+		var candidate evictionCandidate
+		var found bool
+		if strings.HasSuffix(eviction.Policy, "-random") {
+			candidate, found = app.randomEvictionCandidate(strings.HasPrefix(eviction.Policy, "volatile-"))
+		} else {
+			candidate, found = app.bestEvictionCandidate(eviction)
+		}
+		if !found {
+			return false
+		}
+
+		value, _ := candidate.db.dict.Get(candidate.key)
+		toFree -= value.memory
+		app.deleteKey(ctx, candidate.db, candidate.key)
+		app.stats.evictedKeys += 1
+		app.notifyKeyspaceEvent(config.NotifyEvicted, "evicted", candidate.key, candidate.db.id)
+	}
+	return true
+}
+
+// bestEvictionCandidate samples keys of every database into the eviction pool
+// and takes the best one still existing, this approximates evicting the best
+// key of the whole keyspace without sorting it
+func (app *App) bestEvictionCandidate(eviction config.Eviction) (evictionCandidate, bool) {
+	volatile := strings.HasPrefix(eviction.Policy, "volatile-")
+	for {
+		keys := 0
+		for _, db := range app.dbs {
+			count := db.dict.Len()
+			if volatile {
+				count = len(db.expiry)
+			}
+			if count != 0 {
+				app.evictionPoolPopulate(db, eviction)
+				keys += count
+			}
+		}
+		if keys == 0 {
+			return evictionCandidate{}, false
+		}
+
+		for idx := len(app.evictionPool) - 1; idx >= 0; idx-- {
+			candidate := app.evictionPool[idx]
+			app.evictionPool = app.evictionPool[:idx]
+			// the key may have been deleted or persisted since it was sampled
+			_, exists := candidate.db.dict.Get(candidate.key)
+			if volatile {
+				_, exists = candidate.db.expiry[candidate.key]
+			}
+			if exists {
+				return candidate, true
+			}
+		}
+	}
+}
+
+// evictionPoolPopulate adds maxmemory-samples keys of db to the eviction pool,
+// which keeps the best candidates sorted by idle score
+func (app *App) evictionPoolPopulate(db *redisDB, eviction config.Eviction) {
+	samples := int(eviction.Samples)
+	policy := eviction.Policy
+	var keys []string
+	if strings.HasPrefix(policy, "volatile-") {
+		// map iteration starts at a random position
+		for key := range db.expiry {
+			if len(keys) == samples {
+				break
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		for range samples {
+			if key, _, exists := db.dict.RandomKey(); exists {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		value, exists := db.dict.Get(key)
+		if !exists {
+			continue
+		}
+		var idle uint64
+		switch {
+		case strings.HasSuffix(policy, "-lru"):
+			idle = uint64(estimateIdleTime(value).Milliseconds())
+		case strings.HasSuffix(policy, "-lfu"):
+			idle = math.MaxUint8 - uint64(lfuDecrAndReturn(value.lru, eviction.LFUDecayTime))
+		case policy == "volatile-ttl":
+			// the sooner the key expires the better
+			idle = math.MaxUint64 - uint64(db.expiry[key].UnixMilli())
+		}
+		app.evictionPoolInsert(evictionCandidate{idle: idle, key: key, db: db})
+	}
+}
+
+func (app *App) evictionPoolInsert(candidate evictionCandidate) {
+	pool := app.evictionPool
+	if len(pool) == evictionPoolSize && candidate.idle <= pool[0].idle {
+		return
+	}
+	for _, c := range pool {
+		if c.key == candidate.key && c.db == candidate.db {
+			return
+		}
+	}
+	idx := 0
+	for idx < len(pool) && pool[idx].idle < candidate.idle {
+		idx++
+	}
+	pool = slices.Insert(pool, idx, candidate)
+	// drop the worst candidate when full
+	if len(pool) > evictionPoolSize {
+		pool = pool[1:]
+	}
+	app.evictionPool = pool
+}
+
+// randomEvictionCandidate picks a random key, going through the databases in
+// turn
+func (app *App) randomEvictionCandidate(volatile bool) (evictionCandidate, bool) {
+	for range app.dbs {
+		db := app.dbs[app.evictionNextDB%len(app.dbs)]
+		app.evictionNextDB++
+		if volatile {
+			for key := range db.expiry {
+				return evictionCandidate{key: key, db: db}, true
+			}
+		} else if key, _, exists := db.dict.RandomKey(); exists {
+			return evictionCandidate{key: key, db: db}, true
+		}
+	}
+	return evictionCandidate{}, false
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_EvictionPolicies(t *testing.T) {
+	cases := []struct {
+		policy string
+		// keys without TTL can only be evicted by the allkeys policies
+		keepPersistent bool
+	}{
+		{"allkeys-lru", false},
+		{"allkeys-lfu", false},
+		{"allkeys-random", false},
+		{"volatile-lru", true},
+		{"volatile-lfu", true},
+		{"volatile-random", true},
+		{"volatile-ttl", true},
+	}
+	for _, c := range cases {
+		t.Run(c.policy, func(t *testing.T) {
+			app := NewApp(config.New())
+			client := newTestClient(t, startTestApp(t, app))
+			client.do("CONFIG", "SET", "maxmemory", "10kb", "maxmemory-policy", c.policy)
+
+			for i := range 20 {
+				client.do("SET", "persistent:"+strconv.Itoa(i), "v")
+			}
+			for i := range 500 {
+				if reply := client.do("SET", "volatile:"+strconv.Itoa(i), "v", "EX", "100"); reply.Sym == types.SymError {
+					t.Fatalf("unexpected error %+v", reply)
+				}
+			}
+
+			app.mutex.Lock()
+			used, evicted := app.usedMemory(), app.stats.evictedKeys
+			app.mutex.Unlock()
+			if used > 10*1024+200 || evicted == 0 {
+				t.Errorf("expect memory to stay under maxmemory, used %d with %d evicted keys", used, evicted)
+			}
+			if c.keepPersistent {
+				for i := range 20 {
+					expectEqual(t, int64(1), client.do("EXISTS", "persistent:"+strconv.Itoa(i)).Integer)
+				}
+			}
+		})
+	}
+}
+
+func Test_NoEvictionOOM(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	client.do("CONFIG", "SET", "maxmemory", "1kb")
+
+	var reply types.RawCmd
+	for i := 0; i < 100 && reply.Sym != types.SymError; i++ {
+		reply = client.do("SET", "k"+strconv.Itoa(i), "v")
+	}
+	if reply.Sym != types.SymError || !strings.HasPrefix(reply.Error, "OOM ") {
+		t.Fatalf("expect OOM error, got %+v", reply)
+	}
+	// reads and deletes are still allowed
+	expectEqual(t, "v", client.do("GET", "k0").BulkString)
+	expectEqual(t, int64(1), client.do("DEL", "k0").Integer)
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"time"
+)
+
+// Parameters of the active expire cycle, same as Redis:
+// sample keysPerLoop keys with a TTL and delete the expired ones, then keep
+// going while more than acceptableStalePerc percent of the sample was expired,
+// without using more than cycleTimePerc percent of the time between two cycles.
+const (
+	activeExpireKeysPerLoop         = 20
+	activeExpireAcceptableStalePerc = 25
+	activeExpireCycleTimePerc       = 25
+)
+
+// serverCron runs the periodic jobs, hz times per second, until shutdown
+func (app *App) serverCron() {
+	for {
+		interval := time.Second / time.Duration(app.config.Int("hz"))
+		select {
+		case <-app.shutdownCh:
+			return
+		case <-time.After(interval):
+		}
+
+		app.mutex.Lock()
+		if !app.shuttingDown.Load() {
+			// keys must not change while the clients are paused
+			if !app.clientsPaused() {
+				app.activeExpireCycle(interval * activeExpireCycleTimePerc / 100)
+			}
+			app.updatePeakMemory()
+			app.clientsCron(time.Now())
+			app.trackInstantaneousMetrics(time.Now())
+		}
+		app.mutex.Unlock()
+	}
+}
+
+// activeExpireCycle removes expired keys nobody accesses
+func (app *App) activeExpireCycle(timeLimit time.Duration) {
+	start := time.Now()
+	ctx := context.Background()
+
+	totalSampled, totalExpired := 0, 0
+	iteration := 0
+dbs:
+	for _, db := range app.dbs {
+		for {
+			// checking the clock is not free, only do it every 16 iterations
+			iteration += 1
+			if iteration%16 == 0 && time.Since(start) > timeLimit {
+				app.stats.expiredTimeCapReachedCount += 1
+				break dbs
+			}
+
+			sampled, expired := app.activeExpireSample(ctx, db, activeExpireKeysPerLoop, time.Now())
+			totalSampled += sampled
+			totalExpired += expired
+			if sampled == 0 || expired*100/sampled <= activeExpireAcceptableStalePerc {
+				break
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	app.stats.expireCycleTime += elapsed
+
+	// running average of the expired keys still in memory, like Redis
+	if totalSampled != 0 {
+		stalePerc := float64(totalExpired) / float64(totalSampled)
+		app.stats.expiredStalePerc = stalePerc*0.05 + app.stats.expiredStalePerc*0.95
+	}
+}
+
+// activeExpireSample checks up to count keys with a TTL and deletes the expired
+// ones, map iteration starts at a random position so this is a random sample
+func (app *App) activeExpireSample(ctx context.Context, db *redisDB, count int, now time.Time) (sampled, expired int) {
+	var expiredKeys []string
+	var ttlSum time.Duration
+	for key, expireAt := range db.expiry {
+		if sampled == count {
+			break
+		}
+		sampled += 1
+		if db.isExpired(key, now) {
+			expiredKeys = append(expiredKeys, key)
+		} else {
+			ttlSum += expireAt.Sub(now)
+		}
+	}
+	for _, key := range expiredKeys {
+		app.deleteExpiredKey(ctx, db, key)
+	}
+
+	// running average of the TTL reported by INFO, like Redis
+	if alive := sampled - len(expiredKeys); alive != 0 {
+		avgTTL := ttlSum / time.Duration(alive)
+		if db.avgTTL == 0 {
+			db.avgTTL = avgTTL
+		} else {
+			db.avgTTL = db.avgTTL/50*49 + avgTTL/50
+		}
+	}
+	return sampled, len(expiredKeys)
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_ActiveExpireNotifies(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	subscriber := newTestClient(t, addr)
+	writer := newTestClient(t, addr)
+
+	writer.do("CONFIG", "SET", "notify-keyspace-events", "Ex")
+	subscriber.do("SUBSCRIBE", "__keyevent@0__:expired")
+	writer.do("SET", "k", "v", "PX", "20")
+
+	// nobody reads the key, the active expire cycle has to delete it
+	reply := subscriber.read()
+	if len(reply.Array) != 3 || reply.Array[0].BulkString != "message" || reply.Array[2].BulkString != "k" {
+		t.Fatalf("expect expired event of `k`, got %+v", reply)
+	}
+	if reply := writer.do("GET", "k"); reply.Sym != types.SymNull {
+		t.Fatalf("expect expired key to be gone, got %+v", reply)
+	}
+}
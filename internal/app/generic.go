@@ -0,0 +1,500 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/dict"
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+var errExpireTimeOverflow = errors.New("expire time overflow")
+
+// expireTimeInMilliseconds converts value, in unit milliseconds, to an absolute
+// unix time in milliseconds by adding it to base. It fails when the result does
+// not fit in an int64 or, when positive is set, when value is not positive.
+func expireTimeInMilliseconds(base, value, unit int64, positive bool) (int64, error) {
+	if positive && value <= 0 {
+		return 0, errExpireTimeOverflow
+	}
+	if value > math.MaxInt64/unit || value < math.MinInt64/unit {
+		return 0, errExpireTimeOverflow
+	}
+	value *= unit
+	if value > 0 && base > math.MaxInt64-value {
+		return 0, errExpireTimeOverflow
+	}
+	return base + value, nil
+}
+
+type expireFlags struct {
+	NX, XX, GT, LT bool
+}
+
+// handleGenericEXPIRE sets the TTL of key to expire at the unix time at, in
+// milliseconds. It follows EXPIRE: the flags make the change conditional and a
+// time in the past deletes the key right away.
+func (app *App) handleGenericEXPIRE(ctx context.Context, key string, at int64, flags expireFlags) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	if flags.NX && (flags.XX || flags.GT || flags.LT) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if flags.GT && flags.LT {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "GT and LT options at the same time are not compatible")
+	}
+
+	if _, exists := app.lookupKeyWrite(ctx, db, key); !exists {
+		return types.NewIntegerRawCmd(0), nil
+	}
+
+	expireAt := time.UnixMilli(at)
+	// a key without TTL has an infinite one for GT and LT
+	current, hasExpire := db.getExpire(key)
+	switch {
+	case flags.NX && hasExpire,
+		flags.XX && !hasExpire,
+		flags.GT && (!hasExpire || !expireAt.After(current)),
+		flags.LT && hasExpire && !expireAt.Before(current):
+		return types.NewIntegerRawCmd(0), nil
+	}
+
+	if !expireAt.After(time.Now()) {
+		app.deleteKey(ctx, db, key)
+		app.notifyKeyspaceEvent(config.NotifyGeneric, "del", key, db.id)
+		return types.NewIntegerRawCmd(1), nil
+	}
+
+	db.setExpire(key, expireAt)
+	app.signalModifiedKey(ctx, key)
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "expire", key, db.id)
+	return types.NewIntegerRawCmd(1), nil
+}
+
+func (app *App) handleEXPIRE(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.EXPIRE](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	at, err := expireTimeInMilliseconds(time.Now().UnixMilli(), c.Seconds, 1000, false)
+	if err != nil {
+		return types.RawCmd{}, NewInvalidExpireTimeError(args[0])
+	}
+	return app.handleGenericEXPIRE(ctx, c.Key, at, expireFlags{c.NX, c.XX, c.GT, c.LT})
+}
+
+func (app *App) handlePEXPIRE(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PEXPIRE](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	at, err := expireTimeInMilliseconds(time.Now().UnixMilli(), c.Milliseconds, 1, false)
+	if err != nil {
+		return types.RawCmd{}, NewInvalidExpireTimeError(args[0])
+	}
+	return app.handleGenericEXPIRE(ctx, c.Key, at, expireFlags{c.NX, c.XX, c.GT, c.LT})
+}
+
+func (app *App) handleEXPIREAT(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.EXPIREAT](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	at, err := expireTimeInMilliseconds(0, c.UnixTimeSeconds, 1000, false)
+	if err != nil {
+		return types.RawCmd{}, NewInvalidExpireTimeError(args[0])
+	}
+	return app.handleGenericEXPIRE(ctx, c.Key, at, expireFlags{c.NX, c.XX, c.GT, c.LT})
+}
+
+func (app *App) handlePEXPIREAT(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PEXPIREAT](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericEXPIRE(ctx, c.Key, c.UnixTimeMilliseconds, expireFlags{c.NX, c.XX, c.GT, c.LT})
+}
+
+// handleGenericTTL replies with the remaining time to live of key, or with the
+// unix time it expires at when absolute is set. A missing key is -2 and a key
+// without TTL is -1.
+func (app *App) handleGenericTTL(ctx context.Context, key string, unit time.Duration, absolute bool) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	if _, exists := app.lookupKeyRead(ctx, db, key); !exists {
+		return types.NewIntegerRawCmd(-2), nil
+	}
+	expireAt, hasExpire := db.getExpire(key)
+	if !hasExpire {
+		return types.NewIntegerRawCmd(-1), nil
+	}
+
+	// both round to the closest second like Redis
+	if absolute {
+		ms, unitMs := expireAt.UnixMilli(), unit.Milliseconds()
+		return types.NewIntegerRawCmd((ms + unitMs/2) / unitMs), nil
+	}
+	ttl := max(time.Until(expireAt), 0)
+	return types.NewIntegerRawCmd(int64((ttl + unit/2) / unit)), nil
+}
+
+func (app *App) handleTTL(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.TTL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericTTL(ctx, c.Key, time.Second, false)
+}
+
+func (app *App) handlePTTL(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PTTL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericTTL(ctx, c.Key, time.Millisecond, false)
+}
+
+func (app *App) handleEXPIRETIME(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.EXPIRETIME](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericTTL(ctx, c.Key, time.Second, true)
+}
+
+func (app *App) handlePEXPIRETIME(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PEXPIRETIME](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericTTL(ctx, c.Key, time.Millisecond, true)
+}
+
+func (app *App) handlePERSIST(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PERSIST](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+	if _, exists := app.lookupKeyWrite(ctx, db, c.Key); !exists {
+		return types.NewIntegerRawCmd(0), nil
+	}
+	if !db.removeExpire(c.Key) {
+		return types.NewIntegerRawCmd(0), nil
+	}
+	app.signalModifiedKey(ctx, c.Key)
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "persist", c.Key, db.id)
+	return types.NewIntegerRawCmd(1), nil
+}
+
+// handleGenericDEL deletes the existing keys and replies with their count,
+// only detaches the values when lazy like UNLINK
+func (app *App) handleGenericDEL(ctx context.Context, keys []string, lazy bool) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	var deleted int64
+	for _, key := range keys {
+		value, exists := app.lookupKeyWrite(ctx, db, key)
+		if !exists {
+			continue
+		}
+		app.deleteKey(ctx, db, key)
+		if lazy {
+			app.lazyfreeValue(value)
+		}
+		app.notifyKeyspaceEvent(config.NotifyGeneric, "del", key, db.id)
+		deleted += 1
+	}
+	return types.NewIntegerRawCmd(deleted), nil
+}
+
+func (app *App) handleDEL(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.DEL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericDEL(ctx, append([]string{c.Key}, c.KeyRest...), false)
+}
+
+func (app *App) handleUNLINK(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.UNLINK](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericDEL(ctx, append([]string{c.Key}, c.KeyRest...), true)
+}
+
+// countExistingKeys is EXISTS and TOUCH, a key given twice is counted twice
+func (app *App) countExistingKeys(ctx context.Context, keys []string) types.RawCmd {
+	db := app.selectedDB(ctx)
+	var count int64
+	for _, key := range keys {
+		if _, exists := app.lookupKeyRead(ctx, db, key); exists {
+			count += 1
+		}
+	}
+	return types.NewIntegerRawCmd(count)
+}
+
+func (app *App) handleEXISTS(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.EXISTS](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.countExistingKeys(ctx, append([]string{c.Key}, c.KeyRest...)), nil
+}
+
+func (app *App) handleTOUCH(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.TOUCH](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.countExistingKeys(ctx, append([]string{c.Key}, c.KeyRest...)), nil
+}
+
+func (app *App) handleKEYS(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.KEYS](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+
+	now := time.Now()
+	allKeys := c.Pattern == "*"
+	var keys []string
+	for key := range db.dict.All() {
+		// expired keys are skipped but left to the expire cycle
+		if db.isExpired(key, now) {
+			continue
+		}
+		if allKeys || glob.Match(c.Pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	return types.NewBulkArrayBulkString(keys), nil
+}
+
+func (app *App) handleGenericRENAME(ctx context.Context, key, newKey string, nx bool) (types.RawCmd, error) {
+	db := app.selectedDB(ctx)
+	value, exists := app.lookupKeyWrite(ctx, db, key)
+	if !exists {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "no such key")
+	}
+	if key == newKey {
+		if nx {
+			return types.NewIntegerRawCmd(0), nil
+		}
+		return types.NewStringRawCmd("OK"), nil
+	}
+
+	if _, newKeyExists := app.lookupKeyWrite(ctx, db, newKey); newKeyExists {
+		if nx {
+			return types.NewIntegerRawCmd(0), nil
+		}
+		app.deleteKey(ctx, db, newKey)
+	}
+
+	expireAt, hasExpire := db.getExpire(key)
+	app.deleteKey(ctx, db, key)
+	app.setKey(ctx, db, newKey, value)
+	if hasExpire {
+		db.setExpire(newKey, expireAt)
+	}
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "rename_from", key, db.id)
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "rename_to", newKey, db.id)
+	if value.ValueType == ValueTypeList {
+		app.NotifyAndPopBLPOPConsumer(ctx, db, newKey)
+	}
+
+	if nx {
+		return types.NewIntegerRawCmd(1), nil
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleRENAME(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.RENAME](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericRENAME(ctx, c.Key, c.NewKey, false)
+}
+
+func (app *App) handleRENAMENX(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.RENAMENX](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return app.handleGenericRENAME(ctx, c.Key, c.NewKey, true)
+}
+
+func (app *App) handleCOPY(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.COPY](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+	destinationDB := db
+	if c.DB != nil {
+		if destinationDB, err = app.dbByIndex(*c.DB); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+	if c.Source == c.Destination && db == destinationDB {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "source and destination objects are the same")
+	}
+
+	value, exists := app.lookupKeyRead(ctx, db, c.Source)
+	if !exists {
+		return types.NewIntegerRawCmd(0), nil
+	}
+	if _, destinationExists := app.lookupKeyWrite(ctx, destinationDB, c.Destination); destinationExists {
+		if !c.REPLACE {
+			return types.NewIntegerRawCmd(0), nil
+		}
+		app.deleteKey(ctx, destinationDB, c.Destination)
+	}
+
+	// values are mutated in place, the copy must not share the list
+	value.List = slices.Clone(value.List)
+	value.listpack.data = slices.Clone(value.listpack.data)
+	app.setKey(ctx, destinationDB, c.Destination, value)
+	if expireAt, hasExpire := db.getExpire(c.Source); hasExpire {
+		destinationDB.setExpire(c.Destination, expireAt)
+	}
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "copy_to", c.Destination, destinationDB.id)
+	if value.ValueType == ValueTypeList {
+		app.NotifyAndPopBLPOPConsumer(ctx, destinationDB, c.Destination)
+	}
+	return types.NewIntegerRawCmd(1), nil
+}
+
+func (app *App) handleMOVE(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.MOVE](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+	destinationDB, err := app.dbByIndex(c.DB)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	if db == destinationDB {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "source and destination objects are the same")
+	}
+
+	value, exists := app.lookupKeyWrite(ctx, db, c.Key)
+	if !exists {
+		return types.NewIntegerRawCmd(0), nil
+	}
+	if _, destinationExists := app.lookupKeyWrite(ctx, destinationDB, c.Key); destinationExists {
+		return types.NewIntegerRawCmd(0), nil
+	}
+
+	expireAt, hasExpire := db.getExpire(c.Key)
+	app.deleteKey(ctx, db, c.Key)
+	app.setKey(ctx, destinationDB, c.Key, value)
+	if hasExpire {
+		destinationDB.setExpire(c.Key, expireAt)
+	}
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "move_from", c.Key, db.id)
+	app.notifyKeyspaceEvent(config.NotifyGeneric, "move_to", c.Key, destinationDB.id)
+	if value.ValueType == ValueTypeList {
+		app.NotifyAndPopBLPOPConsumer(ctx, destinationDB, c.Key)
+	}
+	return types.NewIntegerRawCmd(1), nil
+}
+
+func (app *App) handleSWAPDB(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.SWAPDB](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db1, err := app.dbByIndex(c.Index1)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db2, err := app.dbByIndex(c.Index2)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	// clients stay on the same index, only the keyspaces are exchanged
+	db1.dict, db2.dict = db2.dict, db1.dict
+	db1.expiry, db2.expiry = db2.expiry, db1.expiry
+	db1.usedMemory, db2.usedMemory = db2.usedMemory, db1.usedMemory
+	db1.avgTTL, db2.avgTTL = db2.avgTTL, db1.avgTTL
+	app.trackingInvalidateAll()
+	for _, db := range []*redisDB{db1, db2} {
+		for key := range db.blpopConsumers {
+			app.NotifyAndPopBLPOPConsumer(ctx, db, key)
+		}
+	}
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleRANDOMKEY(ctx context.Context, args []string) (types.RawCmd, error) {
+	if _, err := argsparser.Parse[cmd.RANDOMKEY](args); err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+	for {
+		key, _, exists := db.dict.RandomKey()
+		if !exists {
+			return types.NewNullRawCmd(), nil
+		}
+		if app.expireIfNeeded(ctx, db, key) {
+			continue
+		}
+		return types.NewBulkStringRawCmd(key), nil
+	}
+}
+
+func (app *App) handleDBSIZE(ctx context.Context, args []string) (types.RawCmd, error) {
+	if _, err := argsparser.Parse[cmd.DBSIZE](args); err != nil {
+		return types.RawCmd{}, err
+	}
+	return types.NewIntegerRawCmd(int64(app.selectedDB(ctx).dict.Len())), nil
+}
+
+// emptyDB removes every key of db, the old keyspace is only detached
+// when async is set
+func (app *App) emptyDB(db *redisDB, async bool) {
+	app.dirty += int64(db.dict.Len())
+	if async {
+		app.lazyfreeDict(db.dict)
+		db.dict = dict.New[Value]()
+		db.expiry = map[string]time.Time{}
+	} else {
+		db.dict.Clear()
+		clear(db.expiry)
+	}
+	db.usedMemory = 0
+	db.avgTTL = 0
+}
+
+func (app *App) handleFLUSHDB(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.FLUSHDB](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	app.emptyDB(app.selectedDB(ctx), c.Mode.ASYNC)
+	app.trackingInvalidateAll()
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleFLUSHALL(args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.FLUSHALL](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	for _, db := range app.dbs {
+		app.emptyDB(db, c.Mode.ASYNC)
+	}
+	app.trackingInvalidateAll()
+	return types.NewStringRawCmd("OK"), nil
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_ExpireFlags(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	expectEqual(t, int64(-2), client.do("TTL", "k").Integer)
+	client.do("SET", "k", "v")
+	expectEqual(t, int64(-1), client.do("TTL", "k").Integer)
+
+	expectEqual(t, int64(0), client.do("EXPIRE", "k", "100", "XX").Integer)
+	expectEqual(t, int64(0), client.do("EXPIRE", "k", "100", "GT").Integer)
+	expectEqual(t, int64(1), client.do("EXPIRE", "k", "100", "NX").Integer)
+	expectEqual(t, int64(0), client.do("EXPIRE", "k", "200", "NX").Integer)
+	expectEqual(t, int64(0), client.do("EXPIRE", "k", "200", "LT").Integer)
+	expectEqual(t, int64(1), client.do("EXPIRE", "k", "200", "GT").Integer)
+	expectEqual(t, int64(200), client.do("TTL", "k").Integer)
+
+	if reply := client.do("EXPIRE", "k", "1", "NX", "GT"); reply.Sym != types.SymError {
+		t.Fatalf("expect incompatible flags error, got %+v", reply)
+	}
+	if reply := client.do("EXPIRE", "k", "9223372036854775807"); reply.Sym != types.SymError {
+		t.Fatalf("expect overflow error, got %+v", reply)
+	}
+
+	expectEqual(t, int64(1), client.do("PERSIST", "k").Integer)
+	expectEqual(t, int64(0), client.do("PERSIST", "k").Integer)
+	expectEqual(t, int64(-1), client.do("PEXPIRETIME", "k").Integer)
+
+	// a time in the past deletes the key
+	expectEqual(t, int64(1), client.do("EXPIREAT", "k", "1").Integer)
+	expectEqual(t, int64(-2), client.do("TTL", "k").Integer)
+}
+
+func Test_SETClearsTTL(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	client.do("SET", "k", "v", "EXAT", "4102444800")
+	expectEqual(t, int64(4102444800), client.do("EXPIRETIME", "k").Integer)
+
+	client.do("SET", "k", "v2", "KEEPTTL")
+	expectEqual(t, int64(4102444800000), client.do("PEXPIRETIME", "k").Integer)
+
+	// EXPIRETIME rounds a sub-second remainder to the closest second
+	client.do("PEXPIREAT", "k", "4102444800600")
+	expectEqual(t, int64(4102444801), client.do("EXPIRETIME", "k").Integer)
+	client.do("PEXPIREAT", "k", "4102444800400")
+	expectEqual(t, int64(4102444800), client.do("EXPIRETIME", "k").Integer)
+	expectEqual(t, int64(4102444800400), client.do("PEXPIRETIME", "k").Integer)
+
+	client.do("SET", "k", "v3")
+	expectEqual(t, int64(-1), client.do("TTL", "k").Integer)
+
+	if reply := client.do("SET", "k", "v", "EX", "0"); reply.Sym != types.SymError {
+		t.Fatalf("expect invalid expire time error, got %+v", reply)
+	}
+	expectEqual(t, "ERR syntax error", client.do("SET", "k", "v", "EX", "10", "KEEPTTL").Error)
+	expectEqual(t, "ERR syntax error", client.do("SET", "k", "v", "NX", "XX").Error)
+}
+
+func Test_KeyspaceCommands(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	client.do("SET", "a", "1")
+	client.do("RPUSH", "list", "x", "y")
+	expectEqual(t, int64(3), client.do("EXISTS", "a", "a", "list").Integer)
+	expectEqual(t, int64(2), client.do("DBSIZE").Integer)
+	expectEqual(t, 1, len(client.do("KEYS", "l*").Array))
+
+	expectEqual(t, int64(1), client.do("COPY", "list", "list2").Integer)
+	expectEqual(t, int64(0), client.do("COPY", "list", "list2").Integer)
+	client.do("RPOP", "list2")
+	expectEqual(t, int64(2), client.do("LLEN", "list").Integer)
+
+	// popping the last element deletes the list
+	client.do("LPOP", "list2")
+	expectEqual(t, int64(0), client.do("EXISTS", "list2").Integer)
+
+	client.do("EXPIRE", "a", "100")
+	expectEqual(t, "OK", client.do("RENAME", "a", "b").String)
+	expectEqual(t, int64(100), client.do("TTL", "b").Integer)
+	expectEqual(t, int64(0), client.do("RENAMENX", "b", "list").Integer)
+	if reply := client.do("RENAME", "a", "c"); reply.Sym != types.SymError {
+		t.Fatalf("expect no such key error, got %+v", reply)
+	}
+
+	expectEqual(t, int64(2), client.do("UNLINK", "b", "list", "missing").Integer)
+	expectEqual(t, types.SymNull, client.do("RANDOMKEY").Sym)
+
+	client.do("SET", "a", "1")
+	expectEqual(t, "a", client.do("RANDOMKEY").BulkString)
+	expectEqual(t, "OK", client.do("FLUSHALL", "ASYNC").String)
+	expectEqual(t, int64(0), client.do("DBSIZE").Integer)
+}
+
+func Test_MultipleDatabases(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	client := newTestClient(t, addr)
+	subscriber := newTestClient(t, addr)
+
+	client.do("CONFIG", "SET", "notify-keyspace-events", "Kg")
+	subscriber.do("SUBSCRIBE", "__keyspace@2__:k")
+
+	expectEqual(t, "OK", client.do("SELECT", "1").String)
+	client.do("SET", "k", "v1")
+	expectEqual(t, int64(1), client.do("MOVE", "k", "2").Integer)
+	expectEqual(t, int64(0), client.do("EXISTS", "k").Integer)
+
+	reply := subscriber.read()
+	if len(reply.Array) != 3 || reply.Array[2].BulkString != "move_to" {
+		t.Fatalf("expect move_to event in database 2, got %+v", reply)
+	}
+
+	expectEqual(t, "OK", client.do("SWAPDB", "1", "2").String)
+	expectEqual(t, "v1", client.do("GET", "k").BulkString)
+	client.do("SELECT", "0")
+	expectEqual(t, types.SymNull, client.do("GET", "k").Sym)
+
+	client.do("SELECT", "1")
+	expectEqual(t, int64(1), client.do("COPY", "k", "k", "DB", "0").Integer)
+	client.do("SELECT", "0")
+	expectEqual(t, "v1", client.do("GET", "k").BulkString)
+
+	if reply := client.do("SELECT", "16"); reply.Sym != types.SymError {
+		t.Fatalf("expect out of range error, got %+v", reply)
+	}
+}
+
+func Test_NumberArguments(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	client.do("SET", "k", "v")
+
+	expectEqual(t, "ERR value is not an integer or out of range", client.do("EXPIRE", "k", "+5").Error)
+	expectEqual(t, "ERR value is not an integer or out of range", client.do("EXPIRE", "k", "0x10").Error)
+	expectEqual(t, "ERR value is not an integer or out of range", client.do("EXPIRE", "k", "99999999999999999999").Error)
+	expectEqual(t, "ERR timeout is not a float or out of range", client.do("BLPOP", "list", "nan").Error)
+	expectEqual(t, "ERR timeout is negative", client.do("BLPOP", "list", "-1").Error)
+	expectEqual(t, types.SymNull, client.do("BLPOP", "list", "1e-3").Sym)
+}
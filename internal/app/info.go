@@ -0,0 +1,261 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// newRunID returns 40 random hex characters like the run_id of Redis
+func newRunID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type infoSection struct {
+	name   string
+	fields func(app *App) []infoField
+}
+
+type infoField struct {
+	name  string
+	value string
+}
+
+// infoSections are in the order INFO replies with them, they are all part of
+// the default sections
+var infoSections = []infoSection{
+	{"server", (*App).infoServer},
+	{"clients", (*App).infoClients},
+	{"memory", (*App).infoMemory},
+	{"persistence", (*App).infoPersistence},
+	{"stats", (*App).infoStats},
+	{"replication", (*App).infoReplication},
+	{"keyspace", (*App).infoKeyspace},
+}
+
+func (app *App) handleINFO(args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.INFO](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	sections := c.Sections
+	if len(sections) == 0 {
+		sections = []string{"default"}
+	}
+	return types.NewBulkStringRawCmd(app.genInfo(sections)), nil
+}
+
+// genInfo builds the INFO reply for the requested sections, unknown sections
+// are ignored
+func (app *App) genInfo(requested []string) string {
+	all := false
+	for _, name := range requested {
+		switch strings.ToLower(name) {
+		case "default", "all", "everything":
+			all = true
+		}
+	}
+
+	var sb strings.Builder
+	for _, section := range infoSections {
+		if !all && !slices.ContainsFunc(requested, func(name string) bool {
+			return strings.EqualFold(name, section.name)
+		}) {
+			continue
+		}
+		if sb.Len() != 0 {
+			sb.WriteString("\r\n")
+		}
+		fmt.Fprintf(&sb, "# %s\r\n", strings.ToUpper(section.name[:1])+section.name[1:])
+		for _, field := range section.fields(app) {
+			fmt.Fprintf(&sb, "%s:%s\r\n", field.name, field.value)
+		}
+	}
+	return sb.String()
+}
+
+func intField(name string, value int64) infoField {
+	return infoField{name, strconv.FormatInt(value, 10)}
+}
+
+func floatField(name string, value float64) infoField {
+	return infoField{name, strconv.FormatFloat(value, 'f', 2, 64)}
+}
+
+// bytesToHuman formats a memory amount like Redis, e.g. 1.50M
+func bytesToHuman(n int64) string {
+	units := []string{"K", "M", "G", "T", "P"}
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n) / 1024
+	for _, unit := range units[:len(units)-1] {
+		if value < 1024 {
+			return fmt.Sprintf("%.2f%s", value, unit)
+		}
+		value /= 1024
+	}
+	return fmt.Sprintf("%.2f%s", value, units[len(units)-1])
+}
+
+func (app *App) infoServer() []infoField {
+	now := time.Now()
+	uptime := now.Sub(app.startTime)
+	executable, _ := os.Executable()
+	hz := app.config.Int("hz")
+	return []infoField{
+		{"redis_version", serverVersion},
+		{"redis_mode", "standalone"},
+		{"os", runtime.GOOS + " " + runtime.GOARCH},
+		intField("arch_bits", strconv.IntSize),
+		{"go_version", runtime.Version()},
+		intField("process_id", int64(os.Getpid())),
+		{"run_id", app.runID},
+		{"tcp_port", app.config.String("port")},
+		intField("server_time_usec", now.UnixMicro()),
+		intField("uptime_in_seconds", int64(uptime.Seconds())),
+		intField("uptime_in_days", int64(uptime.Hours()/24)),
+		intField("hz", hz),
+		intField("configured_hz", hz),
+		intField("lru_clock", int64(lruClock())),
+		{"executable", executable},
+		{"config_file", app.config.File()},
+	}
+}
+
+func (app *App) infoClients() []infoField {
+	var blocked, tracking, pubsub int64
+	for _, db := range app.dbs {
+		for _, consumers := range db.blpopConsumers {
+			blocked += int64(len(consumers))
+		}
+	}
+	for _, client := range app.clients {
+		if client.tracking.enabled {
+			tracking += 1
+		}
+		if len(client.subscriptions) != 0 {
+			pubsub += 1
+		}
+	}
+	return []infoField{
+		intField("connected_clients", int64(len(app.clients))),
+		intField("blocked_clients", blocked),
+		intField("tracking_clients", tracking),
+		intField("pubsub_clients", pubsub),
+	}
+}
+
+// infoMemory reports as used_memory the memory accounted for the keys, the
+// figure compared to maxmemory by the eviction, and the Go heap apart as
+// used_memory_heap
+func (app *App) infoMemory() []infoField {
+	stats := app.computeMemoryStats()
+	used := app.usedMemory()
+	maxmemory := app.config.Int("maxmemory")
+	return []infoField{
+		intField("used_memory", used),
+		{"used_memory_human", bytesToHuman(used)},
+		intField("used_memory_heap", stats.totalAllocated),
+		{"used_memory_heap_human", bytesToHuman(stats.totalAllocated)},
+		intField("used_memory_rss", stats.rss),
+		{"used_memory_rss_human", bytesToHuman(stats.rss)},
+		intField("used_memory_peak", app.stats.peakUsedMemory),
+		{"used_memory_peak_human", bytesToHuman(app.stats.peakUsedMemory)},
+		{"used_memory_peak_perc", fmt.Sprintf("%.2f%%", percentage(used, app.stats.peakUsedMemory))},
+		intField("used_memory_overhead", stats.overheadTotal),
+		intField("used_memory_startup", stats.startupAllocated),
+		intField("used_memory_dataset", stats.datasetBytes),
+		{"used_memory_dataset_perc", fmt.Sprintf("%.2f%%", percentage(stats.datasetBytes, stats.totalAllocated-stats.startupAllocated))},
+		intField("maxmemory", maxmemory),
+		{"maxmemory_human", bytesToHuman(maxmemory)},
+		{"maxmemory_policy", app.config.String("maxmemory-policy")},
+		floatField("mem_fragmentation_ratio", stats.fragmentation),
+		// nothing is left pending since the values are only detached
+		{"lazyfree_pending_objects", "0"},
+	}
+}
+
+func (app *App) infoPersistence() []infoField {
+	lastSaveStatus := "ok"
+	if !app.lastSaveSucceed {
+		lastSaveStatus = "err"
+	}
+	return []infoField{
+		{"loading", "0"},
+		{"async_loading", "0"},
+		intField("rdb_changes_since_last_save", app.dirty),
+		{"rdb_bgsave_in_progress", "0"},
+		intField("rdb_last_save_time", app.lastSave.Unix()),
+		{"rdb_last_bgsave_status", lastSaveStatus},
+		intField("rdb_saves", app.stats.rdbSaves),
+		{"aof_enabled", "0"},
+	}
+}
+
+func (app *App) infoStats() []infoField {
+	return []infoField{
+		intField("total_connections_received", app.stats.totalConnectionsReceived),
+		intField("total_commands_processed", app.stats.totalCommandsProcessed),
+		intField("instantaneous_ops_per_sec", int64(app.stats.instantaneousOps.perSecond())),
+		intField("total_net_input_bytes", app.net.inputBytes.Load()),
+		intField("total_net_output_bytes", app.net.outputBytes.Load()),
+		floatField("instantaneous_input_kbps", app.stats.instantaneousInputBytes.perSecond()/1024),
+		floatField("instantaneous_output_kbps", app.stats.instantaneousOutputBytes.perSecond()/1024),
+		{"rejected_connections", "0"},
+		intField("expired_keys", app.stats.expiredKeys),
+		floatField("expired_stale_perc", app.stats.expiredStalePerc*100),
+		intField("expired_time_cap_reached_count", app.stats.expiredTimeCapReachedCount),
+		intField("expire_cycle_cpu_milliseconds", app.stats.expireCycleTime.Milliseconds()),
+		intField("evicted_keys", app.stats.evictedKeys),
+		intField("keyspace_hits", app.stats.keyspaceHits),
+		intField("keyspace_misses", app.stats.keyspaceMisses),
+		intField("pubsub_channels", int64(len(app.pubsubChannels))),
+		{"pubsub_patterns", "0"},
+		intField("lazyfreed_objects", app.lazyfree.freedObjects),
+		intField("total_error_replies", app.stats.totalErrorReplies),
+		intField("acl_access_denied_auth", app.stats.aclAccessDeniedAuth),
+		intField("acl_access_denied_cmd", app.stats.aclAccessDeniedCmd),
+		intField("acl_access_denied_key", app.stats.aclAccessDeniedKey),
+		intField("acl_access_denied_channel", app.stats.aclAccessDeniedChannel),
+	}
+}
+
+func (app *App) infoReplication() []infoField {
+	return []infoField{
+		{"role", "master"},
+		{"connected_slaves", "0"},
+		{"master_failover_state", "no-failover"},
+		{"master_replid", app.runID},
+		{"master_replid2", strings.Repeat("0", 40)},
+		{"master_repl_offset", "0"},
+		{"second_repl_offset", "-1"},
+		{"repl_backlog_active", "0"},
+	}
+}
+
+func (app *App) infoKeyspace() []infoField {
+	var fields []infoField
+	for _, db := range app.dbs {
+		if db.dict.Len() == 0 {
+			continue
+		}
+		fields = append(fields, infoField{
+			fmt.Sprintf("db%d", db.id),
+			fmt.Sprintf("keys=%d,expires=%d,avg_ttl=%d,subexpiry=0", db.dict.Len(), len(db.expiry), db.avgTTL.Milliseconds()),
+		})
+	}
+	return fields
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+)
+
+// parseInfo returns the fields of an INFO reply by name
+func parseInfo(info string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(info, "\r\n") {
+		if name, value, found := strings.Cut(line, ":"); found && !strings.HasPrefix(line, "#") {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+func Test_INFO(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	client.do("SET", "foo", "bar")
+	client.do("SET", "bar", "foo", "EX", "100")
+	client.do("GET", "foo")
+	client.do("GET", "missing")
+	client.do("SELECT", "3")
+	client.do("SET", "foo", "bar")
+
+	info := client.do("INFO").BulkString
+	for _, section := range []string{"# Server", "# Clients", "# Memory", "# Persistence", "# Stats", "# Replication", "# Keyspace"} {
+		if !strings.Contains(info, section+"\r\n") {
+			t.Errorf("expect section %q in %q", section, info)
+		}
+	}
+	fields := parseInfo(info)
+	expectEqual(t, "1", fields["connected_clients"])
+	expectEqual(t, "1", fields["keyspace_hits"])
+	expectEqual(t, "1", fields["keyspace_misses"])
+	expectEqual(t, "3", fields["rdb_changes_since_last_save"])
+	expectEqual(t, "master", fields["role"])
+	expectEqual(t, "keys=2,expires=1,avg_ttl=0,subexpiry=0", fields["db0"])
+	expectEqual(t, "keys=1,expires=0,avg_ttl=0,subexpiry=0", fields["db3"])
+	// the memory compared to maxmemory by the eviction
+	expectEqual(t, fields["used_memory_dataset"], fields["used_memory"])
+	expectEqual(t, fields["used_memory"], fields["used_memory_peak"])
+
+	// only the requested sections, case insensitive
+	info = client.do("INFO", "STATS", "keyspace").BulkString
+	if !strings.HasPrefix(info, "# Stats\r\n") || !strings.Contains(info, "\r\n\r\n# Keyspace\r\n") || strings.Contains(info, "# Server") {
+		t.Errorf("unexpected sections in %q", info)
+	}
+	expectEqual(t, "", client.do("INFO", "unknown").BulkString)
+
+	client.do("CONFIG", "RESETSTAT")
+	expectEqual(t, "0", parseInfo(client.do("INFO", "stats").BulkString)["keyspace_hits"])
+}
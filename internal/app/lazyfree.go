@@ -0,0 +1,35 @@
+package app
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/dict"
+)
+
+// UNLINK and FLUSHALL ASYNC only detach the values from the keyspace, like
+// Redis lazyfree the command does not walk them: the garbage collector
+// reclaims them once nothing refers to them. Values whose free effort is above
+// lazyfreeThreshold are counted as lazy freed objects.
+const lazyfreeThreshold = 64
+
+type lazyfree struct {
+	freedObjects int64
+}
+
+// freeEffort is an estimate of the work needed to free value
+func freeEffort(value Value) int {
+	if value.ValueType == ValueTypeList {
+		return len(value.List)
+	}
+	return 1
+}
+
+// lazyfreeValue accounts for a value already removed from the keyspace
+func (app *App) lazyfreeValue(value Value) {
+	if freeEffort(value) > lazyfreeThreshold {
+		app.lazyfree.freedObjects += 1
+	}
+}
+
+// lazyfreeDict accounts for a keyspace replaced by an empty one
+func (app *App) lazyfreeDict(d *dict.Dict[Value]) {
+	app.lazyfree.freedObjects += int64(d.Len())
+}
@@ -0,0 +1,149 @@
+package app
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// listpack lays the elements of a small list out in a single allocation, each
+// entry is the length of the element as an uvarint followed by its bytes. The
+// elements are pushed and popped in place, without decoding the whole list.
+type listpack struct {
+	data []byte
+	// number of entries
+	length int
+}
+
+func newListpack(list []string) listpack {
+	var header [binary.MaxVarintLen64]byte
+	size := 0
+	for _, elem := range list {
+		size += binary.PutUvarint(header[:], uint64(len(elem))) + len(elem)
+	}
+	return listpack{data: appendEntries(make([]byte, 0, size), list), length: len(list)}
+}
+
+func appendEntries(data []byte, elems []string) []byte {
+	for _, elem := range elems {
+		data = binary.AppendUvarint(data, uint64(len(elem)))
+		data = append(data, elem...)
+	}
+	return data
+}
+
+// offset returns where the entry at idx starts, only the headers of the
+// entries before it are read
+func (lp listpack) offset(idx int) int {
+	offset := 0
+	for range idx {
+		length, n := binary.Uvarint(lp.data[offset:])
+		offset += n + int(length)
+	}
+	return offset
+}
+
+// decode returns count elements from the entry starting at offset
+func (lp listpack) decode(offset, count int) []string {
+	elems := make([]string, 0, count)
+	for range count {
+		length, n := binary.Uvarint(lp.data[offset:])
+		offset += n
+		elems = append(elems, string(lp.data[offset:offset+int(length)]))
+		offset += int(length)
+	}
+	return elems
+}
+
+// rangeOf returns the elements from start to stop excluded
+func (lp listpack) rangeOf(start, stop int) []string {
+	return lp.decode(lp.offset(start), stop-start)
+}
+
+func (lp listpack) elements() []string {
+	return lp.decode(0, lp.length)
+}
+
+// push adds elems in order at the head or the tail
+func (lp *listpack) push(elems []string, atHead bool) {
+	if atHead {
+		head := newListpack(elems)
+		lp.data = append(slices.Grow(head.data, len(lp.data)), lp.data...)
+	} else {
+		lp.data = appendEntries(lp.data, elems)
+	}
+	lp.length += len(elems)
+}
+
+// pop removes count elements from the head or the tail and returns them in the
+// order they are popped
+func (lp *listpack) pop(count int, fromHead bool) []string {
+	if fromHead {
+		offset := lp.offset(count)
+		elems := lp.decode(0, count)
+		lp.data = lp.data[offset:]
+		lp.length -= count
+		return elems
+	}
+	offset := lp.offset(lp.length - count)
+	elems := lp.decode(offset, count)
+	slices.Reverse(elems)
+	// the next push must not write over the entries the value stored in the
+	// keyspace still holds
+	lp.data = lp.data[:offset:offset]
+	lp.length -= count
+	return elems
+}
+
+// The list commands go through the methods below, which work on the elements
+// of a list in either encoding.
+
+func (value Value) listLen() int {
+	if value.encoding == EncodingListpack {
+		return value.listpack.length
+	}
+	return len(value.List)
+}
+
+// listRange returns the elements from start to stop excluded
+func (value Value) listRange(start, stop int) []string {
+	if value.encoding == EncodingListpack {
+		return value.listpack.rangeOf(start, stop)
+	}
+	return value.List[start:stop]
+}
+
+func (value Value) listElements() []string {
+	return value.listRange(0, value.listLen())
+}
+
+// listPush adds elems in order at the head or the tail of the list
+func (value *Value) listPush(elems []string, atHead bool) {
+	switch {
+	case value.encoding == EncodingListpack:
+		value.listpack.push(elems, atHead)
+	case atHead:
+		value.List = append(elems, value.List...)
+	default:
+		value.List = append(value.List, elems...)
+	}
+}
+
+// listPop removes up to count elements from the head or the tail of the list
+// and returns them in the order they are popped
+func (value *Value) listPop(count int, fromHead bool) []string {
+	length := value.listLen()
+	count = min(max(count, 0), length)
+	switch {
+	case value.encoding == EncodingListpack:
+		return value.listpack.pop(count, fromHead)
+	case fromHead:
+		elems := value.List[:count:count]
+		value.List = value.List[count:]
+		return elems
+	default:
+		elems := slices.Clone(value.List[length-count:])
+		slices.Reverse(elems)
+		value.List = value.List[: length-count : length-count]
+		return elems
+	}
+}
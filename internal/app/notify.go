@@ -1,29 +1,38 @@
 package app
 
-import "github.com/codecrafters-io/redis-starter-go/pkg/ulid"
+import (
+	"context"
+	"fmt"
+	"slices"
 
-func (app *App) SubscribeKeySpace(id ulid.ID) chan Value {
-	if ch, exists := app.keySpaceConsumer[id]; exists {
-		return ch
-	}
-	ch := make(chan Value)
-	app.keySpaceConsumer[id] = ch
-	return ch
-}
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/ulid"
+)
 
-func (app *App) UnsubscribeKeySpace(id ulid.ID) {
-	close(app.keySpaceConsumer[id])
-	delete(app.keySpaceConsumer, id)
-}
+// notifyKeyspaceEvent publishes a keyspace notification for key when the class
+// of the event is enabled by notify-keyspace-events:
+//   - __keyspace@<db>__:<key> receives the event name
+//   - __keyevent@<db>__:<event> receives the key
+func (app *App) notifyKeyspaceEvent(class int, event, key string, dbID int) {
+	flags := app.config.KeyspaceEvents()
+	if flags&class == 0 {
+		return
+	}
 
-func (app *App) NotifyKeySpace(value Value) {
-	for _, ch := range app.keySpaceConsumer {
-		ch <- value
+	if flags&config.NotifyKeyspace != 0 {
+		app.publish(fmt.Sprintf("__keyspace@%d__:%s", dbID, key), event)
+	}
+	if flags&config.NotifyKeyevent != 0 {
+		app.publish(fmt.Sprintf("__keyevent@%d__:%s", dbID, event), key)
 	}
 }
 
-func (app *App) SubscribeBLPOPConsumer(id ulid.ID, key string) chan struct{} {
-	ch := make(chan struct{})
+// SubscribeBLPOPConsumer queues a client waiting for an element of key, the
+// element is sent on the returned channel once a push serves it
+func (app *App) SubscribeBLPOPConsumer(db *redisDB, id ulid.ID, key string) chan string {
+	// buffered so serving a consumer never blocks, even when it is about to
+	// give up: it checks the channel once it holds the mutex again
+	ch := make(chan string, 1)
 
 	c := BLPOPConsumer{
 		id:  id,
@@ -31,39 +40,45 @@ func (app *App) SubscribeBLPOPConsumer(id ulid.ID, key string) chan struct{} {
 		ch:  ch,
 	}
 
-	cs := append(app.blpopConsumers[key], c)
-	app.blpopConsumers[key] = cs
+	cs := append(db.blpopConsumers[key], c)
+	db.blpopConsumers[key] = cs
 
 	return ch
 }
 
-func (app *App) UnsubscribeBLOPConsumer(id ulid.ID, key string) {
-	cs := app.blpopConsumers[key]
-	if len(cs) == 0 {
-		return
-	}
-
+// UnsubscribeBLOPConsumer removes a consumer that has not been served
+func (app *App) UnsubscribeBLOPConsumer(db *redisDB, id ulid.ID, key string) {
+	cs := db.blpopConsumers[key]
 	for idx, c := range cs {
-		if c.id != id {
-			continue
+		if c.id == id {
+			cs = slices.Delete(cs, idx, idx+1)
+			break
 		}
-		close(c.ch)
-		cs = append(cs[:idx], cs[idx+1:]...)
-		break
 	}
+	if len(cs) == 0 {
+		delete(db.blpopConsumers, key)
+	} else {
+		db.blpopConsumers[key] = cs
+	}
+}
 
-	app.blpopConsumers[key] = cs
+// NotifyAndPopBLPOPConsumer serves the consumers waiting for key in order, as
+// long as the list has elements. Elements are popped while holding the mutex
+// so none can be lost or taken by another command before the consumer runs.
+func (app *App) NotifyAndPopBLPOPConsumer(ctx context.Context, db *redisDB, key string) {
+	for len(db.blpopConsumers[key]) != 0 {
+		value, exists := db.dict.Get(key)
+		if !exists || value.ValueType != ValueTypeList || value.listLen() == 0 {
+			return
+		}
 
-}
+		c := db.blpopConsumers[key][0]
+		app.UnsubscribeBLOPConsumer(db, c.id, key)
 
-func (app *App) NotifyAndPopBLPOPConsumer(key string) {
-	cs := app.blpopConsumers[key]
-	if len(cs) == 0 {
-		return
+		v := value.listPop(1, true)[0]
+		app.setKey(ctx, db, key, value)
+		app.notifyKeyspaceEvent(config.NotifyList, "lpop", key, db.id)
+		app.deleteListIfEmpty(ctx, db, key, value)
+		c.ch <- v
 	}
-	c := cs[0]
-	cs = cs[1:]
-	app.blpopConsumers[key] = cs
-	c.ch <- struct{}{}
-	close(c.ch)
 }
@@ -0,0 +1,389 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// Encoding is the representation of a stored value, like Redis small values
+// are kept in a compact form. It is reported by OBJECT ENCODING and drives the
+// memory accounting.
+type Encoding int
+
+const (
+	EncodingRaw Encoding = iota
+	EncodingInt
+	EncodingEmbStr
+	EncodingListpack
+	EncodingQuicklist
+)
+
+func EncodingToName(encoding Encoding) string {
+	switch encoding {
+	case EncodingRaw:
+		return "raw"
+	case EncodingInt:
+		return "int"
+	case EncodingEmbStr:
+		return "embstr"
+	case EncodingListpack:
+		return "listpack"
+	case EncodingQuicklist:
+		return "quicklist"
+	default:
+		return fmt.Sprintf("unknown-%d", int(encoding))
+	}
+}
+
+// strings up to embstrSizeLimit bytes are allocated with their object
+const embstrSizeLimit = 44
+
+func stringEncoding(s string) Encoding {
+	if len(s) <= 20 {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+			return EncodingInt
+		}
+	}
+	if len(s) <= embstrSizeLimit {
+		return EncodingEmbStr
+	}
+	return EncodingRaw
+}
+
+// listpackFits tells whether a list of length elements taking size bytes in a
+// listpack is small enough for one according to list-max-listpack-size: a
+// positive limit is a number of elements, a negative one a size from 4kb (-1)
+// to 64kb (-5)
+func (app *App) listpackFits(length int, size int64, ratio int64) bool {
+	limit := app.config.ListMaxListpackSize()
+	if limit > 0 {
+		return int64(length)*ratio <= limit
+	}
+	return size*ratio <= int64(4096)<<(-limit-1)
+}
+
+// listFitsListpack tells whether list would fit in a listpack, its size is
+// estimated from samples of its elements
+func (app *App) listFitsListpack(list []string, ratio int64) bool {
+	size := listpackMemoryOverhead + int64(len(list)*listpackEntryMemoryOverhead)
+	// every entry takes at least its header, no need to sample a long list
+	if !app.listpackFits(len(list), size, ratio) {
+		return false
+	}
+	return app.listpackFits(len(list), size+sampledListBytes(list, memoryUsageSamples), ratio)
+}
+
+// updateEncoding picks the encoding of value and compacts it before it is
+// stored. A list only changes encoding when it crosses the limit, and like
+// Redis a list converted to a quicklist only goes back to a listpack once it
+// shrinks to half the limit, so a list around the limit does not flip at
+// every push.
+func (app *App) updateEncoding(value *Value) {
+	*value = value.decoded()
+	switch value.ValueType {
+	case ValueTypeString:
+		value.encoding = stringEncoding(value.String)
+		if value.encoding == EncodingInt {
+			value.integer, _ = strconv.ParseInt(value.String, 10, 64)
+			value.String = ""
+		}
+	case ValueTypeList:
+		switch value.encoding {
+		case EncodingListpack:
+			if !app.listpackFits(value.listpack.length, listpackMemoryOverhead+int64(len(value.listpack.data)), 1) {
+				value.List = value.listpack.elements()
+				value.listpack = listpack{}
+				value.encoding = EncodingQuicklist
+			}
+		case EncodingQuicklist:
+			if app.listFitsListpack(value.List, 2) {
+				value.listpack = newListpack(value.List)
+				value.List = nil
+				value.encoding = EncodingListpack
+			}
+		default:
+			// a new list, its elements are in List
+			if app.listFitsListpack(value.List, 1) {
+				value.listpack = newListpack(value.List)
+				value.List = nil
+				value.encoding = EncodingListpack
+			} else {
+				value.encoding = EncodingQuicklist
+			}
+		}
+	}
+}
+
+// decoded returns value with the number of an int string in String, the form
+// the commands work on. The encoding is kept for the next updateEncoding, the
+// lists are worked on in their encoding.
+func (value Value) decoded() Value {
+	if value.encoding == EncodingInt && value.String == "" {
+		value.String = strconv.FormatInt(value.integer, 10)
+		value.integer = 0
+	}
+	return value
+}
+
+func (app *App) handleOBJECT(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.OBJECT](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.ENCODING != nil:
+		return app.handleOBJECTENCODING(ctx, *c.ENCODING)
+	case c.IDLETIME != nil:
+		return app.handleOBJECTIDLETIME(ctx, *c.IDLETIME)
+	case c.FREQ != nil:
+		return app.handleOBJECTFREQ(ctx, *c.FREQ)
+	case c.REFCOUNT != nil:
+		return app.handleOBJECTREFCOUNT(ctx, *c.REFCOUNT)
+	default: // HELP
+		return subcommandHelp[cmd.OBJECT]("object")
+	}
+}
+
+// lookupObject returns the value of key for introspection, without counting
+// it as an access
+func (app *App) lookupObject(ctx context.Context, key string) (Value, bool) {
+	db := app.selectedDB(ctx)
+	app.expireIfNeeded(ctx, db, key)
+	return db.dict.Get(key)
+}
+
+func (app *App) handleOBJECTENCODING(ctx context.Context, c cmd.OBJECT_ENCODING) (types.RawCmd, error) {
+	value, exists := app.lookupObject(ctx, c.Key)
+	if !exists {
+		return types.NewNullRawCmd(), nil
+	}
+	return types.NewBulkStringRawCmd(EncodingToName(value.encoding)), nil
+}
+
+func (app *App) handleOBJECTIDLETIME(ctx context.Context, c cmd.OBJECT_IDLETIME) (types.RawCmd, error) {
+	value, exists := app.lookupObject(ctx, c.Key)
+	if !exists {
+		return types.NewNullRawCmd(), nil
+	}
+	if app.config.Eviction().UsesLFU() {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "An LFU maxmemory policy is selected, idle time not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	}
+	return types.NewIntegerRawCmd(int64(estimateIdleTime(value).Seconds())), nil
+}
+
+func (app *App) handleOBJECTFREQ(ctx context.Context, c cmd.OBJECT_FREQ) (types.RawCmd, error) {
+	value, exists := app.lookupObject(ctx, c.Key)
+	if !exists {
+		return types.NewNullRawCmd(), nil
+	}
+	eviction := app.config.Eviction()
+	if !eviction.UsesLFU() {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	}
+	return types.NewIntegerRawCmd(int64(lfuDecrAndReturn(value.lru, eviction.LFUDecayTime))), nil
+}
+
+func (app *App) handleOBJECTREFCOUNT(ctx context.Context, c cmd.OBJECT_REFCOUNT) (types.RawCmd, error) {
+	if _, exists := app.lookupObject(ctx, c.Key); !exists {
+		return types.NewNullRawCmd(), nil
+	}
+	// values are never shared between keys
+	return types.NewIntegerRawCmd(1), nil
+}
+
+func (app *App) handleMEMORY(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.MEMORY](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+
+	switch {
+	case c.USAGE != nil:
+		return app.handleMEMORYUSAGE(ctx, *c.USAGE)
+	case c.STATS != nil:
+		return app.handleMEMORYSTATS()
+	case c.DOCTOR != nil:
+		return app.handleMEMORYDOCTOR()
+	case c.PURGE != nil:
+		return app.handleMEMORYPURGE()
+	default: // HELP
+		return subcommandHelp[cmd.MEMORY]("memory")
+	}
+}
+
+func (app *App) handleMEMORYUSAGE(ctx context.Context, c cmd.MEMORY_USAGE) (types.RawCmd, error) {
+	samples := memoryUsageSamples
+	if c.SAMPLES != nil {
+		if *c.SAMPLES < 0 {
+			return types.RawCmd{}, NewSyntaxError()
+		}
+		samples = *c.SAMPLES
+	}
+
+	value, exists := app.lookupObject(ctx, c.Key)
+	if !exists {
+		return types.NewNullRawCmd(), nil
+	}
+	return types.NewIntegerRawCmd(valueMemoryUsage(c.Key, value, samples)), nil
+}
+
+// Go runtime metrics used as the allocator statistics
+const (
+	metricHeapObjects = "/memory/classes/heap/objects:bytes"
+	metricTotalMemory = "/memory/classes/total:bytes"
+)
+
+func readMemoryMetrics() (allocated, total int64) {
+	samples := []metrics.Sample{{Name: metricHeapObjects}, {Name: metricTotalMemory}}
+	metrics.Read(samples)
+	return int64(samples[0].Value.Uint64()), int64(samples[1].Value.Uint64())
+}
+
+// updatePeakMemory is called by the server cron and the memory commands
+func (app *App) updatePeakMemory() int64 {
+	allocated, _ := readMemoryMetrics()
+	app.stats.peakAllocated = max(app.stats.peakAllocated, allocated)
+	app.stats.peakUsedMemory = max(app.stats.peakUsedMemory, app.usedMemory())
+	return allocated
+}
+
+// expiry entries cost a map slot, a string header and a time.Time
+const expiryEntryMemoryOverhead = 48
+
+type memoryStats struct {
+	peakAllocated    int64
+	totalAllocated   int64
+	startupAllocated int64
+	// memory obtained from the OS by the runtime
+	rss           int64
+	clientsNormal int64
+	overheadTotal int64
+	keysCount     int64
+	datasetBytes  int64
+	fragmentation float64
+	// overhead of the hashtables of the non empty databases
+	dbOverheads map[int][2]int64
+}
+
+func (app *App) computeMemoryStats() memoryStats {
+	allocated := app.updatePeakMemory()
+	_, total := readMemoryMetrics()
+
+	stats := memoryStats{
+		peakAllocated:    app.stats.peakAllocated,
+		totalAllocated:   allocated,
+		startupAllocated: app.startupAllocated,
+		rss:              total,
+		dbOverheads:      map[int][2]int64{},
+	}
+	for _, client := range app.clients {
+		stats.clientsNormal += int64(client.reader.Size() + client.outputLength())
+	}
+	stats.overheadTotal = stats.startupAllocated + stats.clientsNormal
+	for _, db := range app.dbs {
+		if db.dict.Len() == 0 {
+			continue
+		}
+		main := int64(db.dict.Buckets() * 8)
+		expires := int64(len(db.expiry) * expiryEntryMemoryOverhead)
+		stats.dbOverheads[db.id] = [2]int64{main, expires}
+		stats.overheadTotal += main + expires
+		stats.keysCount += int64(db.dict.Len())
+		stats.datasetBytes += db.usedMemory
+	}
+	if allocated != 0 {
+		stats.fragmentation = float64(total) / float64(allocated)
+	}
+	return stats
+}
+
+func percentage(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
+
+func (app *App) handleMEMORYSTATS() (types.RawCmd, error) {
+	stats := app.computeMemoryStats()
+
+	bytesPerKey := int64(0)
+	if stats.keysCount != 0 {
+		bytesPerKey = (stats.totalAllocated - stats.startupAllocated) / stats.keysCount
+	}
+	formatFloat := func(f float64) types.RawCmd {
+		return types.NewBulkStringRawCmd(strconv.FormatFloat(f, 'f', 6, 64))
+	}
+	result := map[string]types.RawCmd{
+		"peak.allocated":      types.NewIntegerRawCmd(stats.peakAllocated),
+		"total.allocated":     types.NewIntegerRawCmd(stats.totalAllocated),
+		"startup.allocated":   types.NewIntegerRawCmd(stats.startupAllocated),
+		"replication.backlog": types.NewIntegerRawCmd(0),
+		"clients.slaves":      types.NewIntegerRawCmd(0),
+		"clients.normal":      types.NewIntegerRawCmd(stats.clientsNormal),
+		"aof.buffer":          types.NewIntegerRawCmd(0),
+		"overhead.total":      types.NewIntegerRawCmd(stats.overheadTotal),
+		"keys.count":          types.NewIntegerRawCmd(stats.keysCount),
+		"keys.bytes-per-key":  types.NewIntegerRawCmd(bytesPerKey),
+		"dataset.bytes":       types.NewIntegerRawCmd(stats.datasetBytes),
+		"dataset.percentage":  formatFloat(percentage(stats.datasetBytes, stats.totalAllocated-stats.startupAllocated)),
+		"peak.percentage":     formatFloat(percentage(stats.totalAllocated, stats.peakAllocated)),
+		"fragmentation":       formatFloat(stats.fragmentation),
+	}
+	for id, overheads := range stats.dbOverheads {
+		result[fmt.Sprintf("db.%d", id)] = types.NewMapRawCmd(map[string]types.RawCmd{
+			"overhead.hashtable.main":    types.NewIntegerRawCmd(overheads[0]),
+			"overhead.hashtable.expires": types.NewIntegerRawCmd(overheads[1]),
+		})
+	}
+	return types.NewMapRawCmd(result), nil
+}
+
+// thresholds of the issues reported by MEMORY DOCTOR, same as Redis
+const (
+	doctorMinAllocated           = 5 * 1024 * 1024
+	doctorHighPeakRatio          = 1.5
+	doctorHighFragmentationRatio = 1.4
+	doctorBigClientBuffers       = 200 * 1024
+)
+
+func (app *App) handleMEMORYDOCTOR() (types.RawCmd, error) {
+	stats := app.computeMemoryStats()
+
+	if stats.totalAllocated < doctorMinAllocated {
+		return types.NewBulkStringRawCmd("Hi Sam, this instance is empty or is using very little memory, my issues detector can't be used in these conditions. Please, leave for your mission on Earth and fill it with some data. The new Sam and I will be back to our programming as soon as I finished rebooting."), nil
+	}
+
+	var issues []string
+	if float64(stats.peakAllocated) > float64(stats.totalAllocated)*doctorHighPeakRatio {
+		issues = append(issues, " * Peak memory: In the past this instance used more than 150% the memory that is currently using. The allocator is normally not able to release memory after a peak, so you can expect to see a big fragmentation ratio, however this is actually harmless and is only due to the memory peak, and if the Redis instance Resident Set Size (RSS) is currently bigger than expected, the memory will be used as soon as you fill the Redis instance with more data.")
+	}
+	if stats.fragmentation > doctorHighFragmentationRatio {
+		issues = append(issues, fmt.Sprintf(" * High total RSS: This instance has a memory fragmentation and RSS overhead greater than 1.4 (this means that the Resident Set Size of the Redis process is much larger than the sum of the logical allocations Redis performed). This problem is usually due either to a large peak memory (check if there is a peak memory entry above in the report) or may result from a workload that causes the allocator to fragment memory a lot. The current ratio is %.2f.", stats.fragmentation))
+	}
+	if clients := len(app.clients); clients != 0 && stats.clientsNormal/int64(clients) > doctorBigClientBuffers {
+		issues = append(issues, " * Big client buffers: The clients output buffers are in general too big, on average. This means that some client is reading too slowly, or the server has big pipelines of replies in flight.")
+	}
+
+	if len(issues) == 0 {
+		return types.NewBulkStringRawCmd("Hi Sam, I can't find any memory issue in your instance. I can only account for what occurs on this base."), nil
+	}
+	report := "Sam, I detected a few issues in this Redis instance memory implants:\n\n" +
+		strings.Join(issues, "\n\n") +
+		"\n\nI'm here to keep you safe, Sam. I want to help you.\n"
+	return types.NewBulkStringRawCmd(report), nil
+}
+
+func (app *App) handleMEMORYPURGE() (types.RawCmd, error) {
+	debug.FreeOSMemory()
+	return types.NewStringRawCmd("OK"), nil
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_ObjectEncoding(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	client.do("CONFIG", "SET", "list-max-listpack-size", "4")
+
+	client.do("SET", "int", "12345")
+	client.do("SET", "leading-zero", "012")
+	client.do("SET", "embstr", "hello")
+	client.do("SET", "raw", strings.Repeat("x", 100))
+	expectEqual(t, "int", client.do("OBJECT", "ENCODING", "int").BulkString)
+	expectEqual(t, "embstr", client.do("OBJECT", "ENCODING", "leading-zero").BulkString)
+	expectEqual(t, "embstr", client.do("OBJECT", "ENCODING", "embstr").BulkString)
+	expectEqual(t, "raw", client.do("OBJECT", "ENCODING", "raw").BulkString)
+	expectEqual(t, types.SymNull, client.do("OBJECT", "ENCODING", "missing").Sym)
+
+	for i := range 4 {
+		client.do("RPUSH", "list", strconv.Itoa(i))
+	}
+	expectEqual(t, "listpack", client.do("OBJECT", "ENCODING", "list").BulkString)
+	client.do("RPUSH", "list", "4")
+	expectEqual(t, "quicklist", client.do("OBJECT", "ENCODING", "list").BulkString)
+	// only converted back once it is half the limit
+	client.do("LPOP", "list", "2")
+	expectEqual(t, "quicklist", client.do("OBJECT", "ENCODING", "list").BulkString)
+	client.do("LPOP", "list")
+	expectEqual(t, "listpack", client.do("OBJECT", "ENCODING", "list").BulkString)
+}
+
+func Test_CompactEncodings(t *testing.T) {
+	app := NewApp(config.New())
+	client := newTestClient(t, startTestApp(t, app))
+	client.do("CONFIG", "SET", "list-max-listpack-size", "4")
+
+	client.do("SET", "int", "-42")
+	client.do("RPUSH", "list", "a", "", strings.Repeat("b", 200))
+	client.do("RPUSH", "long", "1", "2", "3", "4", "5")
+
+	app.mutex.Lock()
+	stored := func(key string) Value {
+		value, _ := app.dbs[0].dict.Get(key)
+		return value
+	}
+	integer, list, long := stored("int"), stored("list"), stored("long")
+	app.mutex.Unlock()
+
+	expectEqual(t, "", integer.String)
+	expectEqual(t, int64(-42), integer.integer)
+	expectEqual(t, 0, len(list.List))
+	// uvarint lengths of 1, 1 and 2 bytes before the elements
+	expectEqual(t, 1+1+1+2+200, len(list.listpack.data))
+	expectEqual(t, 3, list.listpack.length)
+	expectEqual(t, 5, len(long.List))
+	expectEqual(t, 0, len(long.listpack.data))
+
+	// the commands see the decoded values
+	expectEqual(t, "-42", client.do("GET", "int").BulkString)
+	expectEqual(t, int64(4), client.do("APPEND", "int", "x").Integer)
+	expectEqual(t, "embstr", client.do("OBJECT", "ENCODING", "int").BulkString)
+	expectEqual(t, "a  "+strings.Repeat("b", 200), joinBulkStrings(client.do("LRANGE", "list", "0", "-1")))
+	expectEqual(t, "a", client.do("LPOP", "list").BulkString)
+	expectEqual(t, int64(2), client.do("LLEN", "list").Integer)
+	expectEqual(t, "5", client.do("RPOP", "long").BulkString)
+	expectEqual(t, "4 3", joinBulkStrings(client.do("RPOP", "long", "2")))
+	expectEqual(t, "listpack", client.do("OBJECT", "ENCODING", "long").BulkString)
+	expectEqual(t, "2 1", joinBulkStrings(client.do("RPOP", "long", "5")))
+}
+
+func Test_listpack(t *testing.T) {
+	for _, list := range [][]string{
+		nil,
+		{""},
+		{"a", "", "bc"},
+		{strings.Repeat("x", 128), strings.Repeat("y", 20000)},
+	} {
+		lp := newListpack(list)
+		expectEqual(t, len(lp.data), cap(lp.data))
+		expectEqual(t, strings.Join(list, ","), strings.Join(lp.elements(), ","))
+		expectEqual(t, len(list), lp.length)
+	}
+
+	lp := newListpack([]string{"c"})
+	lp.push([]string{"a", "b"}, true)
+	lp.push([]string{"d", strings.Repeat("e", 200)}, false)
+	expectEqual(t, "a,b,c,d,"+strings.Repeat("e", 200), strings.Join(lp.elements(), ","))
+	expectEqual(t, "b,c", strings.Join(lp.rangeOf(1, 3), ","))
+	expectEqual(t, "a,b", strings.Join(lp.pop(2, true), ","))
+	expectEqual(t, strings.Repeat("e", 200)+",d", strings.Join(lp.pop(2, false), ","))
+	expectEqual(t, 1, lp.length)
+	expectEqual(t, "c", strings.Join(lp.elements(), ","))
+}
+
+func Test_ObjectAndMemoryIntrospection(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+	client.do("SET", "foo", "bar")
+
+	expectEqual(t, int64(0), client.do("OBJECT", "IDLETIME", "foo").Integer)
+	expectEqual(t, int64(1), client.do("OBJECT", "REFCOUNT", "foo").Integer)
+	if reply := client.do("OBJECT", "FREQ", "foo"); !strings.HasPrefix(reply.Error, "ERR An LFU maxmemory policy is not selected") {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	client.do("CONFIG", "SET", "maxmemory-policy", "allkeys-lfu")
+	client.do("SET", "bar", "foo")
+	expectEqual(t, int64(lfuInitVal), client.do("OBJECT", "FREQ", "bar").Integer)
+	if reply := client.do("OBJECT", "IDLETIME", "bar"); !strings.HasPrefix(reply.Error, "ERR An LFU maxmemory policy is selected") {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+
+	if usage := client.do("MEMORY", "USAGE", "foo").Integer; usage <= 0 {
+		t.Errorf("expect a positive memory usage, got %d", usage)
+	}
+	expectEqual(t, types.SymNull, client.do("MEMORY", "USAGE", "missing").Sym)
+	expectEqual(t, types.SymError, client.do("MEMORY", "USAGE", "foo", "SAMPLES", "-1").Sym)
+
+	for _, subcommand := range []string{"STATS", "DOCTOR", "PURGE"} {
+		if reply := client.do("MEMORY", subcommand); reply.Sym == types.SymError {
+			t.Errorf("unexpected error for MEMORY %s: %+v", subcommand, reply)
+		}
+	}
+	expectEqual(t, "ERR unknown subcommand 'foo'. Try OBJECT HELP.", client.do("OBJECT", "foo", "bar").Error)
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+// Replies are queued in the output of the client and sent by its writer
+// goroutine, so a client that does not read cannot block the commands pushing
+// messages to it. Its output is bounded by client-output-buffer-limit instead.
+const (
+	// replies of pipelined commands are sent together unless they exceed
+	// writeBufferSize
+	writeBufferSize = 64 * 1024
+	// a client that does not read anything for clientWriteTimeout while
+	// replies are pending is disconnected
+	clientWriteTimeout = 60 * time.Second
+	// the write deadline is extended after each chunk
+	writeChunkSize = 16 * 1024
+)
+
+var errClientClosed = errors.New("client closed")
+
+type clientOutput struct {
+	mutex sync.Mutex
+	// waiting for the writer goroutine
+	pending []byte
+	// taken by the writer goroutine but not written yet
+	inFlight int
+	// when the output went over the soft limit, zero while it is under
+	softLimitSince time.Time
+
+	// ready wakes up the writer goroutine, closing stops it once the output
+	// is sent and it closes done when it returns
+	ready   chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+}
+
+func newClientOutput() clientOutput {
+	return clientOutput{
+		ready:   make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// writeReply queues a reply, it is sent on the next flush
+func (c *Client) writeReply(cmd types.RawCmd) error {
+	c.output.mutex.Lock()
+	defer c.output.mutex.Unlock()
+	if err := c.queue(cmd); err != nil {
+		return err
+	}
+	if len(c.output.pending) >= writeBufferSize {
+		c.signalWriter()
+	}
+	return nil
+}
+
+// writePush sends an out of band message right away
+func (c *Client) writePush(cmd types.RawCmd) error {
+	c.output.mutex.Lock()
+	defer c.output.mutex.Unlock()
+	if err := c.queue(cmd); err != nil {
+		return err
+	}
+	c.signalWriter()
+	return nil
+}
+
+// flush sends the queued replies
+func (c *Client) flush() {
+	c.signalWriter()
+}
+
+func (c *Client) signalWriter() {
+	select {
+	case c.output.ready <- struct{}{}:
+	default:
+	}
+}
+
+// queue must be called with the output mutex held
+func (c *Client) queue(cmd types.RawCmd) error {
+	if c.killed.Load() {
+		return errClientClosed
+	}
+	data, err := encoding.MarshalCommandProtocol(cmd, c.protocol)
+	if err != nil {
+		log.Println("Failed to marshal response:", err)
+		data, _ = encoding.MarshalCommandProtocol(types.NewErrorRawCmd(ErrorReply(err)), c.protocol)
+	}
+	c.output.pending = append(c.output.pending, data...)
+
+	if c.outputLimitReached(c.config.ClientOutputBufferLimit(clientType(c)), time.Now()) {
+		log.Printf("Client id=%d addr=%s scheduled to be closed ASAP for overcoming of output buffer limits.", c.id, c.addr)
+		c.output.pending = nil
+		c.close()
+		return errClientClosed
+	}
+	return nil
+}
+
+// outputLength is the size of the replies not sent yet
+func (c *Client) outputLength() int {
+	c.output.mutex.Lock()
+	defer c.output.mutex.Unlock()
+	return len(c.output.pending) + c.output.inFlight
+}
+
+// outputLimitReached must be called with the output mutex held
+func (c *Client) outputLimitReached(limit config.ClientOutputBufferLimit, now time.Time) bool {
+	size := int64(len(c.output.pending) + c.output.inFlight)
+	if limit.Hard > 0 && size >= limit.Hard {
+		return true
+	}
+	if limit.Soft > 0 && size >= limit.Soft {
+		if c.output.softLimitSince.IsZero() {
+			c.output.softLimitSince = now
+		}
+		return now.Sub(c.output.softLimitSince) > time.Duration(limit.SoftSeconds)*time.Second
+	}
+	c.output.softLimitSince = time.Time{}
+	return false
+}
+
+// writeLoop is the writer goroutine of the client, it returns once closeOutput
+// is called and the output is sent, or when writing fails
+func (c *Client) writeLoop() {
+	defer close(c.output.done)
+	for {
+		stopping := false
+		select {
+		case <-c.output.ready:
+		case <-c.output.closing:
+			stopping = true
+		}
+		if err := c.writePending(); err != nil {
+			if !c.killed.Load() {
+				log.Println("Failed to response", err)
+			}
+			c.close()
+			return
+		}
+		if stopping {
+			return
+		}
+	}
+}
+
+func (c *Client) writePending() error {
+	for {
+		c.output.mutex.Lock()
+		data := c.output.pending
+		c.output.pending = nil
+		c.output.inFlight = len(data)
+		c.output.mutex.Unlock()
+
+		if len(data) == 0 {
+			return nil
+		}
+		for len(data) != 0 {
+			_ = c.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+			n, err := c.writer.Write(data[:min(len(data), writeChunkSize)])
+			data = data[n:]
+
+			c.output.mutex.Lock()
+			c.output.inFlight = len(data)
+			c.output.mutex.Unlock()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// closeOutput sends the queued replies and stops the writer goroutine
+func (c *Client) closeOutput() {
+	close(c.output.closing)
+	<-c.output.done
+}
+
+// close closes the connection from the server side, the connection goroutine
+// stops on its next read
+func (c *Client) close() {
+	c.killed.Store(true)
+	_ = c.conn.Close()
+}
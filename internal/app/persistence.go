@@ -0,0 +1,89 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/rdb"
+)
+
+// save writes a snapshot of the keyspace to path and records the outcome for
+// INFO persistence
+func (app *App) save(path string) error {
+	err := app.writeRDBFile(path)
+	app.lastSaveSucceed = err == nil
+	if err == nil {
+		app.dirty = 0
+		app.lastSave = time.Now()
+		app.stats.rdbSaves += 1
+	}
+	return err
+}
+
+// writeRDBFile writes a snapshot to path, the previous file is only replaced
+// once the new one is complete
+func (app *App) writeRDBFile(path string) error {
+	tempPath := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-%d.rdb", os.Getpid()))
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(tempPath)
+	}()
+
+	bufWriter := bufio.NewWriter(file)
+	if err := app.writeSnapshot(rdb.NewWriter(bufWriter)); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return fmt.Errorf("flush snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+func (app *App) writeSnapshot(w *rdb.Writer) error {
+	err := w.WriteHeader(map[string]string{
+		"redis-ver":  serverVersion,
+		"redis-bits": "64",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, db := range app.dbs {
+		if db.dict.Len() == 0 {
+			continue
+		}
+		if err := w.WriteSelectDB(db.id, db.dict.Len(), len(db.expiry)); err != nil {
+			return err
+		}
+		for key, value := range db.dict.All() {
+			value = value.decoded()
+			expireAt := db.expiry[key]
+			switch value.ValueType {
+			case ValueTypeString:
+				err = w.WriteString(key, value.String, expireAt)
+			case ValueTypeList:
+				err = w.WriteList(key, value.listElements(), expireAt)
+			default:
+				err = fmt.Errorf("cannot save value of type %s", ValueTypeToName(value.ValueType))
+			}
+			if err != nil {
+				return fmt.Errorf("write key `%s`: %w", key, err)
+			}
+		}
+	}
+
+	return w.WriteEnd()
+}
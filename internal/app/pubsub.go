@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"slices"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+func (app *App) subscribe(client *Client, channel string) {
+	client.subscriptions[channel] = struct{}{}
+
+	subscribers, exists := app.pubsubChannels[channel]
+	if !exists {
+		subscribers = map[int64]*Client{}
+		app.pubsubChannels[channel] = subscribers
+	}
+	subscribers[client.id] = client
+}
+
+func (app *App) unsubscribe(client *Client, channel string) {
+	delete(client.subscriptions, channel)
+
+	subscribers := app.pubsubChannels[channel]
+	delete(subscribers, client.id)
+	if len(subscribers) == 0 {
+		delete(app.pubsubChannels, channel)
+	}
+}
+
+func (app *App) unsubscribeAll(client *Client) {
+	for channel := range client.subscriptions {
+		app.unsubscribe(client, channel)
+	}
+}
+
+// publish sends message to every subscriber of channel and returns how many
+// clients received it
+func (app *App) publish(channel, message string) int {
+	subscribers := app.pubsubChannels[channel]
+	push := types.NewPushRawCmd(
+		types.NewBulkStringRawCmd("message"),
+		types.NewBulkStringRawCmd(channel),
+		types.NewBulkStringRawCmd(message),
+	)
+	for _, client := range subscribers {
+		// a failed write is detected and cleaned up by the subscriber connection
+		_ = client.writePush(push)
+	}
+	return len(subscribers)
+}
+
+func newSubscriptionPush(kind string, channel *string, count int) types.RawCmd {
+	channelCmd := types.NewNullRawCmd()
+	if channel != nil {
+		channelCmd = types.NewBulkStringRawCmd(*channel)
+	}
+	return types.NewPushRawCmd(
+		types.NewBulkStringRawCmd(kind),
+		channelCmd,
+		types.NewIntegerRawCmd(int64(count)),
+	)
+}
+
+func (app *App) handleSUBSCRIBE(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.SUBSCRIBE](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	client := GetClientFromContext(ctx)
+
+	// every channel gets its own confirmation, the last one is the reply
+	channels := append([]string{c.Channel}, c.ChannelRest...)
+	for _, channel := range channels[:len(channels)-1] {
+		app.subscribe(client, channel)
+		if err := client.writeReply(newSubscriptionPush("subscribe", &channel, len(client.subscriptions))); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+	last := channels[len(channels)-1]
+	app.subscribe(client, last)
+	return newSubscriptionPush("subscribe", &last, len(client.subscriptions)), nil
+}
+
+func (app *App) handleUNSUBSCRIBE(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.UNSUBSCRIBE](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	client := GetClientFromContext(ctx)
+
+	channels := c.Channels
+	if len(channels) == 0 {
+		for channel := range client.subscriptions {
+			channels = append(channels, channel)
+		}
+		slices.Sort(channels)
+	}
+	if len(channels) == 0 {
+		return newSubscriptionPush("unsubscribe", nil, 0), nil
+	}
+
+	for _, channel := range channels[:len(channels)-1] {
+		app.unsubscribe(client, channel)
+		if err := client.writeReply(newSubscriptionPush("unsubscribe", &channel, len(client.subscriptions))); err != nil {
+			return types.RawCmd{}, err
+		}
+	}
+	last := channels[len(channels)-1]
+	app.unsubscribe(client, last)
+	return newSubscriptionPush("unsubscribe", &last, len(client.subscriptions)), nil
+}
+
+func (app *App) handlePUBLISH(args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.PUBLISH](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	return types.NewIntegerRawCmd(int64(app.publish(c.Channel, c.Message))), nil
+}
+
+// commands a RESP2 client is still allowed to send after subscribing
+var subscribedContextCommands = []string{"SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE", "PING", "QUIT", "RESET"}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/internal/dict"
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+const defaultScanCount = 10
+
+func parseScanCursor(raw string) (uint64, error) {
+	cursor, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, NewCodedError(ErrorCodeERR, "invalid cursor")
+	}
+	return cursor, nil
+}
+
+func parseScanCount(count *int) (int, error) {
+	if count == nil {
+		return defaultScanCount, nil
+	}
+	if *count < 1 {
+		return 0, NewSyntaxError()
+	}
+	return *count, nil
+}
+
+// scanDict walks d from cursor until about count entries were passed to fn.
+// It visits at most 10 times count buckets so a sparse table does not block
+// the server, the reply is then shorter than count.
+func scanDict[V any](d *dict.Dict[V], cursor uint64, count int, fn func(key string, value V)) uint64 {
+	collected := 0
+	// a huge count must not overflow into no iteration at all
+	for maxIterations := min(count, math.MaxInt/10) * 10; maxIterations > 0; maxIterations-- {
+		cursor = d.Scan(cursor, func(key string, value V) {
+			fn(key, value)
+			collected += 1
+		})
+		if cursor == 0 || collected >= count {
+			break
+		}
+	}
+	return cursor
+}
+
+func newScanReply(cursor uint64, elements []string) types.RawCmd {
+	return types.NewArrayRawCmd(
+		types.NewBulkStringRawCmd(strconv.FormatUint(cursor, 10)),
+		types.NewBulkArrayBulkString(elements),
+	)
+}
+
+func (app *App) handleSCAN(ctx context.Context, args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.SCAN](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	db := app.selectedDB(ctx)
+	cursor, err := parseScanCursor(c.Cursor)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	count, err := parseScanCount(c.COUNT)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	var valueType ValueType
+	if c.TYPE != nil {
+		var known bool
+		if valueType, known = ValueTypeFromName(*c.TYPE); !known {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, fmt.Sprintf("unknown type name '%s'", *c.TYPE))
+		}
+	}
+
+	type scanned struct {
+		key       string
+		valueType ValueType
+	}
+	var candidates []scanned
+	cursor = scanDict(db.dict, cursor, count, func(key string, value Value) {
+		candidates = append(candidates, scanned{key, value.ValueType})
+	})
+
+	// filters are applied after the walk since expiring a key modifies the dict
+	keys := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if c.MATCH != nil && !glob.Match(*c.MATCH, candidate.key) {
+			continue
+		}
+		if app.expireIfNeeded(ctx, db, candidate.key) {
+			continue
+		}
+		if c.TYPE != nil && candidate.valueType != valueType {
+			continue
+		}
+		keys = append(keys, candidate.key)
+	}
+	return newScanReply(cursor, keys), nil
+}
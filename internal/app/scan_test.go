@@ -0,0 +1,53 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_SCAN(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	for i := range 100 {
+		client.do("SET", "user:"+strconv.Itoa(i), "v")
+	}
+	client.do("RPUSH", "user:list", "v")
+	client.do("SET", "other", "v")
+
+	seen := map[string]bool{}
+	cursor := "0"
+	for {
+		reply := client.do("SCAN", cursor, "MATCH", "user:*", "COUNT", "7", "TYPE", "STRING")
+		if len(reply.Array) != 2 {
+			t.Fatalf("unexpected SCAN reply %+v", reply)
+		}
+		for _, key := range reply.Array[1].Array {
+			seen[key.BulkString] = true
+		}
+		cursor = reply.Array[0].BulkString
+		if cursor == "0" {
+			break
+		}
+	}
+	expectEqual(t, 100, len(seen))
+	expectEqual(t, false, seen["other"])
+	expectEqual(t, false, seen["user:list"])
+
+	for _, args := range [][]string{
+		{"SCAN", "-1"},
+		{"SCAN", "0", "COUNT", "0"},
+		{"SCAN", "0", "TYPE", "nope"},
+	} {
+		if reply := client.do(args...); reply.Sym != types.SymError {
+			t.Errorf("expect error for %v, got %+v", args, reply)
+		}
+	}
+
+	// the whole keyspace fits in one call with a huge count
+	reply := client.do("SCAN", "0", "COUNT", "9223372036854775807")
+	expectEqual(t, "0", reply.Array[0].BulkString)
+	expectEqual(t, 102, len(reply.Array[1].Array))
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// how long the connections get to send their last replies on shutdown
+const shutdownGracePeriod = 10 * time.Second
+
+// errShuttingDown is returned for commands received once the shutdown has
+// started, their connection is closed without a reply
+var errShuttingDown = errors.New("server is shutting down")
+
+type ShutdownOptions struct {
+	// Save forces a snapshot, NoSave skips it
+	Save   bool
+	NoSave bool
+	// Now skips waiting for the replies in flight to be sent
+	Now bool
+	// Force exits even if the snapshot cannot be written
+	Force bool
+}
+
+// Serve accepts connections from l until it is closed or the app shuts down
+func (app *App) Serve(l net.Listener) error {
+	app.mutex.Lock()
+	if app.shuttingDown.Load() {
+		app.mutex.Unlock()
+		return l.Close()
+	}
+	app.listeners = append(app.listeners, l)
+	app.mutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			// e.g. too many open files, keep serving the clients already connected
+			log.Println("Failed to accept new connection", err)
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		app.mutex.Lock()
+		if app.shuttingDown.Load() {
+			app.mutex.Unlock()
+			_ = conn.Close()
+			return nil
+		}
+		// added under the lock so it cannot race with the wait of the shutdown
+		app.connections.Add(1)
+		app.mutex.Unlock()
+
+		go func() {
+			defer app.connections.Done()
+			app.HandleConnection(conn)
+		}()
+	}
+}
+
+// Done is closed once the shutdown is complete and the process can exit
+func (app *App) Done() <-chan struct{} {
+	return app.done
+}
+
+// ExitCode is the status the process should exit with after Done is closed
+func (app *App) ExitCode() int {
+	return app.exitCode
+}
+
+// Shutdown stops the app, it returns an error and keeps running when the
+// snapshot cannot be written unless options.Force is set
+func (app *App) Shutdown(options ShutdownOptions) error {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	return app.shutdown(options)
+}
+
+func (app *App) shutdown(options ShutdownOptions) error {
+	if app.shuttingDown.Load() {
+		return nil
+	}
+
+	exitCode := 0
+	// snapshots are enabled by save points, SAVE and NOSAVE override them
+	save := options.Save || app.config.String("save") != ""
+	if save && !options.NoSave {
+		log.Println("Saving the final RDB snapshot before exiting")
+		if err := app.save(app.rdbPath()); err != nil {
+			log.Println("Error trying to save the DB:", err)
+			if !options.Force {
+				return err
+			}
+			exitCode = 1
+		}
+	}
+
+	log.Println("Shutting down")
+	app.shuttingDown.Store(true)
+	app.exitCode = exitCode
+
+	for _, l := range app.listeners {
+		_ = l.Close()
+	}
+	// wake up the blocked clients so they reply an error
+	close(app.shutdownCh)
+	// interrupt idle connections waiting for their next command, the ones
+	// running a command still send its reply
+	for _, client := range app.clients {
+		_ = client.conn.SetReadDeadline(time.Now())
+	}
+
+	go func() {
+		if !options.Now {
+			app.waitConnections(shutdownGracePeriod)
+		}
+		log.Println("Server is now ready to exit, bye bye...")
+		close(app.done)
+	}()
+
+	return nil
+}
+
+func (app *App) waitConnections(timeout time.Duration) {
+	finished := make(chan struct{})
+	go func() {
+		app.connections.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+		log.Println("Timed out waiting for connections to finish")
+	}
+}
+
+func (app *App) handleSHUTDOWN(args []string) (types.RawCmd, error) {
+	c, err := argsparser.Parse[cmd.SHUTDOWN](args)
+	if err != nil {
+		return types.RawCmd{}, err
+	}
+	if c.SaveMode.NOSAVE && c.SaveMode.SAVE {
+		return types.RawCmd{}, NewSyntaxError()
+	}
+	if c.ABORT {
+		if c.SaveMode.Key != "" || c.NOW || c.FORCE {
+			return types.RawCmd{}, NewSyntaxError()
+		}
+		// the shutdown never waits in the background, there is nothing to abort
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "No shutdown in progress.")
+	}
+
+	err = app.shutdown(ShutdownOptions{
+		Save:   c.SaveMode.SAVE,
+		NoSave: c.SaveMode.NOSAVE,
+		Now:    c.NOW,
+		Force:  c.FORCE,
+	})
+	if err != nil {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "Errors trying to SHUTDOWN. Check logs.")
+	}
+	// like Redis, the client only sees its connection being closed
+	return types.RawCmd{}, errShuttingDown
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func Test_SHUTDOWNUnblocksWaiters(t *testing.T) {
+	app := NewApp(config.New())
+	addr := startTestApp(t, app)
+	blocked := newTestClient(t, addr)
+	admin := newTestClient(t, addr)
+
+	if _, err := blocked.conn.Write(mustMarshal(t, "BLPOP", "list", "0")); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	// make sure BLPOP is waiting before shutting down
+	waitForBlockedClients(t, admin, 1)
+
+	if _, err := admin.conn.Write(mustMarshal(t, "SHUTDOWN", "NOSAVE")); err != nil {
+		t.Fatal("write failed:", err)
+	}
+	if _, err := encoding.UnmarshalCommand(admin.reader); !errors.Is(err, io.EOF) {
+		t.Errorf("expect connection closed without reply, got %v", err)
+	}
+
+	reply := blocked.read()
+	if reply.Sym != types.SymError || !strings.HasPrefix(reply.Error, ErrorCodeUnblocked) {
+		t.Errorf("expect blocked client to be unblocked with an error, got %+v", reply)
+	}
+
+	select {
+	case <-app.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expect shutdown to complete")
+	}
+	expectEqual(t, 0, app.ExitCode())
+}
+
+func expectEqual[T comparable](t *testing.T, expected, actual T) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("expect equal\nexpected=%+v\n  actual=%+v", expected, actual)
+	}
+}
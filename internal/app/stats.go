@@ -0,0 +1,117 @@
+package app
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// stats are the counters reported by INFO, CONFIG RESETSTAT sets them back to 0
+type stats struct {
+	totalConnectionsReceived int64
+	totalCommandsProcessed   int64
+	totalErrorReplies        int64
+	keyspaceHits             int64
+	keyspaceMisses           int64
+
+	expiredKeys                int64
+	expiredTimeCapReachedCount int64
+	expiredStalePerc           float64
+	expireCycleTime            time.Duration
+
+	evictedKeys int64
+
+	peakAllocated  int64
+	peakUsedMemory int64
+
+	rdbSaves int64
+
+	aclAccessDeniedAuth    int64
+	aclAccessDeniedCmd     int64
+	aclAccessDeniedKey     int64
+	aclAccessDeniedChannel int64
+
+	instantaneousOps         instantaneousMetric
+	instantaneousInputBytes  instantaneousMetric
+	instantaneousOutputBytes instantaneousMetric
+}
+
+// netStats are the bytes exchanged with the clients, they are updated by the
+// connection goroutines without holding App.mutex
+type netStats struct {
+	inputBytes  atomic.Int64
+	outputBytes atomic.Int64
+}
+
+func (app *App) resetStats() {
+	app.stats = stats{}
+	app.net.inputBytes.Store(0)
+	app.net.outputBytes.Store(0)
+}
+
+// Like Redis the instantaneous metrics are the average rate over the last
+// instantaneousMetricSamples samples, taken every instantaneousMetricPeriod.
+const (
+	instantaneousMetricSamples = 16
+	instantaneousMetricPeriod  = 100 * time.Millisecond
+)
+
+type instantaneousMetric struct {
+	lastSampleTime  time.Time
+	lastSampleCount int64
+	samples         [instantaneousMetricSamples]float64
+	index           int
+}
+
+// track records a sample of a counter, it is called by the server cron
+func (m *instantaneousMetric) track(count int64, now time.Time) {
+	elapsed := now.Sub(m.lastSampleTime)
+	if elapsed < instantaneousMetricPeriod {
+		return
+	}
+	if !m.lastSampleTime.IsZero() {
+		m.samples[m.index] = float64(count-m.lastSampleCount) / elapsed.Seconds()
+		m.index = (m.index + 1) % instantaneousMetricSamples
+	}
+	m.lastSampleTime = now
+	m.lastSampleCount = count
+}
+
+// perSecond returns the average rate of the counter
+func (m *instantaneousMetric) perSecond() float64 {
+	var sum float64
+	for _, sample := range m.samples {
+		sum += sample
+	}
+	return sum / instantaneousMetricSamples
+}
+
+func (app *App) trackInstantaneousMetrics(now time.Time) {
+	app.stats.instantaneousOps.track(app.stats.totalCommandsProcessed, now)
+	app.stats.instantaneousInputBytes.track(app.net.inputBytes.Load(), now)
+	app.stats.instantaneousOutputBytes.track(app.net.outputBytes.Load(), now)
+}
+
+// countingReader adds the bytes read from r to n
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// countingWriter adds the bytes written to w to n
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
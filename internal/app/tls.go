@@ -0,0 +1,53 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+)
+
+// NewTLSConfig builds the configuration of the TLS listeners from the tls-*
+// parameters. Clients must present a certificate signed by tls-ca-cert-file
+// unless tls-auth-clients is no, or optional when they may go without one.
+func NewTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	certFile, keyFile := cfg.String("tls-cert-file"), cfg.String("tls-key-file")
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls-cert-file and tls-key-file are required for TLS")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	authClients := cfg.String("tls-auth-clients")
+	if authClients == "no" {
+		tlsConfig.ClientAuth = tls.NoClientCert
+		return tlsConfig, nil
+	}
+
+	caFile := cfg.String("tls-ca-cert-file")
+	if caFile == "" {
+		return nil, errors.New("tls-ca-cert-file is required to authenticate the clients, set tls-auth-clients to no otherwise")
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificates: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificate found in %s", caFile)
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if authClients == "optional" {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
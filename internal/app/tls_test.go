@@ -0,0 +1,176 @@
+package app
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCert creates a certificate signed by parent, self-signed when parent
+// is nil
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("generate key failed:", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal("create certificate failed:", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) keyPEM(t *testing.T) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal("marshal key failed:", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	cert, err := tls.X509KeyPair(c.pem, c.keyPEM(t))
+	if err != nil {
+		t.Fatal("load key pair failed:", err)
+	}
+	return cert
+}
+
+// startTLSTestApp serves an app on a TLS listener configured with the tls-*
+// parameters and returns its address and the CA of the certificates
+func startTLSTestApp(t *testing.T, authClients string) (string, *testCert) {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newTestCert(t, "test ca", nil)
+	server := newTestCert(t, "server", ca)
+	files := map[string][]byte{"ca.crt": ca.pem, "server.crt": server.pem, "server.key": server.keyPEM(t)}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatal("write file failed:", err)
+		}
+	}
+
+	cfg, err := config.Load([]string{
+		"--tls-cert-file", filepath.Join(dir, "server.crt"),
+		"--tls-key-file", filepath.Join(dir, "server.key"),
+		"--tls-ca-cert-file", filepath.Join(dir, "ca.crt"),
+		"--tls-auth-clients", authClients,
+	})
+	if err != nil {
+		t.Fatal("load config failed:", err)
+	}
+	tlsConfig, err := NewTLSConfig(cfg)
+	if err != nil {
+		t.Fatal("tls config failed:", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listen failed:", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		_ = NewApp(cfg).Serve(tls.NewListener(l, tlsConfig))
+	}()
+	return l.Addr().String(), ca
+}
+
+func newTLSTestClient(t *testing.T, addr string, ca *testCert, cert *testCert) (*testClient, error) {
+	t.Helper()
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(ca.cert)
+	if cert != nil {
+		tlsConfig.Certificates = []tls.Certificate{cert.tlsCertificate(t)}
+	}
+
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	// with TLS 1.3 a rejected client certificate is only reported on read
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Write(mustMarshal(t, "PING")); err != nil {
+		return nil, err
+	}
+	client := &testClient{tb: t, conn: conn, reader: bufio.NewReader(conn)}
+	if _, err := client.reader.Peek(1); err != nil {
+		return nil, err
+	}
+	client.read()
+	return client, nil
+}
+
+func Test_TLSMutualAuth(t *testing.T) {
+	addr, ca := startTLSTestApp(t, "yes")
+
+	client, err := newTLSTestClient(t, addr, ca, newTestCert(t, "client", ca))
+	if err != nil {
+		t.Fatal("connect with a client certificate failed:", err)
+	}
+	client.do("SET", "foo", "bar")
+	expectEqual(t, "bar", client.do("GET", "foo").BulkString)
+
+	if _, err := newTLSTestClient(t, addr, ca, nil); err == nil {
+		t.Error("expect a client without certificate to be rejected")
+	}
+	if _, err := newTLSTestClient(t, addr, ca, newTestCert(t, "stranger", newTestCert(t, "other ca", nil))); err == nil {
+		t.Error("expect a client certificate of another CA to be rejected")
+	}
+}
+
+func Test_TLSOptionalAuth(t *testing.T) {
+	addr, ca := startTLSTestApp(t, "optional")
+
+	if _, err := newTLSTestClient(t, addr, ca, nil); err != nil {
+		t.Error("connect without a client certificate failed:", err)
+	}
+	if _, err := newTLSTestClient(t, addr, ca, newTestCert(t, "client", ca)); err != nil {
+		t.Error("connect with a client certificate failed:", err)
+	}
+}
+
+func Test_NewTLSConfig(t *testing.T) {
+	if _, err := NewTLSConfig(config.New()); err == nil {
+		t.Error("expect the certificate to be required")
+	}
+}
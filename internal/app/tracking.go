@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/encoding"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types/cmd"
+)
+
+// RESP2 clients receive invalidations as messages of this channel on the
+// connection they redirect to
+const trackingChannel = "__redis__:invalidate"
+
+type clientTracking struct {
+	enabled bool
+	bcast   bool
+	optIn   bool
+	optOut  bool
+	noLoop  bool
+	// id of the client receiving the invalidations, 0 means the client itself
+	redirect int64
+	prefixes []string
+
+	// set by CLIENT CACHING, only applies to the next command
+	caching bool
+}
+
+// shouldTrackReads tells whether keys read by the current command must be
+// remembered for the client (default mode, or opted in / not opted out)
+func (t clientTracking) shouldTrackReads() bool {
+	switch {
+	case !t.enabled || t.bcast:
+		return false
+	case t.optIn:
+		return t.caching
+	case t.optOut:
+		return !t.caching
+	default:
+		return true
+	}
+}
+
+func (app *App) enableTracking(client *Client, tracking clientTracking) {
+	app.disableTracking(client)
+
+	tracking.enabled = true
+	client.tracking = tracking
+	if !tracking.bcast {
+		return
+	}
+
+	prefixes := tracking.prefixes
+	if len(prefixes) == 0 {
+		// no prefix means every key
+		prefixes = []string{""}
+	}
+	for _, prefix := range prefixes {
+		ids, exists := app.trackingPrefixes[prefix]
+		if !exists {
+			ids = map[int64]struct{}{}
+			app.trackingPrefixes[prefix] = ids
+		}
+		ids[client.id] = struct{}{}
+	}
+}
+
+func (app *App) disableTracking(client *Client) {
+	if !client.tracking.enabled {
+		return
+	}
+	for prefix, ids := range app.trackingPrefixes {
+		delete(ids, client.id)
+		if len(ids) == 0 {
+			delete(app.trackingPrefixes, prefix)
+		}
+	}
+	// entries in trackingKeys are dropped lazily when the keys are invalidated
+	client.tracking = clientTracking{}
+}
+
+// trackingRememberKey records that the client of ctx read key so it gets
+// invalidated when key changes
+func (app *App) trackingRememberKey(ctx context.Context, key string) {
+	client := GetClientFromContext(ctx)
+	if client == nil || !client.tracking.shouldTrackReads() {
+		return
+	}
+	ids, exists := app.trackingKeys[key]
+	if !exists {
+		ids = map[int64]struct{}{}
+		app.trackingKeys[key] = ids
+	}
+	ids[client.id] = struct{}{}
+}
+
+// trackingInvalidateKey notifies every client caching key that it changed
+func (app *App) trackingInvalidateKey(ctx context.Context, key string) {
+	modifier := GetClientFromContext(ctx)
+
+	if ids, exists := app.trackingKeys[key]; exists {
+		delete(app.trackingKeys, key)
+		for id := range ids {
+			client, exists := app.clients[id]
+			if !exists || !client.tracking.enabled || client.tracking.bcast {
+				continue
+			}
+			if client.tracking.noLoop && client == modifier {
+				continue
+			}
+			app.sendTrackingMessage(client, []string{key})
+		}
+	}
+
+	for prefix, ids := range app.trackingPrefixes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for id := range ids {
+			client, exists := app.clients[id]
+			if !exists || (client.tracking.noLoop && client == modifier) {
+				continue
+			}
+			app.sendTrackingMessage(client, []string{key})
+		}
+	}
+}
+
+// trackingInvalidateAll tells every tracking client to drop its whole cache,
+// it is used when the keyspace is flushed
+func (app *App) trackingInvalidateAll() {
+	clear(app.trackingKeys)
+	for _, client := range app.clients {
+		if client.tracking.enabled {
+			app.sendTrackingMessage(client, nil)
+		}
+	}
+}
+
+// sendTrackingMessage sends an invalidation message for keys, nil keys
+// invalidates everything
+func (app *App) sendTrackingMessage(client *Client, keys []string) {
+	target := client
+	usingRedirect := false
+
+	if redirect := client.tracking.redirect; redirect != 0 {
+		target = app.clients[redirect]
+		if target == nil {
+			if client.protocol == encoding.ProtocolRESP3 {
+				_ = client.writePush(types.NewPushRawCmd(
+					types.NewBulkStringRawCmd("tracking-redir-broken"),
+					types.NewIntegerRawCmd(redirect),
+				))
+			}
+			return
+		}
+		if _, subscribed := target.subscriptions[trackingChannel]; !subscribed && target.protocol != encoding.ProtocolRESP3 {
+			return
+		}
+		usingRedirect = true
+	}
+
+	keysCmd := types.NewNullRawCmd()
+	if keys != nil {
+		keysCmd = types.NewBulkArrayBulkString(keys)
+	}
+
+	switch {
+	case target.protocol == encoding.ProtocolRESP3:
+		_ = target.writePush(types.NewPushRawCmd(types.NewBulkStringRawCmd("invalidate"), keysCmd))
+	case usingRedirect:
+		_ = target.writePush(types.NewPushRawCmd(
+			types.NewBulkStringRawCmd("message"),
+			types.NewBulkStringRawCmd(trackingChannel),
+			keysCmd,
+		))
+	default:
+		// a RESP2 connection cannot receive pushes without a redirection
+	}
+}
+
+func (app *App) handleCLIENTTRACKING(ctx context.Context, c cmd.CLIENT_TRACKING) (types.RawCmd, error) {
+	client := GetClientFromContext(ctx)
+
+	var on bool
+	switch strings.ToUpper(c.Status) {
+	case "ON":
+		on = true
+	case "OFF":
+		on = false
+	default:
+		return types.RawCmd{}, NewSyntaxError()
+	}
+
+	tracking := clientTracking{
+		bcast:    c.BCAST,
+		optIn:    c.OPTIN,
+		optOut:   c.OPTOUT,
+		noLoop:   c.NOLOOP,
+		prefixes: c.PREFIX,
+	}
+	if c.REDIRECT != nil {
+		tracking.redirect = *c.REDIRECT
+	}
+
+	if !on {
+		app.disableTracking(client)
+		return types.NewStringRawCmd("OK"), nil
+	}
+
+	if tracking.redirect != 0 {
+		if _, exists := app.clients[tracking.redirect]; !exists {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "The client ID you want redirect to does not exist")
+		}
+	}
+	if len(tracking.prefixes) != 0 && !tracking.bcast {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "PREFIX option requires BCAST mode to be enabled")
+	}
+	if err := checkPrefixOverlaps(tracking.prefixes); err != nil {
+		return types.RawCmd{}, err
+	}
+	if tracking.optIn && tracking.optOut {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "You can't use both OPTIN and OPTOUT")
+	}
+	if tracking.bcast && (tracking.optIn || tracking.optOut) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "OPTIN and OPTOUT are not compatible with BCAST")
+	}
+	if current := client.tracking; current.enabled {
+		if current.bcast != tracking.bcast {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+		}
+		if current.optIn != tracking.optIn || current.optOut != tracking.optOut {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode.")
+		}
+	}
+
+	app.enableTracking(client, tracking)
+	return types.NewStringRawCmd("OK"), nil
+}
+
+// checkPrefixOverlaps rejects prefixes of which one starts with another, a key
+// matching both would be invalidated twice
+func checkPrefixOverlaps(prefixes []string) error {
+	for i, prefix := range prefixes {
+		for _, existing := range prefixes[:i] {
+			if strings.HasPrefix(prefix, existing) || strings.HasPrefix(existing, prefix) {
+				return NewCodedError(ErrorCodeERR, fmt.Sprintf("Prefix '%s' overlaps with an existing prefix '%s'. Prefixes for a single client must not overlap.", prefix, existing))
+			}
+		}
+	}
+	return nil
+}
+
+func (app *App) handleCLIENTCACHING(ctx context.Context, c cmd.CLIENT_CACHING) (types.RawCmd, error) {
+	client := GetClientFromContext(ctx)
+	tracking := client.tracking
+
+	if !tracking.enabled || (!tracking.optIn && !tracking.optOut) {
+		return types.RawCmd{}, NewCodedError(ErrorCodeERR, "CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	}
+
+	switch strings.ToUpper(c.Mode) {
+	case "YES":
+		if !tracking.optIn {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+		}
+	case "NO":
+		if !tracking.optOut {
+			return types.RawCmd{}, NewCodedError(ErrorCodeERR, "CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+		}
+	default:
+		return types.RawCmd{}, NewSyntaxError()
+	}
+
+	client.tracking.caching = true
+	return types.NewStringRawCmd("OK"), nil
+}
+
+func (app *App) handleCLIENTGETREDIR(ctx context.Context) (types.RawCmd, error) {
+	tracking := GetClientFromContext(ctx).tracking
+	if !tracking.enabled {
+		return types.NewIntegerRawCmd(-1), nil
+	}
+	return types.NewIntegerRawCmd(tracking.redirect), nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
+	"github.com/codecrafters-io/redis-starter-go/pkg/types"
+)
+
+func expectInvalidate(t *testing.T, reply types.RawCmd, sym types.Sym, key string) {
+	t.Helper()
+	if reply.Sym != sym || len(reply.Array) < 2 {
+		t.Fatalf("expect invalidation message, got %+v", reply)
+	}
+	keys := reply.Array[len(reply.Array)-1]
+	if len(keys.Array) != 1 || keys.Array[0].BulkString != key {
+		t.Fatalf("expect invalidation of `%s`, got %+v", key, reply)
+	}
+}
+
+func Test_TrackingDefaultRESP3(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	cache := newTestClient(t, addr)
+	writer := newTestClient(t, addr)
+
+	if reply := cache.do("HELLO", "3"); reply.Sym != types.SymMap {
+		t.Fatalf("expect map reply for HELLO, got %+v", reply)
+	}
+	cache.do("CLIENT", "TRACKING", "ON")
+	cache.do("GET", "k")
+
+	writer.do("SET", "k", "v")
+	expectInvalidate(t, cache.read(), types.SymPush, "k")
+
+	// the key is no longer tracked until it is read again
+	writer.do("SET", "k", "v2")
+	if reply := cache.do("ECHO", "next"); reply.String != "next" {
+		t.Fatalf("expect no more invalidation, got %+v", reply)
+	}
+}
+
+func Test_TrackingBCASTRedirectRESP2(t *testing.T) {
+	addr := startTestApp(t, NewApp(config.New()))
+	cache := newTestClient(t, addr)
+	subscriber := newTestClient(t, addr)
+	writer := newTestClient(t, addr)
+
+	// RESP2 has no map, HELLO replies with a flat array of key and values
+	var subscriberID int64
+	hello := subscriber.do("HELLO")
+	for idx := 0; idx+1 < len(hello.Array); idx += 2 {
+		if hello.Array[idx].BulkString == "id" {
+			subscriberID = hello.Array[idx+1].Integer
+		}
+	}
+	subscriber.do("SUBSCRIBE", trackingChannel)
+	if reply := subscriber.do("GET", "k"); reply.Sym != types.SymError {
+		t.Fatalf("expect subscribed RESP2 client to be restricted, got %+v", reply)
+	}
+
+	reply := cache.do("CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "user:", "PREFIX", "session:", "REDIRECT", strconv.FormatInt(subscriberID, 10))
+	if reply.String != "OK" {
+		t.Fatalf("expect OK, got %+v", reply)
+	}
+
+	writer.do("SET", "other", "v")
+	writer.do("SET", "user:1", "v")
+	expectInvalidate(t, subscriber.read(), types.SymArray, "user:1")
+	writer.do("SET", "session:1", "v")
+	expectInvalidate(t, subscriber.read(), types.SymArray, "session:1")
+}
+
+func Test_TrackingBCASTOverlappingPrefixes(t *testing.T) {
+	client := newTestClient(t, startTestApp(t, NewApp(config.New())))
+
+	expectEqual(t,
+		"ERR Prefix 'ab' overlaps with an existing prefix 'a'. Prefixes for a single client must not overlap.",
+		client.do("CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "a", "PREFIX", "ab").Error)
+	expectEqual(t,
+		"ERR Prefix 'a' overlaps with an existing prefix 'ab'. Prefixes for a single client must not overlap.",
+		client.do("CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "ab", "PREFIX", "a").Error)
+	expectEqual(t, "OK", client.do("CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "a", "PREFIX", "b").String)
+}
@@ -2,8 +2,13 @@ package app
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/config"
 	"github.com/codecrafters-io/redis-starter-go/pkg/ulid"
 )
 
@@ -40,39 +45,137 @@ func ValueTypeToName(valueType ValueType) string {
 	}
 }
 
+// ValueTypeFromName is the reverse of ValueTypeToName, ignoring the case
+func ValueTypeFromName(name string) (ValueType, bool) {
+	for valueType := ValueTypeString; valueType <= ValueTypeVectorSet; valueType++ {
+		if strings.EqualFold(ValueTypeToName(valueType), name) {
+			return valueType, true
+		}
+	}
+	return 0, false
+}
+
 type Value struct {
 	Key       string
 	ValueType ValueType
 	String    string
 	List      []string
+
+	// how the value is laid out, see updateEncoding
+	encoding Encoding
+	// compact forms of stored values: the number of an int string and the
+	// entries of a listpack list, String or List is then empty
+	integer  int64
+	listpack listpack
+	// access time or frequency for eviction, see updateAccess
+	lru uint32
+	// memory accounted for the value in its database
+	memory int64
 }
 
 type BLPOPConsumer struct {
 	id  ulid.ID
 	key string
-	ch  chan struct{}
+	// receives the element popped for the consumer
+	ch chan string
 }
 
 type App struct {
-	// TODO: make this thread safe
+	// held while executing a command, everything below is guarded by it
+	mutex sync.Mutex
+
+	config *config.Config
+	stats  stats
+	net    netStats
+
+	// random identifier of this run of the server
+	runID     string
+	startTime time.Time
 
-	dict   map[string]Value
-	expiry map[string]time.Time
+	// number of changes since the last snapshot
+	dirty           int64
+	lastSave        time.Time
+	lastSaveSucceed bool
 
-	keySpaceConsumer map[ulid.ID]chan Value
-	blpopConsumers   map[string][]BLPOPConsumer
+	// logical databases, as many as the databases parameter
+	dbs []*redisDB
+
+	// best candidates for eviction found by the previous samplings
+	evictionPool []evictionCandidate
+	// database to pick from next with the random policies
+	evictionNextDB int
+	// memory allocated once the server is initialized
+	startupAllocated int64
+
+	// ACL users by name, the default user always exists
+	users map[string]*aclUser
+	// ACL LOG entries, the most recent first
+	aclLog       []*aclLogEntry
+	aclLogNextID int64
+
+	clients      map[int64]*Client
+	lastClientID int64
+	// set by CLIENT PAUSE, pauseCh is closed by CLIENT UNPAUSE
+	pauseEnd    time.Time
+	pauseWrites bool
+	pauseCh     chan struct{}
+
+	pubsubChannels map[string]map[int64]*Client
+
+	// client ids caching a key, for tracking in default mode
+	trackingKeys map[string]map[int64]struct{}
+	// client ids interested in keys with a prefix, for tracking in BCAST mode
+	trackingPrefixes map[string]map[int64]struct{}
 
 	idGenerator *ulid.Generator
+
+	// atomic counters of the values freed in the background
+	lazyfree lazyfree
+
+	listeners []net.Listener
+	// connection goroutines started by Serve, waited for on shutdown
+	connections  sync.WaitGroup
+	shuttingDown atomic.Bool
+	// closed when the shutdown starts, to wake up blocked commands
+	shutdownCh chan struct{}
+	// closed when the shutdown is complete
+	done     chan struct{}
+	exitCode int
 }
 
-func NewApp() *App {
-	return &App{
-		dict:   map[string]Value{},
-		expiry: map[string]time.Time{},
+func NewApp(cfg *config.Config) *App {
+	app := &App{
+		config: cfg,
+
+		runID:           newRunID(),
+		startTime:       time.Now(),
+		lastSave:        time.Now(),
+		lastSaveSucceed: true,
+
+		users: map[string]*aclUser{defaultUser: newDefaultACLUser()},
 
-		keySpaceConsumer: map[ulid.ID]chan Value{},
-		blpopConsumers:   map[string][]BLPOPConsumer{},
+		clients: map[int64]*Client{},
+
+		pubsubChannels: map[string]map[int64]*Client{},
+
+		trackingKeys:     map[string]map[int64]struct{}{},
+		trackingPrefixes: map[string]map[int64]struct{}{},
 
 		idGenerator: ulid.NewGenerator(),
+
+		shutdownCh: make(chan struct{}),
+		done:       make(chan struct{}),
 	}
+
+	for id := range int(cfg.Int("databases")) {
+		app.dbs = append(app.dbs, newRedisDB(id))
+	}
+
+	app.applyRequirepass()
+
+	app.startupAllocated, _ = readMemoryMetrics()
+
+	go app.serverCron()
+
+	return app
 }
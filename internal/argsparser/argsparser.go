@@ -1,6 +1,7 @@
 package argsparser
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"slices"
@@ -8,29 +9,38 @@ import (
 	"strings"
 )
 
-/*
-TODO:
-- handle sub command
-- support different fieldName and argName
-*/
-
+// Errors returned by Parse wrap one of these, their messages follow the wording
+// of Redis so callers can report them to clients as is.
 var (
-	// TODO: move to use thread safe map
-	parsedCache map[reflect.Type]structMetadata
+	ErrWrongNumberOfArguments = errors.New("wrong number of arguments")
+	ErrSyntax                 = errors.New("syntax error")
+	ErrNotInteger             = errors.New("value is not an integer or out of range")
+	ErrNotFloat               = errors.New("value is not a valid float")
+	ErrNotTimeout             = errors.New("timeout is not a float or out of range")
+	ErrNegativeTimeout        = errors.New("timeout is negative")
+	ErrNotScoreRange          = errors.New("min or max is not a float")
+	ErrNotLexRange            = errors.New("min or max not valid string range item")
 )
 
-func init() {
-	parsedCache = map[reflect.Type]structMetadata{}
+// UnknownSubcommandError is returned when the subcommand of a container
+// command is not one of its subcommand fields
+type UnknownSubcommandError struct {
+	Subcommand string
+}
+
+func (e UnknownSubcommandError) Error() string {
+	return fmt.Sprintf("unknown subcommand '%s'", e.Subcommand)
 }
 
 type fieldType = string
 
 var (
-	fieldTypePosition  fieldType = "pos"
-	fieldTypeOption    fieldType = "opt"
-	fieldTypeEnum      fieldType = "enum"
-	fieldTypeEnumValue fieldType = "enum-value"
-	fieldTypeEnumKey   fieldType = "enum-key"
+	fieldTypePosition   fieldType = "pos"
+	fieldTypeOption     fieldType = "opt"
+	fieldTypeEnum       fieldType = "enum"
+	fieldTypeEnumValue  fieldType = "enum-value"
+	fieldTypeEnumKey    fieldType = "enum-key"
+	fieldTypeSubcommand fieldType = "sub"
 
 	fieldTypeAuto = "auto"
 )
@@ -40,36 +50,63 @@ type attribute struct {
 	isUnimplemented bool
 	isVariadic      bool
 	rawDefault      string
+	// name of the value in the schema, e.g. `seconds` for `EX seconds`
+	display string
+	// keyword of an option or enum member when it differs from the field
+	// name, e.g. `WITHSCORES` for `WithScores`
+	name string
 }
 
 type positionMetadata struct {
-	fieldName string
-	kind      reflect.Kind
+	fieldSetter
+	kind reflect.Kind
+	// type of the value, the element of pointers and slices
+	valueType reflect.Type
 	attribute attribute
 	position  int
+	// fields of a repeated group, e.g. `field value [field value ...]`
+	group *structMetadata
 }
 
 type enumMemberMetadata struct {
-	fieldName string
+	fieldSetter
 	argName   string
 	kind      reflect.Kind
+	valueType reflect.Type
 	attribute attribute
 	parent    *enumMetadata
 }
 
 type enumMetadata struct {
 	fieldName string
+	index     int
 	attribute attribute
 	// keys are arg name
 	enumMembers       map[string]enumMemberMetadata
 	storeKeyFieldName string
+	storeKeyIndex     int
+	// arg names of the members in declaration order
+	memberNames []string
 }
 
 type optionMetadata struct {
-	fieldName string
+	fieldSetter
 	argName   string
 	kind      reflect.Kind
+	valueType reflect.Type
 	attribute attribute
+	// fields following the keyword, e.g. `LIMIT offset count`
+	group *structMetadata
+}
+
+// subcommandMetadata is a subcommand field of a container struct, a pointer to
+// the struct of the subcommand arguments or the raw arguments for the ones the
+// tags cannot describe yet
+type subcommandMetadata struct {
+	fieldName string
+	index     int
+	name      string
+	raw       bool
 }
 
 type optionOrEnumMember struct {
@@ -84,38 +121,74 @@ type structMetadata struct {
 
 	options []optionMetadata
 	enums   []enumMetadata
+	// field names of the options and enums in declaration order
+	fieldOrder []string
 
 	argKeys map[string]optionOrEnumMember
+
+	// keys are upper case subcommand names
+	subcommands map[string]subcommandMetadata
+	// subcommand names in declaration order
+	subcommandNames []string
 }
 
 func Parse[T any](args []string) (T, error) {
 	var result T
-	var smd structMetadata
+	err := parseValue(args, reflect.ValueOf(&result).Elem())
+	return result, err
+}
 
-	if cached, exists := parsedCache[reflect.TypeFor[T]()]; exists {
-		smd = cached
-	} else {
-		var err error
-		smd, err = extractTag[T]()
-		if err != nil {
-			return result, err
-		}
-		parsedCache[reflect.TypeFor[T]()] = smd
+func parseValue(args []string, value reflect.Value) error {
+	smd, err := metadataOf(value.Type())
+	if err != nil {
+		return err
+	}
+	if len(smd.subcommands) != 0 {
+		return parseSubcommand(args, smd, value)
 	}
 
-	value := reflect.ValueOf(&result)
-	value = value.Elem()
 	idx := 1
 
 	if err := parsePositions(args, smd, value, &idx); err != nil {
-		return result, err
+		return usageError(args[0], smd, err)
 	}
 
 	if err := parseOptionsAndEnums(args, smd, value, &idx); err != nil {
-		return result, err
+		return usageError(args[0], smd, err)
 	}
 
-	return result, nil
+	return nil
+}
+
+// parseSubcommand sets the field of the subcommand named by the second
+// argument, the subcommand arguments start with its name like the ones of a
+// command
+func parseSubcommand(args []string, smd structMetadata, value reflect.Value) error {
+	if len(args) < 2 {
+		return fmt.Errorf("no subcommand: %w", ErrWrongNumberOfArguments)
+	}
+	sub, exists := smd.subcommands[strings.ToUpper(args[1])]
+	if !exists {
+		return UnknownSubcommandError{Subcommand: args[1]}
+	}
+
+	subArgs := args[1:]
+	fieldValue := value.Field(sub.index)
+	if sub.raw {
+		fieldValue.Set(reflect.ValueOf(slices.Clone(subArgs)))
+		return nil
+	}
+	subValue := reflect.New(fieldValue.Type().Elem())
+	if err := parseValue(subArgs, subValue.Elem()); err != nil {
+		return err
+	}
+	fieldValue.Set(subValue)
+	return nil
+}
+
+// usageError adds the syntax of the command to an error of its arguments
+func usageError(command string, smd structMetadata, err error) error {
+	return fmt.Errorf("%w, usage: %s", err, Syntax(command, buildSchema(smd)))
 }
 
 func parsePositions(args []string, smd structMetadata, value reflect.Value, idx *int) error {
@@ -136,13 +209,13 @@ func parsePositionsVariadic(args []string, smd structMetadata, value reflect.Val
 	variadicArgsLength := argsLength - (posLength - 1)
 	variadicEndIdx := variadicStartIdx + variadicArgsLength
 
-	if argsLength < variadicStartIdx || variadicArgsLength < 0 {
-		return fmt.Errorf("not enough required arguments")
+	if argsLength < variadicStartIdx || variadicArgsLength < 1 {
+		return fmt.Errorf("not enough required arguments: %w", ErrWrongNumberOfArguments)
 	}
 
 	// handle required arguments before variadic argument
 	for *idx < argsLength && posIdx < variadicStartIdx {
-		if err := setFieldValue(value, positions[posIdx].fieldName, args[*idx]); err != nil {
+		if err := setFieldValue(value, positions[posIdx].fieldSetter, args[*idx]); err != nil {
 			return fmt.Errorf("set required position argument %d failed: %w", posIdx, err)
 		}
 		*idx += 1
@@ -151,7 +224,11 @@ func parsePositionsVariadic(args []string, smd structMetadata, value reflect.Val
 
 	// handle variadic arguments
 	variadictArgs := args[variadicStartIdx+1 : variadicEndIdx]
-	if err := setFieldArrayValue(value, positions[smd.variadicPositionArgIndex].fieldName, variadictArgs); err != nil {
+	variadic := positions[smd.variadicPositionArgIndex]
+	if len(variadictArgs) == 0 && !variadic.attribute.isOptional {
+		return fmt.Errorf("not enough values for the variadic position argument: %w", ErrWrongNumberOfArguments)
+	}
+	if err := setFieldArrayValue(value, variadic.fieldSetter, variadic.group, variadictArgs); err != nil {
 		return err
 	}
 	*idx += variadicArgsLength - 1
@@ -159,7 +236,7 @@ func parsePositionsVariadic(args []string, smd structMetadata, value reflect.Val
 
 	// handle required arguments after variadic argument
 	for *idx < argsLength {
-		if err := setFieldValue(value, positions[posIdx].fieldName, args[*idx]); err != nil {
+		if err := setFieldValue(value, positions[posIdx].fieldSetter, args[*idx]); err != nil {
 			return fmt.Errorf("set required position argument %d failed: %w", posIdx, err)
 		}
 		*idx += 1
@@ -176,13 +253,17 @@ func parsePositionsOptional(args []string, smd structMetadata, value reflect.Val
 	argsLength := len(args)
 	posLength := len(smd.positions)
 
-	if argsLength < smd.optionalPositionArgStartIndex {
-		return fmt.Errorf("not enough required arguments")
+	requiredLength := smd.optionalPositionArgStartIndex
+	if requiredLength == -1 {
+		requiredLength = posLength
+	}
+	if argsLength-1 < requiredLength {
+		return fmt.Errorf("not enough required arguments: %w", ErrWrongNumberOfArguments)
 	}
 
 	// handle required position arguments
-	for *idx < argsLength && posIdx < smd.optionalPositionArgStartIndex {
-		if err := setFieldValue(value, positions[posIdx].fieldName, args[*idx]); err != nil {
+	for *idx < argsLength && posIdx < requiredLength {
+		if err := setFieldValue(value, positions[posIdx].fieldSetter, args[*idx]); err != nil {
 			return fmt.Errorf("set required position argument %d failed: %w", posIdx, err)
 		}
 		*idx += 1
@@ -191,7 +272,7 @@ func parsePositionsOptional(args []string, smd structMetadata, value reflect.Val
 
 	// handle optional position arguments
 	for *idx < argsLength && posIdx < posLength {
-		if err := setFieldValue(value, smd.positions[posIdx].fieldName, args[*idx]); err != nil {
+		if err := setFieldValue(value, smd.positions[posIdx].fieldSetter, args[*idx]); err != nil {
 			return fmt.Errorf("set optional position argument %d failed: %w", posIdx, err)
 		}
 		*idx += 1
@@ -201,7 +282,7 @@ func parsePositionsOptional(args []string, smd structMetadata, value reflect.Val
 	// handle default value for optional position arguments
 	for posIdx < posLength {
 		if pmd := positions[posIdx]; pmd.attribute.rawDefault != "" {
-			if err := setFieldValue(value, pmd.fieldName, pmd.attribute.rawDefault); err != nil {
+			if err := setFieldValue(value, pmd.fieldSetter, pmd.attribute.rawDefault); err != nil {
 				return fmt.Errorf("set default value for optional position argument %d failed: %w", posIdx, err)
 			}
 		}
@@ -214,20 +295,32 @@ func parsePositionsOptional(args []string, smd structMetadata, value reflect.Val
 
 func parseOptionsAndEnums(args []string, smd structMetadata, value reflect.Value, idx *int) error {
 	length := len(args)
+	if *idx < length && len(smd.argKeys) == 0 {
+		return fmt.Errorf("too many arguments: %w", ErrWrongNumberOfArguments)
+	}
 	doneFields := map[string]bool{}
+	// enum field names to the member given, only one alternative is allowed
+	doneEnums := map[string]string{}
 	for *idx < length {
 		name := strings.ToUpper(args[*idx])
 		*idx += 1
 
-		// TODO: handle enum can only appears one alternative (e.g. "SET a a NX XX")
-
-		if doneFields[name] {
-			return fmt.Errorf("argument `%s` appears more than one time", name)
-		}
-
 		metadata, exists := smd.argKeys[name]
 		if !exists {
-			return fmt.Errorf("invalid argument `%s`", name)
+			return fmt.Errorf("invalid argument `%s`: %w", name, ErrSyntax)
+		}
+
+		// options of slice kind may be repeated
+		repeated := metadata.option != nil && metadata.option.kind == reflect.Slice
+		if doneFields[name] && !repeated {
+			return fmt.Errorf("argument `%s` appears more than one time: %w", name, ErrSyntax)
+		}
+		if metadata.enumMember != nil {
+			enumName := metadata.enumMember.parent.fieldName
+			if other, exists := doneEnums[enumName]; exists {
+				return fmt.Errorf("argument `%s` cannot be used with `%s`: %w", name, other, ErrSyntax)
+			}
+			doneEnums[enumName] = name
 		}
 
 		if err := processOptionsAndEnums(args, name, metadata, value, idx); err != nil {
@@ -250,11 +343,18 @@ func processOptionsAndEnums(args []string, key string, md optionOrEnumMember, va
 }
 
 func processOption(args []string, omd optionMetadata, value reflect.Value, idx *int) error {
+	if omd.group != nil {
+		return processGroupOption(args, omd, value, idx)
+	}
+	if omd.attribute.isVariadic {
+		return processVariadicOption(args, omd, value, idx)
+	}
+
 	raw := ""
 	if omd.kind != reflect.Bool {
 		if len(args) == *idx {
 			if !omd.attribute.isOptional {
-				return fmt.Errorf("no value provided")
+				return fmt.Errorf("no value provided: %w", ErrSyntax)
 			}
 			raw = omd.attribute.rawDefault
 		} else {
@@ -262,33 +362,73 @@ func processOption(args []string, omd optionMetadata, value reflect.Value, idx *
 			*idx += 1
 		}
 	}
-	if err := setFieldValue(value, omd.fieldName, raw); err != nil {
+	if omd.kind == reflect.Slice {
+		return appendFieldValue(value, omd.fieldSetter, nil, []string{raw})
+	}
+	if err := setFieldValue(value, omd.fieldSetter, raw); err != nil {
 		return err
 	}
 	return nil
 }
 
+// processGroupOption sets the fields of the group following the keyword of
+// the option, all of them are required
+func processGroupOption(args []string, omd optionMetadata, value reflect.Value, idx *int) error {
+	size := len(omd.group.positions)
+	if len(args)-*idx < size {
+		return fmt.Errorf("expect %d values: %w", size, ErrSyntax)
+	}
+	raws := args[*idx : *idx+size]
+	*idx += size
+
+	if omd.kind == reflect.Slice {
+		return appendFieldValue(value, omd.fieldSetter, omd.group, raws)
+	}
+	fieldValue := value.Field(omd.index)
+	groupValue := reflect.New(fieldValue.Type().Elem())
+	if err := setGroupValue(groupValue.Elem(), *omd.group, raws); err != nil {
+		return fmt.Errorf("set value for field %s: %w", omd.fieldName, err)
+	}
+	fieldValue.Set(groupValue)
+	return nil
+}
+
+// processVariadicOption sets the field of the option from every argument
+// following its keyword, e.g. `ID id [id ...]`
+func processVariadicOption(args []string, omd optionMetadata, value reflect.Value, idx *int) error {
+	if len(args) == *idx {
+		return fmt.Errorf("no value provided: %w", ErrSyntax)
+	}
+	raws := args[*idx:]
+	*idx = len(args)
+	return setFieldArrayValue(value, omd.fieldSetter, nil, raws)
+}
+
 func processEnumMember(args []string, key string, md enumMemberMetadata, value reflect.Value, idx *int) error {
 	raw := ""
 	if md.kind != reflect.Bool {
 		if len(args) == *idx {
-			return fmt.Errorf("no value provided")
+			return fmt.Errorf("no value provided: %w", ErrSyntax)
 		}
 		raw = args[*idx]
 		*idx += 1
 	}
 	parent := md.parent
-	if err := setEnumMemberValue(value, parent.fieldName, md.fieldName, raw); err != nil {
-		return err
+	enumValue := value.Field(parent.index)
+	if err := setFieldValue(enumValue, md.fieldSetter, raw); err != nil {
+		return fmt.Errorf("set value for option enum %s: %w", parent.fieldName, err)
 	}
 	if parent.storeKeyFieldName != "" {
-		if err := setEnumMemberValue(value, parent.fieldName, parent.storeKeyFieldName, key); err != nil {
-			return err
-		}
+		enumValue.Field(parent.storeKeyIndex).SetString(key)
 	}
 	return nil
 }
 
+// isSimpleType reports whether t is set from a single argument
+func isSimpleType(t reflect.Type) bool {
+	return isValueType(t) || isSimpleKind(t.Kind())
+}
+
 func isSimpleKind(kind reflect.Kind) bool {
 	switch kind {
 	case reflect.Bool,
@@ -301,49 +441,27 @@ func isSimpleKind(kind reflect.Kind) bool {
 	return false
 }
 
-func setSimpleValue(value reflect.Value, raw string) error {
-	switch kind := value.Type().Kind(); kind {
+func setFieldArrayValue(value reflect.Value, f fieldSetter, group *structMetadata, raws []string) error {
+	fieldValue := value.Field(f.index)
 
-	// TODO: refined parse number method
-	// TODO: validate with value.Can***
-	case reflect.Bool:
-		// bool is always set to true without checking raw
-		// since to get here, the key must be present on the arg
-		value.SetBool(true)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v, err := strconv.ParseInt(raw, 10, 64)
-		if err != nil {
-			return err
-		}
-		value.SetInt(v)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		v, err := strconv.ParseUint(raw, 10, 64)
-		if err != nil {
-			return err
-		}
-		value.SetUint(v)
-	case reflect.Float32, reflect.Float64:
-		v, err := strconv.ParseFloat(raw, 64)
-		if err != nil {
-			return err
+	if group == nil {
+		slice := reflect.MakeSlice(fieldValue.Type(), len(raws), len(raws))
+		for idx, raw := range raws {
+			if err := f.set(slice.Index(idx), raw); err != nil {
+				return fmt.Errorf("set value for field %s: %w", f.fieldName, err)
+			}
 		}
-		value.SetFloat(v)
-	case reflect.String:
-		value.SetString(raw)
-	default:
-		return fmt.Errorf("invalid simple type %s", kind)
+		fieldValue.Set(slice)
+		return nil
 	}
 
-	return nil
-}
-
-func setFieldArrayValue(value reflect.Value, fieldName string, raws []string) error {
-	fieldValue := value.FieldByName(fieldName)
-
-	slice := reflect.MakeSlice(fieldValue.Type(), len(raws), len(raws))
-	for idx, raw := range raws {
-		elem := slice.Index(idx)
-		if err := setSimpleValue(elem, raw); err != nil {
+	size := len(group.positions)
+	if len(raws)%size != 0 {
+		return fmt.Errorf("expect groups of %d values: %w", size, ErrWrongNumberOfArguments)
+	}
+	slice := reflect.MakeSlice(fieldValue.Type(), len(raws)/size, len(raws)/size)
+	for idx := range slice.Len() {
+		if err := setGroupValue(slice.Index(idx), *group, raws[idx*size:(idx+1)*size]); err != nil {
 			return err
 		}
 	}
@@ -351,35 +469,46 @@ func setFieldArrayValue(value reflect.Value, fieldName string, raws []string) er
 	return nil
 }
 
-func setFieldValue(value reflect.Value, fieldName string, raw string) error {
-	// TODO: validate field exists
-	fieldValue := value.FieldByName(fieldName)
-
-	if fieldValue.Kind() == reflect.Pointer {
-		ptrValue := reflect.New(fieldValue.Type().Elem())
-		fieldValue.Set(ptrValue)
-		fieldValue = fieldValue.Elem()
+// appendFieldValue appends to a slice field the value of one occurrence of a
+// repeated option
+func appendFieldValue(value reflect.Value, f fieldSetter, group *structMetadata, raws []string) error {
+	fieldValue := value.Field(f.index)
+	elem := reflect.New(fieldValue.Type().Elem()).Elem()
+	var err error
+	if group != nil {
+		err = setGroupValue(elem, *group, raws)
+	} else {
+		err = f.set(elem, raws[0])
 	}
-
-	if err := setSimpleValue(fieldValue, raw); err != nil {
-		return fmt.Errorf("set value for field %s: %w", fieldName, err)
+	if err != nil {
+		return fmt.Errorf("set value for field %s: %w", f.fieldName, err)
 	}
+	fieldValue.Set(reflect.Append(fieldValue, elem))
 	return nil
 }
 
-func setEnumMemberValue(value reflect.Value, parentFieldName, fieldName, raw string) error {
-	// TODO: handle error
-	fieldValue := value.FieldByName(parentFieldName).FieldByName(fieldName)
-	if err := setSimpleValue(fieldValue, raw); err != nil {
-		return fmt.Errorf("set value for option enum %s.%s: %w", parentFieldName, fieldName, err)
+// setGroupValue sets the position fields of a group, one raw value each
+func setGroupValue(value reflect.Value, group structMetadata, raws []string) error {
+	for idx, pmd := range group.positions {
+		if err := setFieldValue(value, pmd.fieldSetter, raws[idx]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+func setFieldValue(value reflect.Value, f fieldSetter, raw string) error {
+	if err := f.set(value.Field(f.index), raw); err != nil {
+		return fmt.Errorf("set value for field %s: %w", f.fieldName, err)
+	}
 	return nil
 }
 
 func extractTag[T any]() (structMetadata, error) {
-	t := reflect.TypeFor[T]()
+	return extractMetadata(reflect.TypeFor[T]())
+}
 
+func extractMetadata(t reflect.Type) (structMetadata, error) {
 	if t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
@@ -428,8 +557,7 @@ func validateAndFillMetadata(result *structMetadata) error {
 		}
 		seenPos[pmd.position] = true
 
-		isInOptional = isInOptional || pmd.attribute.isOptional
-
+		// a variadic argument takes at least one value unless it is optional
 		if pmd.attribute.isVariadic {
 			if isInOptional {
 				return fmt.Errorf("has optional and variadic position arguments at the same time")
@@ -441,14 +569,16 @@ func validateAndFillMetadata(result *structMetadata) error {
 			continue
 		}
 
-		if pmd.attribute.isOptional {
-			if !isInOptional {
-				return fmt.Errorf("position %d is a required value appears after optional value", pmd.position)
-			}
+		isInOptional = isInOptional || pmd.attribute.isOptional
+
+		if !pmd.attribute.isOptional && isInOptional {
+			return fmt.Errorf("position %d is a required value appears after optional value", pmd.position)
+		}
+		if pmd.attribute.isOptional && result.optionalPositionArgStartIndex == -1 {
 			result.optionalPositionArgStartIndex = idx
 		}
 	}
-	if length := len(seenPos); length != result.positions[length-1].position {
+	if length := len(seenPos); length != 0 && length != result.positions[length-1].position {
 		return fmt.Errorf("max position is not them same as the number of position arguments")
 	}
 
@@ -476,12 +606,22 @@ func validateAndFillMetadata(result *structMetadata) error {
 		return fmt.Errorf("has variadic position argument and option enum argument at the same time")
 	}
 
+	if len(result.subcommands) != 0 && (len(result.positions) != 0 || len(argKeys) != 0) {
+		return fmt.Errorf("has subcommands and arguments at the same time")
+	}
+
 	return nil
 }
 
 func extractFieldTag(field reflect.StructField, smd *structMetadata) (err error) {
 	kind := field.Type.Kind()
 	fieldName := field.Name
+	valueType := field.Type
+	if kind == reflect.Pointer || kind == reflect.Slice {
+		valueType = field.Type.Elem()
+	}
+	// structs are groups of arguments unless they are parsed as a Value
+	isGroup := valueType.Kind() == reflect.Struct && !isValueType(valueType)
 
 	fieldType, fieldSnd, attribute, err := parseTag(field.Tag.Get("arg"))
 	if err != nil {
@@ -493,19 +633,19 @@ func extractFieldTag(field reflect.StructField, smd *structMetadata) (err error)
 		if kind == reflect.Bool {
 			return fmt.Errorf("invalid position argument must not be of boolean type")
 		}
+		var group *structMetadata
 		if attribute.isVariadic {
 			// TODO: check for reflect.Array
 			if kind != reflect.Slice {
 				return fmt.Errorf("variadic position argument must have the kind array")
 			}
-		} else {
-			elemKind := kind
-			if elemKind == reflect.Pointer {
-				elemKind = field.Type.Elem().Kind()
-			}
-			if !isSimpleKind(elemKind) {
-				return fmt.Errorf("invalid non variadic position argument kind")
+			if isGroup {
+				if group, err = groupMetadata(valueType); err != nil {
+					return err
+				}
 			}
+		} else if kind == reflect.Slice || !isSimpleType(valueType) {
+			return fmt.Errorf("invalid non variadic position argument kind")
 		}
 		if fieldSnd == "" {
 			return fmt.Errorf("invalid pos format")
@@ -514,15 +654,29 @@ func extractFieldTag(field reflect.StructField, smd *structMetadata) (err error)
 		if err != nil {
 			return fmt.Errorf("convert position failed %w", err)
 		}
+		setterType := valueType
+		if kind != reflect.Slice {
+			setterType = field.Type
+		}
+		if group != nil {
+			setterType = nil
+		}
+		f, err := newFieldSetter(field, setterType)
+		if err != nil {
+			return fmt.Errorf("extract setter for field %s failed: %w", fieldName, err)
+		}
 		smd.positions = append(smd.positions, positionMetadata{
-			fieldName: fieldName,
-			kind:      kind,
-			attribute: attribute,
-			position:  position,
+			fieldSetter: f,
+			kind:        kind,
+			valueType:   valueType,
+			attribute:   attribute,
+			position:    position,
+			group:       group,
 		})
 	case fieldTypeEnum:
 		emd := enumMetadata{
 			fieldName: fieldName,
+			index:     field.Index[0],
 			attribute: attribute,
 		}
 
@@ -533,13 +687,66 @@ func extractFieldTag(field reflect.StructField, smd *structMetadata) (err error)
 
 		emd.enumMembers = enumMembers
 		smd.enums = append(smd.enums, emd)
+		smd.fieldOrder = append(smd.fieldOrder, fieldName)
 	case fieldTypeOption, fieldTypeAuto:
-		smd.options = append(smd.options, optionMetadata{
+		omd := optionMetadata{
+			fieldSetter: fieldSetter{fieldName: fieldName, index: field.Index[0]},
+			argName:     fieldName,
+			kind:        kind,
+			valueType:   valueType,
+			attribute:   attribute,
+		}
+		if attribute.name != "" {
+			omd.argName = strings.ToUpper(attribute.name)
+		}
+		if attribute.isVariadic && (kind != reflect.Slice || isGroup) {
+			return fmt.Errorf("variadic option must be a slice of values")
+		}
+		if isGroup {
+			if kind == reflect.Struct {
+				return fmt.Errorf("group option must be a pointer or a slice of structs")
+			}
+			if omd.group, err = groupMetadata(valueType); err != nil {
+				return err
+			}
+		} else {
+			setterType := valueType
+			if kind != reflect.Slice {
+				setterType = field.Type
+			}
+			if omd.set, err = newSetter(setterType); err != nil {
+				return fmt.Errorf("extract setter for field %s failed: %w", fieldName, err)
+			}
+		}
+		smd.options = append(smd.options, omd)
+		smd.fieldOrder = append(smd.fieldOrder, fieldName)
+	case fieldTypeSubcommand:
+		sub := subcommandMetadata{
 			fieldName: fieldName,
-			argName:   fieldName,
-			kind:      kind,
-			attribute: attribute,
-		})
+			index:     field.Index[0],
+			name:      strings.ToUpper(fieldName),
+		}
+		if fieldSnd != "" {
+			sub.name = strings.ToUpper(fieldSnd)
+		}
+		switch {
+		case kind == reflect.Slice && field.Type.Elem().Kind() == reflect.String:
+			sub.raw = true
+		case kind == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct:
+			if _, err := metadataOf(field.Type.Elem()); err != nil {
+				return fmt.Errorf("extract subcommand %s metadata failed: %w", sub.name, err)
+			}
+		default:
+			return fmt.Errorf("subcommand must be a pointer to struct or a string slice")
+		}
+		if smd.subcommands == nil {
+			smd.subcommands = map[string]subcommandMetadata{}
+		}
+		if _, exists := smd.subcommands[sub.name]; exists {
+			return fmt.Errorf("subcommand %s appears more than one time", sub.name)
+		}
+		smd.subcommands[sub.name] = sub
+		smd.subcommandNames = append(smd.subcommandNames, sub.name)
 	default:
 		return fmt.Errorf("unknown tag field type %s", fieldType)
 	}
@@ -547,6 +754,20 @@ func extractFieldTag(field reflect.StructField, smd *structMetadata) (err error)
 	return
 }
 
+// groupMetadata returns the metadata of the struct of a group, only required
+// position arguments can be grouped
+func groupMetadata(t reflect.Type) (*structMetadata, error) {
+	group, err := metadataOf(t)
+	if err != nil {
+		return nil, fmt.Errorf("extract group metadata failed: %w", err)
+	}
+	if len(group.positions) == 0 || len(group.argKeys) != 0 || len(group.subcommands) != 0 ||
+		group.optionalPositionArgStartIndex != -1 || group.variadicPositionArgIndex != -1 {
+		return nil, fmt.Errorf("group must only have required position arguments")
+	}
+	return &group, nil
+}
+
 func parseTag(tag string) (fieldType fieldType, fieldSnd string, attribute attribute, err error) {
 	// TODO: handle trim after split
 	fieldType = fieldTypeAuto
@@ -570,12 +791,19 @@ func parseTag(tag string) (fieldType fieldType, fieldSnd string, attribute attri
 		switch parts[0] {
 		case "default":
 			if len(parts) != 2 {
-				panic("wrong default attribute format")
+				return "", "", attribute, fmt.Errorf("wrong default attribute format `%s`", attrRaw)
 			}
 			attribute.rawDefault = parts[1]
+		case "display":
+			if len(parts) != 2 {
+				return "", "", attribute, fmt.Errorf("wrong display attribute format `%s`", attrRaw)
+			}
+			attribute.display = parts[1]
 		case "name":
-			// TODO:
-			panic("name attribute not handled")
+			if len(parts) != 2 {
+				return "", "", attribute, fmt.Errorf("wrong name attribute format `%s`", attrRaw)
+			}
+			attribute.name = parts[1]
 		case "optional":
 			attribute.isOptional = true
 		case "variadic":
@@ -584,8 +812,7 @@ func parseTag(tag string) (fieldType fieldType, fieldSnd string, attribute attri
 		case "unimplemented":
 			attribute.isUnimplemented = true
 		default:
-			// TODO:
-			panic("attribute not handled")
+			return "", "", attribute, fmt.Errorf("attribute `%s` not handled", parts[0])
 		}
 		// TODO: strict validation
 	}
@@ -608,32 +835,45 @@ func extractEnumMember(t reflect.Type, parent *enumMetadata) (map[string]enumMem
 		fieldKind := field.Type.Kind()
 
 		fieldName := field.Name
-		argName := field.Name
 
 		// TODO: validate fieldSnd
 		fieldType, _, attribute, err := parseTag(field.Tag.Get("arg"))
 		if err != nil {
 			return nil, fmt.Errorf("extract tag for field %s failed: %w", fieldName, err)
 		}
+		argName := fieldName
+		if attribute.name != "" {
+			argName = strings.ToUpper(attribute.name)
+		}
 
 		switch fieldType {
 		case fieldTypeEnumValue, fieldTypeAuto:
-			if !isSimpleKind(fieldKind) {
+			if !isSimpleType(field.Type) {
 				return nil, fmt.Errorf("invalid kind %s", fieldKind)
 			}
+			f, err := newFieldSetter(field, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("extract setter for field %s failed: %w", fieldName, err)
+			}
 			result[argName] = enumMemberMetadata{
-				fieldName: fieldName,
-				argName:   argName,
-				kind:      fieldKind,
-				attribute: attribute,
-				parent:    parent,
+				fieldSetter: f,
+				argName:     argName,
+				kind:        fieldKind,
+				valueType:   field.Type,
+				attribute:   attribute,
+				parent:      parent,
 			}
+			parent.memberNames = append(parent.memberNames, argName)
 		case fieldTypeEnumKey:
 			// TODO: strict validate no other options
 			if parent.storeKeyFieldName != "" {
 				return nil, fmt.Errorf("cannot have multiple %s tag", fieldTypeEnumKey)
 			}
+			if fieldKind != reflect.String {
+				return nil, fmt.Errorf("%s field %s must be a string", fieldTypeEnumKey, fieldName)
+			}
 			parent.storeKeyFieldName = fieldName
+			parent.storeKeyIndex = i
 		default:
 			return nil, fmt.Errorf("invalid tag field type %s", fieldType)
 		}
@@ -1,6 +1,8 @@
 package argsparser
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -127,6 +129,32 @@ func Test_ParseVariadicRequiredAfter(t *testing.T) {
 	expectEqual(t, "another", c.TestKey)
 }
 
+func Test_ParseVariadicEmpty(t *testing.T) {
+	type subscribe struct {
+		Channels []string `arg:"pos:1,variadic"`
+	}
+	if _, err := Parse[subscribe]([]string{"SUBSCRIBE"}); !errors.Is(err, ErrWrongNumberOfArguments) {
+		t.Errorf("expect a required variadic argument without values to fail, got %v", err)
+	}
+
+	type unsubscribe struct {
+		Channels []string `arg:"pos:1,variadic,optional"`
+	}
+	c1, err := Parse[unsubscribe]([]string{"UNSUBSCRIBE"})
+	expectNoError(t, err)
+	expectEqual(t, 0, len(c1.Channels))
+
+	type blpop struct {
+		Key     string   `arg:"pos:1"`
+		KeyRest []string `arg:"pos:2,variadic,optional"`
+		Timeout int      `arg:"pos:3"`
+	}
+	c2, err := Parse[blpop]([]string{"BLPOP", "key", "0"})
+	expectNoError(t, err)
+	expectEqual(t, "key", c2.Key)
+	expectEqual(t, 0, len(c2.KeyRest))
+}
+
 func Test_ParseOptionalPositionPointer(t *testing.T) {
 	type lpop struct {
 		Key   string `arg:"pos:1"`
@@ -145,3 +173,233 @@ func Test_ParseOptionalPositionPointer(t *testing.T) {
 	expectEqual(t, "key_2", c2.Key)
 	expectNoNilEqual(t, 12, c2.Count)
 }
+
+type objectEncoding struct {
+	Key string `arg:"pos:1"`
+}
+
+type object struct {
+	ENCODING *objectEncoding `arg:"sub"`
+	NOEVICT  *struct {
+		Enabled string `arg:"pos:1"`
+	} `arg:"sub:NO-EVICT"`
+	LIST []string `arg:"sub"`
+}
+
+func Test_ParseSubcommand(t *testing.T) {
+	c1, err1 := Parse[object]([]string{"OBJECT", "encoding", "foo"})
+
+	expectNoError(t, err1)
+	expectNoNilEqual(t, objectEncoding{Key: "foo"}, c1.ENCODING)
+	expectEqual(t, true, c1.NOEVICT == nil)
+	expectEqual(t, 0, len(c1.LIST))
+
+	c2, err2 := Parse[object]([]string{"OBJECT", "NO-EVICT", "on"})
+
+	expectNoError(t, err2)
+	expectEqual(t, "on", c2.NOEVICT.Enabled)
+
+	// raw subcommands start with their name like the other subcommands
+	c3, err3 := Parse[object]([]string{"OBJECT", "LIST", "ID", "1", "2"})
+
+	expectNoError(t, err3)
+	expectEqualSlice(t, []string{"LIST", "ID", "1", "2"}, c3.LIST)
+}
+
+func Test_ParseSubcommandErrors(t *testing.T) {
+	_, err := Parse[object]([]string{"OBJECT", "nope"})
+	var subcommandErr UnknownSubcommandError
+	if !errors.As(err, &subcommandErr) {
+		t.Fatal("expect an unknown subcommand error but got:", err)
+	}
+	expectEqual(t, "nope", subcommandErr.Subcommand)
+
+	if _, err := Parse[object]([]string{"OBJECT"}); !errors.Is(err, ErrWrongNumberOfArguments) {
+		t.Error("expect a wrong number of arguments error but got:", err)
+	}
+	if _, err := Parse[object]([]string{"OBJECT", "ENCODING"}); !errors.Is(err, ErrWrongNumberOfArguments) {
+		t.Error("expect a wrong number of arguments error but got:", err)
+	}
+}
+
+func Test_extractTagSubcommandWithArguments(t *testing.T) {
+	type mixed struct {
+		Key      string          `arg:"pos:1"`
+		ENCODING *objectEncoding `arg:"sub"`
+	}
+	if _, err := extractTag[mixed](); err == nil {
+		t.Error("expect subcommands mixed with arguments to be invalid")
+	}
+
+	type notStruct struct {
+		ENCODING *string `arg:"sub"`
+	}
+	if _, err := extractTag[notStruct](); err == nil {
+		t.Error("expect a subcommand of a non struct type to be invalid")
+	}
+}
+
+type zrangeStruct struct {
+	Key   string `arg:"pos:1"`
+	Start string `arg:"pos:2"`
+	Stop  string `arg:"pos:3"`
+
+	Limit *struct {
+		Offset int `arg:"pos:1"`
+		Count  int `arg:"pos:2"`
+	} `arg:"opt,name:LIMIT"`
+	WithScores bool `arg:",name:withscores"`
+}
+
+type hsetStruct struct {
+	Key   string `arg:"pos:1"`
+	Pairs []struct {
+		Field string `arg:"pos:1"`
+		Value string `arg:"pos:2"`
+	} `arg:"pos:2,variadic"`
+}
+
+func Test_ParseNamedArguments(t *testing.T) {
+	c, err := Parse[zrangeStruct]([]string{"ZRANGE", "key", "0", "-1", "WithScores"})
+
+	expectNoError(t, err)
+	expectEqual(t, true, c.WithScores)
+	expectEqual(t, true, c.Limit == nil)
+
+	type setName struct {
+		Condition struct {
+			IfNotExists bool `arg:",name:NX"`
+			IfExists    bool `arg:",name:XX"`
+		} `arg:"enum"`
+	}
+	c2, err2 := Parse[setName]([]string{"SET", "xx"})
+
+	expectNoError(t, err2)
+	expectEqual(t, true, c2.Condition.IfExists)
+	expectEqual(t, false, c2.Condition.IfNotExists)
+}
+
+func Test_ParseGroupOption(t *testing.T) {
+	c, err := Parse[zrangeStruct]([]string{"ZRANGE", "key", "0", "-1", "LIMIT", "5", "10", "WITHSCORES"})
+
+	expectNoError(t, err)
+	if c.Limit == nil {
+		t.Fatal("expect LIMIT to be set")
+	}
+	expectEqual(t, 5, c.Limit.Offset)
+	expectEqual(t, 10, c.Limit.Count)
+	expectEqual(t, true, c.WithScores)
+
+	if _, err := Parse[zrangeStruct]([]string{"ZRANGE", "key", "0", "-1", "LIMIT", "5"}); !errors.Is(err, ErrSyntax) {
+		t.Error("expect a syntax error for an incomplete group but got:", err)
+	}
+	if _, err := Parse[zrangeStruct]([]string{"ZRANGE", "key", "0", "-1", "LIMIT", "a", "10"}); !errors.Is(err, ErrNotInteger) {
+		t.Error("expect a not integer error but got:", err)
+	}
+}
+
+func Test_ParseRepeatedGroup(t *testing.T) {
+	c, err := Parse[hsetStruct]([]string{"HSET", "key", "f1", "v1", "f2", "v2"})
+
+	expectNoError(t, err)
+	if expectEqual(t, 2, len(c.Pairs)) {
+		expectEqual(t, "f1", c.Pairs[0].Field)
+		expectEqual(t, "v1", c.Pairs[0].Value)
+		expectEqual(t, "f2", c.Pairs[1].Field)
+		expectEqual(t, "v2", c.Pairs[1].Value)
+	}
+
+	if _, err := Parse[hsetStruct]([]string{"HSET", "key", "f1", "v1", "f2"}); !errors.Is(err, ErrWrongNumberOfArguments) {
+		t.Error("expect a wrong number of arguments error but got:", err)
+	}
+}
+
+func Test_ParseVariadicOption(t *testing.T) {
+	type clientList struct {
+		TYPE *string
+		ID   []int64 `arg:",variadic"`
+	}
+
+	c, err := Parse[clientList]([]string{"CLIENT|LIST", "TYPE", "normal", "ID", "3", "1"})
+	expectNoError(t, err)
+	expectNoNilEqual(t, "normal", c.TYPE)
+	expectEqualSlice(t, []int64{3, 1}, c.ID)
+
+	if _, err := Parse[clientList]([]string{"CLIENT|LIST", "ID"}); !errors.Is(err, ErrSyntax) {
+		t.Error("expect a syntax error for a variadic option without values but got:", err)
+	}
+	// the values go on until the last argument
+	if _, err := Parse[clientList]([]string{"CLIENT|LIST", "ID", "1", "TYPE", "normal"}); !errors.Is(err, ErrNotInteger) {
+		t.Error("expect a not integer error but got:", err)
+	}
+}
+
+func Test_ParseRepeatedOption(t *testing.T) {
+	type tracking struct {
+		Status string `arg:"pos:1"`
+		PREFIX []string
+		BCAST  bool
+	}
+
+	c, err := Parse[tracking]([]string{"TRACKING", "ON", "PREFIX", "a", "BCAST", "prefix", "b"})
+
+	expectNoError(t, err)
+	expectEqualSlice(t, []string{"a", "b"}, c.PREFIX)
+
+	if _, err := Parse[tracking]([]string{"TRACKING", "ON", "BCAST", "BCAST"}); !errors.Is(err, ErrSyntax) {
+		t.Error("expect a syntax error for a repeated flag but got:", err)
+	}
+}
+
+func Test_ParseEnumExclusive(t *testing.T) {
+	_, err := Parse[setStruct]([]string{"SET", "a", "a", "NX", "XX"})
+	if !errors.Is(err, ErrSyntax) {
+		t.Error("expect a syntax error but got:", err)
+	}
+
+	_, err = Parse[setStruct]([]string{"SET", "a", "a", "EX", "10", "KEEPTTL"})
+	if !errors.Is(err, ErrSyntax) {
+		t.Error("expect a syntax error but got:", err)
+	}
+}
+
+func Test_extractTagInvalidGroup(t *testing.T) {
+	type optionalMember struct {
+		Limit *struct {
+			Offset int  `arg:"pos:1"`
+			Count  *int `arg:"pos:2,optional"`
+		} `arg:"opt,name:LIMIT"`
+	}
+	if _, err := extractTag[optionalMember](); err == nil {
+		t.Error("expect a group with an optional member to be invalid")
+	}
+
+	type structOption struct {
+		Limit struct {
+			Offset int `arg:"pos:1"`
+		}
+	}
+	if _, err := extractTag[structOption](); err == nil {
+		t.Error("expect a group option not held by a pointer to be invalid")
+	}
+}
+
+func Test_extractTagInvalidAttribute(t *testing.T) {
+	for _, tag := range []string{
+		"pos:1,default",
+		"pos:1,display:a:b",
+		"opt,name",
+		"pos:1,unknown",
+	} {
+		if _, _, _, err := parseTag(tag); err == nil {
+			t.Errorf("expect tag %q to be invalid", tag)
+		}
+	}
+
+	type unknownAttribute struct {
+		Key string `arg:"pos:1,required"`
+	}
+	if err := Register(reflect.TypeFor[unknownAttribute]()); err == nil {
+		t.Error("expect an unknown attribute to fail the registration")
+	}
+}
@@ -0,0 +1,66 @@
+package argsparser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseInt parses an integer like Redis does: decimal digits only with an
+// optional `-` sign, no leading zeros, no spaces and within the bit size
+func parseInt(raw string, bitSize int) (int64, error) {
+	if !isDecimalInteger(strings.TrimPrefix(raw, "-")) || raw == "-0" {
+		return 0, fmt.Errorf("%w: invalid integer `%s`", ErrNotInteger, raw)
+	}
+	v, err := strconv.ParseInt(raw, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrNotInteger, err)
+	}
+	return v, nil
+}
+
+// parseUint parses an unsigned integer with the rules of parseInt, without
+// sign at all
+func parseUint(raw string, bitSize int) (uint64, error) {
+	if !isDecimalInteger(raw) {
+		return 0, fmt.Errorf("%w: invalid integer `%s`", ErrNotInteger, raw)
+	}
+	v, err := strconv.ParseUint(raw, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrNotInteger, err)
+	}
+	return v, nil
+}
+
+// isDecimalInteger reports whether digits is `0` or decimal digits not
+// starting with `0`
+func isDecimalInteger(digits string) bool {
+	if digits == "" || (digits[0] == '0' && len(digits) > 1) {
+		return false
+	}
+	for idx := range len(digits) {
+		if digits[idx] < '0' || digits[idx] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFloat parses a float like Redis does: a decimal number with an optional
+// sign and exponent, or `inf` and `-inf`. NaN, hexadecimal floats, spaces and
+// values out of the range of the bit size are refused.
+func parseFloat(raw string, bitSize int) (float64, error) {
+	lower := strings.ToLower(raw)
+	if strings.ContainsAny(lower, "_x") || strings.Contains(lower, "nan") {
+		return 0, fmt.Errorf("%w: invalid float `%s`", ErrNotFloat, raw)
+	}
+	v, err := strconv.ParseFloat(raw, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrNotFloat, err)
+	}
+	if math.IsNaN(v) {
+		return 0, fmt.Errorf("%w: invalid float `%s`", ErrNotFloat, raw)
+	}
+	return v, nil
+}
@@ -0,0 +1,175 @@
+package argsparser
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func Test_parseInt(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		bitSize  int
+		expected int64
+		valid    bool
+	}{
+		{"0", 64, 0, true},
+		{"5", 64, 5, true},
+		{"-5", 64, -5, true},
+		{"1234567890", 64, 1234567890, true},
+		{"9223372036854775807", 64, math.MaxInt64, true},
+		{"-9223372036854775808", 64, math.MinInt64, true},
+		{"9223372036854775808", 64, 0, false},
+		{"-9223372036854775809", 64, 0, false},
+		{"127", 8, 127, true},
+		{"-128", 8, -128, true},
+		{"128", 8, 0, false},
+		{"-129", 8, 0, false},
+		{"32767", 16, 32767, true},
+		{"32768", 16, 0, false},
+		{"2147483647", 32, math.MaxInt32, true},
+		{"2147483648", 32, 0, false},
+		{"", 64, 0, false},
+		{"-", 64, 0, false},
+		{"+5", 64, 0, false},
+		{"-0", 64, 0, false},
+		{"007", 64, 0, false},
+		{"-07", 64, 0, false},
+		{"0x10", 64, 0, false},
+		{"1_000", 64, 0, false},
+		{"1e3", 64, 0, false},
+		{"1.0", 64, 0, false},
+		{" 5", 64, 0, false},
+		{"5 ", 64, 0, false},
+		{"--5", 64, 0, false},
+		{"five", 64, 0, false},
+	} {
+		v, err := parseInt(c.raw, c.bitSize)
+		if !c.valid {
+			if !errors.Is(err, ErrNotInteger) {
+				t.Errorf("expect %q to be invalid for %d bits, got %d", c.raw, c.bitSize, v)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			expectEqual(t, c.expected, v)
+		}
+	}
+}
+
+func Test_parseUint(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		bitSize  int
+		expected uint64
+		valid    bool
+	}{
+		{"0", 64, 0, true},
+		{"42", 64, 42, true},
+		{"18446744073709551615", 64, math.MaxUint64, true},
+		{"18446744073709551616", 64, 0, false},
+		{"255", 8, 255, true},
+		{"256", 8, 0, false},
+		{"65535", 16, 65535, true},
+		{"65536", 16, 0, false},
+		{"-1", 64, 0, false},
+		{"-0", 64, 0, false},
+		{"+1", 64, 0, false},
+		{"01", 64, 0, false},
+		{"0x1", 64, 0, false},
+		{"", 64, 0, false},
+	} {
+		v, err := parseUint(c.raw, c.bitSize)
+		if !c.valid {
+			if !errors.Is(err, ErrNotInteger) {
+				t.Errorf("expect %q to be invalid for %d bits, got %d", c.raw, c.bitSize, v)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			expectEqual(t, c.expected, v)
+		}
+	}
+}
+
+func Test_parseFloat(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		bitSize  int
+		expected float64
+		valid    bool
+	}{
+		{"0", 64, 0, true},
+		{"1.5", 64, 1.5, true},
+		{"-1.5", 64, -1.5, true},
+		{"+1.5", 64, 1.5, true},
+		{".5", 64, 0.5, true},
+		{"5.", 64, 5, true},
+		{"007", 64, 7, true},
+		{"1e3", 64, 1000, true},
+		{"1E-3", 64, 0.001, true},
+		{"inf", 64, math.Inf(1), true},
+		{"+inf", 64, math.Inf(1), true},
+		{"-inf", 64, math.Inf(-1), true},
+		{"-INF", 64, math.Inf(-1), true},
+		{"infinity", 64, math.Inf(1), true},
+		{"1.7976931348623157e308", 64, math.MaxFloat64, true},
+		{"1e309", 64, 0, false},
+		{"-1e309", 64, 0, false},
+		{"3.4e38", 32, 3.4e38, true},
+		{"3.5e38", 32, 0, false},
+		{"nan", 64, 0, false},
+		{"NaN", 64, 0, false},
+		{"-nan", 64, 0, false},
+		{"0x10", 64, 0, false},
+		{"0x1p4", 64, 0, false},
+		{"1_000", 64, 0, false},
+		{"", 64, 0, false},
+		{" 1", 64, 0, false},
+		{"1 ", 64, 0, false},
+		{"1.5.5", 64, 0, false},
+		{"(1", 64, 0, false},
+		{"one", 64, 0, false},
+	} {
+		v, err := parseFloat(c.raw, c.bitSize)
+		if !c.valid {
+			if !errors.Is(err, ErrNotFloat) {
+				t.Errorf("expect %q to be invalid for %d bits, got %f", c.raw, c.bitSize, v)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			if c.bitSize == 32 {
+				v = float64(float32(v))
+				c.expected = float64(float32(c.expected))
+			}
+			expectEqual(t, c.expected, v)
+		}
+	}
+}
+
+func Test_ParseNumberKinds(t *testing.T) {
+	type numbers struct {
+		Small    int8    `arg:"pos:1"`
+		Unsigned uint16  `arg:"pos:2"`
+		Ratio    float32 `arg:"pos:3"`
+	}
+
+	c, err := Parse[numbers]([]string{"NUMBERS", "-12", "65535", "0.25"})
+
+	expectNoError(t, err)
+	expectEqual(t, numbers{Small: -12, Unsigned: 65535, Ratio: 0.25}, c)
+
+	for _, args := range [][]string{
+		{"NUMBERS", "128", "1", "1"},
+		{"NUMBERS", "1", "65536", "1"},
+		{"NUMBERS", "+1", "1", "1"},
+	} {
+		if _, err := Parse[numbers](args); !errors.Is(err, ErrNotInteger) {
+			t.Errorf("expect %v to be out of range, got %v", args, err)
+		}
+	}
+	if _, err := Parse[numbers]([]string{"NUMBERS", "1", "1", "nan"}); !errors.Is(err, ErrNotFloat) {
+		t.Error("expect NaN to be invalid, got:", err)
+	}
+}
@@ -0,0 +1,60 @@
+package argsparser
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// registry holds the metadata of the structs given to Parse. The structs
+// registered at init are only read afterwards, the other ones are added on
+// their first use by any connection.
+type registry struct {
+	mutex    sync.RWMutex
+	metadata map[reflect.Type]structMetadata
+}
+
+var parsed = &registry{metadata: map[reflect.Type]structMetadata{}}
+
+func (r *registry) get(t reflect.Type) (structMetadata, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	smd, exists := r.metadata[t]
+	return smd, exists
+}
+
+// put stores the metadata of t unless another goroutine did it first, and
+// returns the stored one
+func (r *registry) put(t reflect.Type, smd structMetadata) structMetadata {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if existing, exists := r.metadata[t]; exists {
+		return existing
+	}
+	r.metadata[t] = smd
+	return smd
+}
+
+// Register extracts and validates the metadata of structs given to Parse
+// ahead of their first use, so mistakes in their tags are found at startup
+func Register(types ...reflect.Type) error {
+	for _, t := range types {
+		if _, err := metadataOf(t); err != nil {
+			return fmt.Errorf("register %s failed: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func metadataOf(t reflect.Type) (structMetadata, error) {
+	if smd, exists := parsed.get(t); exists {
+		return smd, nil
+	}
+	// the lock is not held while extracting since the metadata of groups and
+	// subcommands is extracted recursively
+	smd, err := extractMetadata(t)
+	if err != nil {
+		return structMetadata{}, err
+	}
+	return parsed.put(t, smd), nil
+}
@@ -0,0 +1,122 @@
+package argsparser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func Test_Register(t *testing.T) {
+	type broken struct {
+		Key bool `arg:"pos:1"`
+	}
+
+	expectNoError(t, Register(reflect.TypeFor[setStruct](), reflect.TypeFor[zrangeStruct]()))
+	if _, exists := parsed.get(reflect.TypeFor[setStruct]()); !exists {
+		t.Error("expect setStruct to be registered")
+	}
+
+	if err := Register(reflect.TypeFor[broken]()); err == nil {
+		t.Error("expect a boolean position to fail the registration")
+	}
+	if _, exists := parsed.get(reflect.TypeFor[broken]()); exists {
+		t.Error("expect a broken struct not to be registered")
+	}
+}
+
+func Test_ParseConcurrently(t *testing.T) {
+	type concurrent struct {
+		Key   string `arg:"pos:1"`
+		Value string `arg:"pos:2"`
+		EX    *int64
+	}
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := Parse[concurrent]([]string{"SET", "key", "value", "EX", strconv.Itoa(i)})
+			expectNoError(t, err)
+			expectNoNilEqual(t, int64(i), c.EX)
+		}()
+	}
+	wg.Wait()
+}
+
+// setByName sets a field the way the parser did before the setters were
+// compiled, looking up the field and switching on its kind for each argument
+func setByName(value reflect.Value, fieldName, raw string) error {
+	field := value.FieldByName(fieldName)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := parseInt(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.String:
+		field.SetString(raw)
+	default:
+		return fmt.Errorf("invalid simple type %s", field.Kind())
+	}
+	return nil
+}
+
+func BenchmarkSetField(b *testing.B) {
+	type fields struct {
+		Key   string
+		Count int64
+	}
+	t := reflect.TypeFor[fields]()
+	keyField, _ := t.FieldByName("Key")
+	countField, _ := t.FieldByName("Count")
+
+	b.Run("reflection", func(b *testing.B) {
+		var v fields
+		value := reflect.ValueOf(&v).Elem()
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := setByName(value, "Key", "key"); err != nil {
+				b.Fatal(err)
+			}
+			if err := setByName(value, "Count", "42"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("setter", func(b *testing.B) {
+		key, err := newFieldSetter(keyField, keyField.Type)
+		if err != nil {
+			b.Fatal(err)
+		}
+		count, err := newFieldSetter(countField, countField.Type)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var v fields
+		value := reflect.ValueOf(&v).Elem()
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := setFieldValue(value, key, "key"); err != nil {
+				b.Fatal(err)
+			}
+			if err := setFieldValue(value, count, "42"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	args := []string{"SET", "key", "value", "GET", "NX", "EX", "10"}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := Parse[setStruct](args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package argsparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ArgumentType is the type of an argument, named like the argument types of
+// COMMAND DOCS
+type ArgumentType string
+
+const (
+	ArgumentTypeString    ArgumentType = "string"
+	ArgumentTypeInteger   ArgumentType = "integer"
+	ArgumentTypeDouble    ArgumentType = "double"
+	ArgumentTypePureToken ArgumentType = "pure-token"
+	ArgumentTypeOneOf     ArgumentType = "oneof"
+	ArgumentTypeBlock     ArgumentType = "block"
+)
+
+// Argument describes an argument of a command as declared by the struct tags
+type Argument struct {
+	Name string
+	Type ArgumentType
+	// keyword preceding the value, the keyword itself for pure tokens
+	Token    string
+	Optional bool
+	Multiple bool
+	// the keyword is repeated with each value, e.g. `[PREFIX prefix
+	// [PREFIX prefix ...]]` rather than `ID id [id ...]`
+	MultipleToken bool
+	// alternatives of a oneof argument, members of a block
+	Arguments []Argument
+}
+
+// Schema returns the arguments of a struct given to Parse: the position
+// arguments in order, then the options and enums in declaration order
+func Schema(t reflect.Type) ([]Argument, error) {
+	smd, err := metadataOf(t)
+	if err != nil {
+		return nil, err
+	}
+	return buildSchema(smd), nil
+}
+
+// Help returns the lines of the HELP subcommand of a container struct given to
+// Parse: the syntax of the command then the syntax of each subcommand
+func Help(t reflect.Type, command string) ([]string, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	smd, err := metadataOf(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(smd.subcommands) == 0 {
+		return nil, fmt.Errorf("%s has no subcommands", t)
+	}
+
+	lines := []string{fmt.Sprintf("%s <subcommand> [<arg> [value] [opt] ...]. Subcommands are:", strings.ToUpper(command))}
+	for _, name := range smd.subcommandNames {
+		sub := smd.subcommands[name]
+		if sub.raw {
+			lines = append(lines, name+" [<arg> ...]")
+			continue
+		}
+		field := t.Field(sub.index)
+		arguments, err := Schema(field.Type.Elem())
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, Syntax(name, arguments))
+	}
+	return lines, nil
+}
+
+func buildSchema(smd structMetadata) []Argument {
+	arguments := make([]Argument, 0, len(smd.positions)+len(smd.fieldOrder))
+	for _, pmd := range smd.positions {
+		argument := Argument{
+			Name:     valueName(pmd.fieldName, pmd.attribute),
+			Type:     argumentType(pmd.valueType),
+			Optional: pmd.attribute.isOptional,
+		}
+		if pmd.group != nil {
+			argument.Type, argument.Arguments = ArgumentTypeBlock, buildSchema(*pmd.group)
+		}
+		if pmd.attribute.isVariadic {
+			// the rest of the previous argument, e.g. `Key` and `KeyRest`, is
+			// documented as that argument repeated: `key [key ...]`
+			if last := len(arguments) - 1; last >= 0 && isRepeatedBy(arguments[last], argument) {
+				arguments[last].Multiple = true
+				continue
+			}
+			argument.Multiple = true
+		}
+		arguments = append(arguments, argument)
+	}
+
+	for _, fieldName := range smd.fieldOrder {
+		for _, omd := range smd.options {
+			if omd.fieldName != fieldName {
+				continue
+			}
+			argument := tokenArgument(omd.argName, omd.valueType, omd.attribute, true)
+			if omd.group != nil {
+				argument.Type, argument.Arguments = ArgumentTypeBlock, buildSchema(*omd.group)
+			}
+			// repeated options, e.g. `[PREFIX prefix [PREFIX prefix ...]]`,
+			// unless the values follow a single keyword
+			argument.Multiple = omd.kind == reflect.Slice
+			argument.MultipleToken = argument.Multiple && !omd.attribute.isVariadic
+			arguments = append(arguments, argument)
+		}
+		for _, emd := range smd.enums {
+			if emd.fieldName != fieldName {
+				continue
+			}
+			argument := Argument{
+				Name:     valueName(emd.fieldName, emd.attribute),
+				Type:     ArgumentTypeOneOf,
+				Optional: true,
+			}
+			for _, name := range emd.memberNames {
+				member := emd.enumMembers[name]
+				argument.Arguments = append(argument.Arguments, tokenArgument(member.argName, member.valueType, member.attribute, false))
+			}
+			arguments = append(arguments, argument)
+		}
+	}
+	return arguments
+}
+
+// isRepeatedBy reports whether rest holds the next values of argument
+func isRepeatedBy(argument, rest Argument) bool {
+	return !argument.Optional && !argument.Multiple && argument.Type != ArgumentTypeBlock &&
+		argument.Name == rest.Name && argument.Type == rest.Type
+}
+
+// tokenArgument describes an option or enum member, a keyword alone for
+// booleans or followed by a value
+func tokenArgument(token string, t reflect.Type, attribute attribute, optional bool) Argument {
+	argument := Argument{
+		Name:     valueName(token, attribute),
+		Type:     argumentType(t),
+		Token:    token,
+		Optional: optional,
+	}
+	if t.Kind() == reflect.Bool {
+		argument.Name, argument.Type = strings.ToLower(token), ArgumentTypePureToken
+	}
+	return argument
+}
+
+func argumentType(t reflect.Type) ArgumentType {
+	if isValueType(t) {
+		return reflect.New(t).Interface().(Value).ArgumentType()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ArgumentTypeInteger
+	case reflect.Float32, reflect.Float64:
+		return ArgumentTypeDouble
+	case reflect.Bool:
+		return ArgumentTypePureToken
+	default:
+		return ArgumentTypeString
+	}
+}
+
+// valueName is the display attribute, or the field name in kebab case with
+// the `Rest` of the repeated arguments removed (e.g. `KeyRest` is `key`)
+func valueName(fieldName string, attribute attribute) string {
+	if attribute.display != "" {
+		return attribute.display
+	}
+	return kebabCase(strings.TrimSuffix(fieldName, "Rest"))
+}
+
+func kebabCase(name string) string {
+	var sb strings.Builder
+	runes := []rune(name)
+	for idx, r := range runes {
+		// a word starts at an upper case letter following a lower case one,
+		// or preceding one for acronyms (e.g. `TTLValue` is `ttl-value`)
+		if idx > 0 && unicode.IsUpper(r) && (!unicode.IsUpper(runes[idx-1]) || (idx+1 < len(runes) && unicode.IsLower(runes[idx+1]))) {
+			sb.WriteByte('-')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
+
+// Syntax returns the syntax of a command like the Redis documentation shows
+// it, e.g. `SET key value [NX | XX]`
+func Syntax(command string, arguments []Argument) string {
+	parts := []string{strings.ToUpper(command)}
+	for _, argument := range arguments {
+		parts = append(parts, argumentSyntax(argument))
+	}
+	return strings.Join(parts, " ")
+}
+
+func argumentSyntax(argument Argument) string {
+	var syntax string
+	token := argument.Token
+	switch argument.Type {
+	case ArgumentTypePureToken:
+		syntax, token = argument.Token, ""
+	case ArgumentTypeOneOf:
+		alternatives := make([]string, 0, len(argument.Arguments))
+		for _, alternative := range argument.Arguments {
+			alternatives = append(alternatives, argumentSyntax(alternative))
+		}
+		syntax = strings.Join(alternatives, " | ")
+		if !argument.Optional {
+			syntax = "<" + syntax + ">"
+		}
+	case ArgumentTypeBlock:
+		members := make([]string, 0, len(argument.Arguments))
+		for _, member := range argument.Arguments {
+			members = append(members, argumentSyntax(member))
+		}
+		syntax = strings.Join(members, " ")
+	default:
+		syntax = argument.Name
+	}
+
+	if argument.Multiple && !argument.MultipleToken {
+		syntax += " [" + syntax + " ...]"
+	}
+	if token != "" {
+		syntax = token + " " + syntax
+	}
+	if argument.MultipleToken {
+		syntax += " [" + syntax + " ...]"
+	}
+	if argument.Optional {
+		syntax = "[" + syntax + "]"
+	}
+	return syntax
+}
@@ -0,0 +1,115 @@
+package argsparser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type scanStruct struct {
+	Cursor string `arg:"pos:1"`
+
+	MATCH *string `arg:",display:pattern"`
+	COUNT *int
+	Mode  struct {
+		Key   string `arg:"enum-key"`
+		ASYNC bool
+		SYNC  bool
+	} `arg:"enum"`
+}
+
+func Test_Schema(t *testing.T) {
+	arguments, err := Schema(reflect.TypeFor[scanStruct]())
+	expectNoError(t, err)
+	if !expectEqual(t, 4, len(arguments)) {
+		return
+	}
+	expectEqual(t, "cursor", arguments[0].Name)
+	expectEqual(t, ArgumentTypeString, arguments[0].Type)
+	expectEqual(t, false, arguments[0].Optional)
+
+	expectEqual(t, "pattern", arguments[1].Name)
+	expectEqual(t, "MATCH", arguments[1].Token)
+	expectEqual(t, true, arguments[1].Optional)
+	expectEqual(t, ArgumentTypeInteger, arguments[2].Type)
+
+	expectEqual(t, ArgumentTypeOneOf, arguments[3].Type)
+	expectEqual(t, "mode", arguments[3].Name)
+	if expectEqual(t, 2, len(arguments[3].Arguments)) {
+		expectEqual(t, ArgumentTypePureToken, arguments[3].Arguments[0].Type)
+		expectEqual(t, "ASYNC", arguments[3].Arguments[0].Token)
+	}
+}
+
+func Test_Syntax(t *testing.T) {
+	for _, c := range []struct {
+		t        reflect.Type
+		command  string
+		expected string
+	}{
+		{reflect.TypeFor[setStruct](), "set", "SET key value [GET] [NX | XX] [EX ex | PX px | EXAT exat | PXAT pxat | KEEPTTL]"},
+		{reflect.TypeFor[scanStruct](), "scan", "SCAN cursor [MATCH pattern] [COUNT count] [ASYNC | SYNC]"},
+		{reflect.TypeFor[struct {
+			Key     string   `arg:"pos:1"`
+			KeyRest []string `arg:"pos:2,variadic"`
+			Timeout float64  `arg:"pos:3"`
+		}](), "blpop", "BLPOP key [key ...] timeout"},
+		{reflect.TypeFor[struct {
+			Message string `arg:"pos:1,default:PONG,optional"`
+		}](), "ping", "PING [message]"},
+		{reflect.TypeFor[struct {
+			Section []string `arg:"pos:1,variadic,optional"`
+		}](), "info", "INFO [section [section ...]]"},
+		{reflect.TypeFor[zrangeStruct](), "zrange", "ZRANGE key start stop [LIMIT offset count] [WITHSCORES]"},
+		{reflect.TypeFor[hsetStruct](), "hset", "HSET key field value [field value ...]"},
+		{reflect.TypeFor[struct {
+			PREFIX []string
+		}](), "tracking", "TRACKING [PREFIX prefix [PREFIX prefix ...]]"},
+		{reflect.TypeFor[struct {
+			ID []int64 `arg:",variadic,display:client-id"`
+		}](), "client|list", "CLIENT|LIST [ID client-id [client-id ...]]"},
+	} {
+		arguments, err := Schema(c.t)
+		expectNoError(t, err)
+		expectEqual(t, c.expected, Syntax(c.command, arguments))
+	}
+}
+
+func Test_ParseErrorUsage(t *testing.T) {
+	_, err := Parse[scanStruct]([]string{"SCAN", "0", "LIMIT", "10"})
+	if !errors.Is(err, ErrSyntax) {
+		t.Fatal("expect a syntax error, got:", err)
+	}
+	if !strings.HasSuffix(err.Error(), "usage: SCAN cursor [MATCH pattern] [COUNT count] [ASYNC | SYNC]") {
+		t.Error("expect the usage in the error, got:", err)
+	}
+}
+
+func Test_kebabCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Key":             "key",
+		"UnixTimeSeconds": "unix-time-seconds",
+		"COUNT":           "count",
+		"TTLValue":        "ttl-value",
+		"Index1":          "index1",
+	} {
+		expectEqual(t, expected, kebabCase(name))
+	}
+}
+
+func Test_Help(t *testing.T) {
+	lines, err := Help(reflect.TypeFor[object](), "object")
+
+	expectNoError(t, err)
+	expectEqualSlice(t, []string{
+		"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+		"ENCODING key",
+		"NO-EVICT enabled",
+		"LIST [<arg> ...]",
+	}, lines)
+
+	if _, err := Help(reflect.TypeFor[objectEncoding](), "object"); err == nil {
+		t.Error("expect a struct without subcommands to have no help")
+	}
+}
@@ -0,0 +1,104 @@
+package argsparser
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// setter sets a value from a raw argument. It is chosen once from the type of
+// the value when the metadata is extracted, instead of switching on the kind
+// of the value for each argument.
+type setter func(value reflect.Value, raw string) error
+
+// fieldSetter is a field of a struct set from the arguments
+type fieldSetter struct {
+	fieldName string
+	index     int
+	// setter of the field, of its elements for slices, nil for groups
+	set setter
+}
+
+func newFieldSetter(field reflect.StructField, t reflect.Type) (fieldSetter, error) {
+	f := fieldSetter{
+		fieldName: field.Name,
+		index:     field.Index[0],
+	}
+	if t == nil {
+		return f, nil
+	}
+	set, err := newSetter(t)
+	if err != nil {
+		return fieldSetter{}, err
+	}
+	f.set = set
+	return f, nil
+}
+
+func newSetter(t reflect.Type) (setter, error) {
+	if isValueType(t) {
+		return func(value reflect.Value, raw string) error {
+			return value.Addr().Interface().(Value).Set(raw)
+		}, nil
+	}
+
+	switch kind := t.Kind(); kind {
+	case reflect.Pointer:
+		elemType := t.Elem()
+		set, err := newSetter(elemType)
+		if err != nil {
+			return nil, err
+		}
+		return func(value reflect.Value, raw string) error {
+			ptr := reflect.New(elemType)
+			if err := set(ptr.Elem(), raw); err != nil {
+				return err
+			}
+			value.Set(ptr)
+			return nil
+		}, nil
+	case reflect.Bool:
+		// bool is always set to true without checking raw
+		// since to get here, the key must be present on the arg
+		return func(value reflect.Value, _ string) error {
+			value.SetBool(true)
+			return nil
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bitSize := t.Bits()
+		return func(value reflect.Value, raw string) error {
+			v, err := parseInt(raw, bitSize)
+			if err != nil {
+				return err
+			}
+			value.SetInt(v)
+			return nil
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		bitSize := t.Bits()
+		return func(value reflect.Value, raw string) error {
+			v, err := parseUint(raw, bitSize)
+			if err != nil {
+				return err
+			}
+			value.SetUint(v)
+			return nil
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		bitSize := t.Bits()
+		return func(value reflect.Value, raw string) error {
+			v, err := parseFloat(raw, bitSize)
+			if err != nil {
+				return err
+			}
+			value.SetFloat(v)
+			return nil
+		}, nil
+	case reflect.String:
+		return func(value reflect.Value, raw string) error {
+			value.SetString(raw)
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid simple type %s", kind)
+	}
+}
@@ -0,0 +1,136 @@
+package argsparser
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Value is implemented by the types of arguments parsed with their own rules
+// instead of the ones of their kind, e.g. the bounds of ranges
+type Value interface {
+	// Set parses the raw argument into the value
+	Set(raw string) error
+	// ArgumentType is the type of the argument in the schema
+	ArgumentType() ArgumentType
+}
+
+var valueType = reflect.TypeFor[Value]()
+
+// isValueType reports whether the pointers of t implement Value
+func isValueType(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(valueType)
+}
+
+// ScoreBound is a bound of a range of sorted set scores: a float, `inf` or
+// `-inf`, excluded from the range when prefixed by `(`
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+func (b *ScoreBound) Set(raw string) error {
+	value, exclusive := strings.CutPrefix(raw, "(")
+	score, err := parseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid bound `%s`", ErrNotScoreRange, raw)
+	}
+	b.Score, b.Exclusive = score, exclusive
+	return nil
+}
+
+func (b *ScoreBound) ArgumentType() ArgumentType {
+	return ArgumentTypeDouble
+}
+
+// MinAccepts reports whether score is in a range starting at the bound
+func (b ScoreBound) MinAccepts(score float64) bool {
+	if b.Exclusive {
+		return score > b.Score
+	}
+	return score >= b.Score
+}
+
+// MaxAccepts reports whether score is in a range ending at the bound
+func (b ScoreBound) MaxAccepts(score float64) bool {
+	if b.Exclusive {
+		return score < b.Score
+	}
+	return score <= b.Score
+}
+
+// LexBound is a bound of a lexicographical range of sorted set members: a
+// value prefixed by `[` when included or `(` when excluded, or `-` and `+`
+// for the smallest and the greatest strings
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	// -1 for `-` and 1 for `+`, Value is not set for them
+	Infinite int
+}
+
+func (b *LexBound) Set(raw string) error {
+	switch {
+	case raw == "-":
+		*b = LexBound{Infinite: -1}
+	case raw == "+":
+		*b = LexBound{Infinite: 1}
+	case strings.HasPrefix(raw, "["):
+		*b = LexBound{Value: raw[1:]}
+	case strings.HasPrefix(raw, "("):
+		*b = LexBound{Value: raw[1:], Exclusive: true}
+	default:
+		return fmt.Errorf("%w: invalid bound `%s`", ErrNotLexRange, raw)
+	}
+	return nil
+}
+
+func (b *LexBound) ArgumentType() ArgumentType {
+	return ArgumentTypeString
+}
+
+// MinAccepts reports whether member is in a range starting at the bound
+func (b LexBound) MinAccepts(member string) bool {
+	switch {
+	case b.Infinite != 0:
+		return b.Infinite < 0
+	case b.Exclusive:
+		return member > b.Value
+	default:
+		return member >= b.Value
+	}
+}
+
+// MaxAccepts reports whether member is in a range ending at the bound
+func (b LexBound) MaxAccepts(member string) bool {
+	switch {
+	case b.Infinite != 0:
+		return b.Infinite > 0
+	case b.Exclusive:
+		return member < b.Value
+	default:
+		return member <= b.Value
+	}
+}
+
+// Timeout is the timeout of blocking commands given in seconds as a float, 0
+// blocks forever
+type Timeout time.Duration
+
+func (t *Timeout) Set(raw string) error {
+	seconds, err := parseFloat(raw, 64)
+	if err != nil || math.IsInf(seconds, 0) || seconds*float64(time.Second) >= math.MaxInt64 {
+		return fmt.Errorf("%w: invalid timeout `%s`", ErrNotTimeout, raw)
+	}
+	if seconds < 0 {
+		return ErrNegativeTimeout
+	}
+	*t = Timeout(seconds * float64(time.Second))
+	return nil
+}
+
+func (t *Timeout) ArgumentType() ArgumentType {
+	return ArgumentTypeDouble
+}
@@ -0,0 +1,170 @@
+package argsparser
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_ScoreBound(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		expected ScoreBound
+		valid    bool
+	}{
+		{"1", ScoreBound{Score: 1}, true},
+		{"-1.5", ScoreBound{Score: -1.5}, true},
+		{"(1", ScoreBound{Score: 1, Exclusive: true}, true},
+		{"(-2e2", ScoreBound{Score: -200, Exclusive: true}, true},
+		{"inf", ScoreBound{Score: math.Inf(1)}, true},
+		{"+inf", ScoreBound{Score: math.Inf(1)}, true},
+		{"-inf", ScoreBound{Score: math.Inf(-1)}, true},
+		{"(-inf", ScoreBound{Score: math.Inf(-1), Exclusive: true}, true},
+		{"", ScoreBound{}, false},
+		{"(", ScoreBound{}, false},
+		{"((1", ScoreBound{}, false},
+		{"[1", ScoreBound{}, false},
+		{"nan", ScoreBound{}, false},
+		{"(nan", ScoreBound{}, false},
+		{"1)", ScoreBound{}, false},
+		{"one", ScoreBound{}, false},
+	} {
+		var bound ScoreBound
+		err := bound.Set(c.raw)
+		if !c.valid {
+			if !errors.Is(err, ErrNotScoreRange) {
+				t.Errorf("expect %q to be an invalid score bound, got %+v", c.raw, bound)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			expectEqual(t, c.expected, bound)
+		}
+	}
+}
+
+func Test_ScoreBoundAccepts(t *testing.T) {
+	inclusive, exclusive := ScoreBound{Score: 1}, ScoreBound{Score: 1, Exclusive: true}
+
+	expectEqual(t, true, inclusive.MinAccepts(1))
+	expectEqual(t, false, exclusive.MinAccepts(1))
+	expectEqual(t, true, exclusive.MinAccepts(1.5))
+	expectEqual(t, true, inclusive.MaxAccepts(1))
+	expectEqual(t, false, exclusive.MaxAccepts(1))
+	expectEqual(t, true, exclusive.MaxAccepts(0.5))
+	expectEqual(t, true, ScoreBound{Score: math.Inf(-1)}.MinAccepts(math.Inf(-1)))
+	expectEqual(t, false, ScoreBound{Score: math.Inf(1), Exclusive: true}.MaxAccepts(math.Inf(1)))
+}
+
+func Test_LexBound(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		expected LexBound
+		valid    bool
+	}{
+		{"-", LexBound{Infinite: -1}, true},
+		{"+", LexBound{Infinite: 1}, true},
+		{"[a", LexBound{Value: "a"}, true},
+		{"(a", LexBound{Value: "a", Exclusive: true}, true},
+		{"[", LexBound{Value: ""}, true},
+		{"(", LexBound{Value: "", Exclusive: true}, true},
+		{"[-", LexBound{Value: "-"}, true},
+		{"((a", LexBound{Value: "(a", Exclusive: true}, true},
+		{"", LexBound{}, false},
+		{"a", LexBound{}, false},
+		{"--", LexBound{}, false},
+		{"+a", LexBound{}, false},
+		{"]a", LexBound{}, false},
+	} {
+		var bound LexBound
+		err := bound.Set(c.raw)
+		if !c.valid {
+			if !errors.Is(err, ErrNotLexRange) {
+				t.Errorf("expect %q to be an invalid lex bound, got %+v", c.raw, bound)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			expectEqual(t, c.expected, bound)
+		}
+	}
+}
+
+func Test_LexBoundAccepts(t *testing.T) {
+	lowest, highest := LexBound{Infinite: -1}, LexBound{Infinite: 1}
+	inclusive, exclusive := LexBound{Value: "b"}, LexBound{Value: "b", Exclusive: true}
+
+	expectEqual(t, true, lowest.MinAccepts(""))
+	expectEqual(t, false, lowest.MaxAccepts("z"))
+	expectEqual(t, true, highest.MaxAccepts("zzz"))
+	expectEqual(t, false, highest.MinAccepts("a"))
+	expectEqual(t, true, inclusive.MinAccepts("b"))
+	expectEqual(t, false, exclusive.MinAccepts("b"))
+	expectEqual(t, true, exclusive.MinAccepts("ba"))
+	expectEqual(t, true, inclusive.MaxAccepts("b"))
+	expectEqual(t, false, exclusive.MaxAccepts("b"))
+	expectEqual(t, true, exclusive.MaxAccepts("a"))
+}
+
+func Test_Timeout(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		expected time.Duration
+		err      error
+	}{
+		{"0", 0, nil},
+		{"1", time.Second, nil},
+		{"0.5", 500 * time.Millisecond, nil},
+		{"1e-3", time.Millisecond, nil},
+		{"-1", 0, ErrNegativeTimeout},
+		{"-0.1", 0, ErrNegativeTimeout},
+		{"inf", 0, ErrNotTimeout},
+		{"1e300", 0, ErrNotTimeout},
+		// 2^63 nanoseconds overflows a duration, float64(math.MaxInt64) is 2^63
+		{"9223372036.854775808", 0, ErrNotTimeout},
+		{"9223372036.854775", 9223372036854774784, nil},
+		{"nan", 0, ErrNotTimeout},
+		{"", 0, ErrNotTimeout},
+		{"1s", 0, ErrNotTimeout},
+	} {
+		var timeout Timeout
+		err := timeout.Set(c.raw)
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Errorf("expect %q to fail with %v, got %v", c.raw, c.err, err)
+			}
+			continue
+		}
+		if expectNoError(t, err) {
+			expectEqual(t, c.expected, time.Duration(timeout))
+		}
+	}
+}
+
+func Test_ParseValue(t *testing.T) {
+	type zrange struct {
+		Key     string     `arg:"pos:1"`
+		Min     ScoreBound `arg:"pos:2"`
+		Max     ScoreBound `arg:"pos:3"`
+		TIMEOUT *Timeout
+	}
+
+	c, err := Parse[zrange]([]string{"ZRANGE", "key", "(1", "+inf", "TIMEOUT", "2"})
+
+	expectNoError(t, err)
+	expectEqual(t, ScoreBound{Score: 1, Exclusive: true}, c.Min)
+	expectEqual(t, ScoreBound{Score: math.Inf(1)}, c.Max)
+	expectNoNilEqual(t, Timeout(2*time.Second), c.TIMEOUT)
+
+	if _, err := Parse[zrange]([]string{"ZRANGE", "key", "[1", "2"}); !errors.Is(err, ErrNotScoreRange) {
+		t.Error("expect an invalid score bound, got:", err)
+	}
+
+	arguments, err := Schema(reflect.TypeFor[zrange]())
+	expectNoError(t, err)
+	expectEqual(t, ArgumentTypeDouble, arguments[1].Type)
+	expectEqual(t, ArgumentTypeDouble, arguments[3].Type)
+	expectEqual(t, "ZRANGE key min max [TIMEOUT timeout]", Syntax("zrange", arguments))
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ClientOutputBufferLimit bounds the replies waiting to be sent to a client of
+// a class: it is disconnected as soon as they exceed Hard, or when they stay
+// over Soft for SoftSeconds. A limit of 0 is disabled.
+type ClientOutputBufferLimit struct {
+	Hard        int64
+	Soft        int64
+	SoftSeconds int64
+}
+
+// ordered like Redis formats them back
+var clientClasses = []string{"normal", "replica", "pubsub"}
+
+// parseClientOutputBufferLimits parses `<class> <hard> <soft> <soft seconds>`
+// groups, memory amounts can have a unit
+func parseClientOutputBufferLimits(raw string) (map[string]ClientOutputBufferLimit, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 || len(fields)%4 != 0 {
+		return nil, fmt.Errorf("Wrong number of arguments in buffer limit configuration.")
+	}
+
+	limits := map[string]ClientOutputBufferLimit{}
+	for idx := 0; idx < len(fields); idx += 4 {
+		class := strings.ToLower(fields[idx])
+		if class == "slave" {
+			class = "replica"
+		}
+		if !slices.Contains(clientClasses, class) {
+			return nil, fmt.Errorf("Invalid client class specified in buffer limit configuration.")
+		}
+
+		hard, hardErr := normalizeMemory(fields[idx+1])
+		soft, softErr := normalizeMemory(fields[idx+2])
+		softSeconds, softSecondsErr := strconv.ParseInt(fields[idx+3], 10, 64)
+		if hardErr != nil || softErr != nil || softSecondsErr != nil || softSeconds < 0 {
+			return nil, fmt.Errorf("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		limit := ClientOutputBufferLimit{SoftSeconds: softSeconds}
+		limit.Hard, _ = strconv.ParseInt(hard, 10, 64)
+		limit.Soft, _ = strconv.ParseInt(soft, 10, 64)
+		limits[class] = limit
+	}
+	return limits, nil
+}
+
+func formatClientOutputBufferLimits(limits map[string]ClientOutputBufferLimit) string {
+	var groups []string
+	for _, class := range clientClasses {
+		if limit, exists := limits[class]; exists {
+			groups = append(groups, fmt.Sprintf("%s %d %d %d", class, limit.Hard, limit.Soft, limit.SoftSeconds))
+		}
+	}
+	return strings.Join(groups, " ")
+}
+
+func normalizeClientOutputBufferLimit(raw string) (string, error) {
+	limits, err := parseClientOutputBufferLimits(raw)
+	if err != nil {
+		return "", err
+	}
+	return formatClientOutputBufferLimits(limits), nil
+}
+
+// updateClientOutputBufferLimit only replaces the classes that are set, like
+// `CONFIG SET client-output-buffer-limit pubsub 0 0 0`
+func updateClientOutputBufferLimit(current, value string) string {
+	// both values are already normalized
+	limits, _ := parseClientOutputBufferLimits(current)
+	updates, _ := parseClientOutputBufferLimits(value)
+	maps.Copy(limits, updates)
+	return formatClientOutputBufferLimits(limits)
+}
+
+// ClientOutputBufferLimit returns the limit of a class of clients
+func (c *Config) ClientOutputBufferLimit(class string) ClientOutputBufferLimit {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.clientOutputBufferLimits[class]
+}
+
+func clientOutputBufferLimitChanged(c *Config, value string) {
+	// the value is validated when set, the map is replaced and never changed
+	c.clientOutputBufferLimits, _ = parseClientOutputBufferLimits(value)
+}
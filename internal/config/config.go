@@ -0,0 +1,395 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/glob"
+)
+
+// Config holds the server parameters, they come from a redis.conf style file,
+// the command line and CONFIG SET. Values are stored in their canonical string
+// form (the one CONFIG GET replies with) and converted by the typed getters.
+type Config struct {
+	mutex  sync.RWMutex
+	values map[string]string
+
+	// path of the config file, empty when started without one
+	file string
+
+	// parsed values of the parameters read on every command, kept up to
+	// date by the changed hook of their param
+	keyspaceEvents           int
+	clientOutputBufferLimits map[string]ClientOutputBufferLimit
+	eviction                 Eviction
+	listMaxListpackSize      int64
+}
+
+type param struct {
+	name         string
+	defaultValue string
+	// immutable parameters can only be set at startup
+	mutable bool
+	// multiArg parameters take several arguments on their directive line
+	// (e.g. `save 3600 1 300 100`), others are a single (quoted) argument
+	multiArg bool
+	// normalize validates a raw value and returns its canonical form
+	normalize func(raw string) (string, error)
+	// update combines the current value with a normalized one, for the
+	// parameters that can be set partially, by default the value is replaced
+	update func(current, value string) string
+	// changed is called with the mutex held once the value is stored, to
+	// update what is derived from it
+	changed func(c *Config, value string)
+}
+
+var params = []param{
+	{name: "bind", defaultValue: "* -::*", multiArg: true, normalize: normalizeString},
+	{name: "port", defaultValue: "6379", normalize: normalizeInt(0, 65535)},
+	{name: "tls-port", defaultValue: "0", normalize: normalizeInt(0, 65535)},
+	{name: "tls-cert-file", defaultValue: "", normalize: normalizeString},
+	{name: "tls-key-file", defaultValue: "", normalize: normalizeString},
+	{name: "tls-ca-cert-file", defaultValue: "", normalize: normalizeString},
+	{name: "tls-auth-clients", defaultValue: "yes", normalize: normalizeEnum("yes", "no", "optional")},
+	{name: "unixsocket", defaultValue: "", normalize: normalizeString},
+	{name: "unixsocketperm", defaultValue: "0", normalize: normalizePermissions},
+	{name: "dir", defaultValue: ".", mutable: true, normalize: normalizeDir},
+	{name: "dbfilename", defaultValue: "dump.rdb", mutable: true, normalize: normalizeFilename},
+	{name: "save", defaultValue: "", mutable: true, multiArg: true, normalize: normalizeSave},
+	{name: "databases", defaultValue: "16", normalize: normalizeInt(1, math.MaxInt32)},
+	{name: "hz", defaultValue: "10", mutable: true, normalize: normalizeInt(1, 500)},
+	{name: "timeout", defaultValue: "0", mutable: true, normalize: normalizeInt(0, math.MaxInt32)},
+	{
+		name:         "client-output-buffer-limit",
+		defaultValue: "normal 0 0 0 replica 268435456 67108864 60 pubsub 33554432 8388608 60",
+		mutable:      true,
+		multiArg:     true,
+		normalize:    normalizeClientOutputBufferLimit,
+		update:       updateClientOutputBufferLimit,
+		changed:      clientOutputBufferLimitChanged,
+	},
+	{name: "requirepass", defaultValue: "", mutable: true, normalize: normalizeString},
+	{name: "aclfile", defaultValue: "", normalize: normalizeString},
+	{name: "acllog-max-len", defaultValue: "128", mutable: true, normalize: normalizeInt(0, math.MaxInt32)},
+	{name: "notify-keyspace-events", defaultValue: "", mutable: true, normalize: normalizeKeyspaceEvents, changed: keyspaceEventsChanged},
+	{
+		name:         "list-max-listpack-size",
+		defaultValue: "-2",
+		mutable:      true,
+		normalize:    normalizeInt(-5, math.MaxInt32),
+		changed:      listMaxListpackSizeChanged,
+	},
+	{
+		name:         "maxmemory",
+		defaultValue: "0",
+		mutable:      true,
+		normalize:    normalizeMemory,
+		changed:      evictionIntChanged(func(e *Eviction) *int64 { return &e.MaxMemory }),
+	},
+	{
+		name:         "maxmemory-policy",
+		defaultValue: "noeviction",
+		mutable:      true,
+		normalize: normalizeEnum(
+			"volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl",
+			"allkeys-lru", "allkeys-lfu", "allkeys-random", "noeviction",
+		),
+		changed: maxmemoryPolicyChanged,
+	},
+	{
+		name:         "maxmemory-samples",
+		defaultValue: "5",
+		mutable:      true,
+		normalize:    normalizeInt(1, 64),
+		changed:      evictionIntChanged(func(e *Eviction) *int64 { return &e.Samples }),
+	},
+	{
+		name:         "lfu-log-factor",
+		defaultValue: "10",
+		mutable:      true,
+		normalize:    normalizeInt(0, math.MaxInt32),
+		changed:      evictionIntChanged(func(e *Eviction) *int64 { return &e.LFULogFactor }),
+	},
+	{
+		name:         "lfu-decay-time",
+		defaultValue: "1",
+		mutable:      true,
+		normalize:    normalizeInt(0, math.MaxInt32),
+		changed:      evictionIntChanged(func(e *Eviction) *int64 { return &e.LFUDecayTime }),
+	},
+}
+
+func findParam(name string) (param, bool) {
+	name = strings.ToLower(name)
+	for _, p := range params {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return param{}, false
+}
+
+// New returns a config with every parameter set to its default
+func New() *Config {
+	c := &Config{values: make(map[string]string, len(params))}
+	for _, p := range params {
+		c.store(p, p.defaultValue)
+	}
+	return c
+}
+
+func (c *Config) Get(name string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	value, exists := c.values[strings.ToLower(name)]
+	return value, exists
+}
+
+// String returns the value of a parameter, it panics for unknown parameters
+// since they can only come from a programming mistake
+func (c *Config) String(name string) string {
+	value, exists := c.Get(name)
+	if !exists {
+		panic(fmt.Sprintf("unknown config parameter `%s`", name))
+	}
+	return value
+}
+
+// Strings returns the space separated arguments of a parameter
+func (c *Config) Strings(name string) []string {
+	return strings.Fields(c.String(name))
+}
+
+func (c *Config) Int(name string) int64 {
+	value, err := strconv.ParseInt(c.String(name), 10, 64)
+	if err != nil {
+		panic(fmt.Sprintf("config parameter `%s` is not an integer: %s", name, err))
+	}
+	return value
+}
+
+func (c *Config) Bool(name string) bool {
+	return c.String(name) == "yes"
+}
+
+// Match returns the parameters whose name matches pattern
+func (c *Config) Match(pattern string) map[string]string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	result := map[string]string{}
+	for _, p := range params {
+		if glob.MatchNoCase(pattern, p.name) {
+			result[p.name] = c.values[p.name]
+		}
+	}
+	return result
+}
+
+// Names returns the names of every parameter
+func Names() []string {
+	names := make([]string, 0, len(params))
+	for _, p := range params {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// Set changes parameters at runtime, either all of them are applied or none
+func (c *Config) Set(pairs [][2]string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	normalized := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, raw := strings.ToLower(pair[0]), pair[1]
+		p, exists := findParam(name)
+		if !exists {
+			return UnknownParamError{Name: pair[0]}
+		}
+		if _, duplicated := normalized[name]; duplicated {
+			return SetParamError{Name: name, Reason: "duplicate parameter"}
+		}
+		if !p.mutable {
+			return SetParamError{Name: name, Reason: "can't set immutable config"}
+		}
+		value, err := p.normalize(raw)
+		if err != nil {
+			return SetParamError{Name: name, Reason: err.Error()}
+		}
+		normalized[name] = value
+	}
+
+	for name, value := range normalized {
+		p, _ := findParam(name)
+		c.store(p, c.updated(name, value))
+	}
+	return nil
+}
+
+// apply sets a parameter while loading, immutable parameters are allowed
+func (c *Config) apply(name string, args []string) error {
+	p, exists := findParam(name)
+	if !exists {
+		return fmt.Errorf("Bad directive or wrong number of arguments")
+	}
+	if !p.multiArg && len(args) != 1 {
+		return fmt.Errorf("wrong number of arguments")
+	}
+	value, err := p.normalize(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	c.store(p, c.updated(p.name, value))
+	return nil
+}
+
+// store sets the value of a parameter, the mutex must be held
+func (c *Config) store(p param, value string) {
+	c.values[p.name] = value
+	if p.changed != nil {
+		p.changed(c, value)
+	}
+}
+
+// updated returns the value of a parameter once a normalized value is set
+func (c *Config) updated(name, value string) string {
+	p, _ := findParam(name)
+	if p.update == nil {
+		return value
+	}
+	return p.update(c.values[name], value)
+}
+
+// ListMaxListpackSize returns the limit of the lists kept in a listpack
+func (c *Config) ListMaxListpackSize() int64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.listMaxListpackSize
+}
+
+func listMaxListpackSizeChanged(c *Config, value string) {
+	// the value is validated when set
+	c.listMaxListpackSize, _ = strconv.ParseInt(value, 10, 64)
+}
+
+// File returns the path of the config file the server was started with
+func (c *Config) File() string {
+	return c.file
+}
+
+type UnknownParamError struct {
+	Name string
+}
+
+func (e UnknownParamError) Error() string {
+	return fmt.Sprintf("Unknown option or number of arguments for CONFIG SET - '%s'", e.Name)
+}
+
+type SetParamError struct {
+	Name   string
+	Reason string
+}
+
+func (e SetParamError) Error() string {
+	return fmt.Sprintf("CONFIG SET failed (possibly related to argument '%s') - %s", e.Name, e.Reason)
+}
+
+func normalizeString(raw string) (string, error) {
+	return raw, nil
+}
+
+func normalizeInt(minValue, maxValue int64) func(string) (string, error) {
+	return func(raw string) (string, error) {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		if value < minValue || value > maxValue {
+			return "", fmt.Errorf("argument must be between %d and %d inclusive", minValue, maxValue)
+		}
+		return strconv.FormatInt(value, 10), nil
+	}
+}
+
+func normalizeEnum(values ...string) func(string) (string, error) {
+	return func(raw string) (string, error) {
+		for _, value := range values {
+			if strings.EqualFold(raw, value) {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("argument(s) must be one of the following: %s", strings.Join(values, ", "))
+	}
+}
+
+// normalizePermissions validates octal file permissions, e.g. 700
+func normalizePermissions(raw string) (string, error) {
+	value, err := strconv.ParseUint(raw, 8, 32)
+	if err != nil || value > 0o777 {
+		return "", fmt.Errorf("argument must be octal file permissions")
+	}
+	return strconv.FormatUint(value, 8), nil
+}
+
+var memoryUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	// longest suffixes first
+	{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+	{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+// normalizeMemory parses a memory amount with an optional unit like `100mb`
+// and returns it in bytes
+func normalizeMemory(raw string) (string, error) {
+	number, multiplier := strings.ToLower(raw), int64(1)
+	for _, unit := range memoryUnits {
+		if trimmed, found := strings.CutSuffix(number, unit.suffix); found {
+			number, multiplier = trimmed, unit.multiplier
+			break
+		}
+	}
+	value, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || value < 0 || value > math.MaxInt64/multiplier {
+		return "", fmt.Errorf("argument must be a memory value")
+	}
+	return strconv.FormatInt(value*multiplier, 10), nil
+}
+
+func normalizeFilename(raw string) (string, error) {
+	if raw == "" || strings.ContainsAny(raw, "/\\") {
+		return "", fmt.Errorf("dbfilename can't be a path, just a filename")
+	}
+	return raw, nil
+}
+
+func normalizeDir(raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("dir can't be empty")
+	}
+	if err := checkDir(raw); err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// normalizeSave validates `<seconds> <changes>` pairs, an empty value disables
+// snapshots
+func normalizeSave(raw string) (string, error) {
+	fields := strings.Fields(raw)
+	if len(fields)%2 != 0 {
+		return "", fmt.Errorf("Invalid save parameters")
+	}
+	for _, field := range fields {
+		if value, err := strconv.ParseInt(field, 10, 64); err != nil || value < 0 {
+			return "", fmt.Errorf("Invalid save parameters")
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
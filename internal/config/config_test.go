@@ -0,0 +1,247 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_splitArgs(t *testing.T) {
+	cases := []struct {
+		line     string
+		expected []string
+	}{
+		{"port 6380", []string{"port", "6380"}},
+		{"  save   60 100  ", []string{"save", "60", "100"}},
+		{`dir "/tmp/with space"`, []string{"dir", "/tmp/with space"}},
+		{`save ""`, []string{"save", ""}},
+		{`key "a\"b\n\x41"`, []string{"key", "a\"b\nA"}},
+		{`key 'it\'s'`, []string{"key", "it's"}},
+	}
+	for _, c := range cases {
+		args, err := splitArgs(c.line)
+		if err != nil {
+			t.Errorf("split %q failed: %s", c.line, err)
+			continue
+		}
+		if !slices.Equal(c.expected, args) {
+			t.Errorf("split %q: expected %q, got %q", c.line, c.expected, args)
+		}
+	}
+
+	if _, err := splitArgs(`dir "/tmp`); err == nil {
+		t.Error("expect unbalanced quotes error")
+	}
+}
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "redis.conf")
+	content := "# comment\nport 7000\ndbfilename data.rdb\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load([]string{file, "--port", "7001", "--save", "60", "100", "--dir", dir})
+	if err != nil {
+		t.Fatal("load failed:", err)
+	}
+	if port := c.Int("port"); port != 7001 {
+		t.Errorf("expect flags to override the file, got port %d", port)
+	}
+	if name := c.String("dbfilename"); name != "data.rdb" {
+		t.Errorf("unexpected dbfilename %s", name)
+	}
+	if save := c.String("save"); save != "60 100" {
+		t.Errorf("unexpected save %s", save)
+	}
+
+	if _, err := Load([]string{"--unknown", "1"}); err == nil {
+		t.Error("expect error for unknown directive")
+	}
+	if _, err := Load([]string{"--port", "notanumber"}); err == nil {
+		t.Error("expect error for invalid value")
+	}
+}
+
+func Test_SetIsAtomic(t *testing.T) {
+	c := New()
+	err := c.Set([][2]string{{"dbfilename", "other.rdb"}, {"save", "1"}})
+	if err == nil {
+		t.Fatal("expect invalid save to fail")
+	}
+	if name := c.String("dbfilename"); name != "dump.rdb" {
+		t.Errorf("expect no parameter to be changed, got dbfilename %s", name)
+	}
+
+	if err := c.Set([][2]string{{"port", "1"}}); err == nil {
+		t.Error("expect immutable parameter to fail")
+	}
+}
+
+func Test_Match(t *testing.T) {
+	c := New()
+	matched := c.Match("d[ib]*")
+	if len(matched) != 2 || matched["dir"] != "." || matched["dbfilename"] != "dump.rdb" {
+		t.Errorf("unexpected match result %v", matched)
+	}
+}
+
+func Test_Rewrite(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "redis.conf")
+	content := "# keep me\nport 7000\n\ndbfilename a.rdb\ndbfilename b.rdb\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load([]string{file})
+	if err != nil {
+		t.Fatal("load failed:", err)
+	}
+	if err := c.Set([][2]string{{"dbfilename", "c.rdb"}, {"save", "60 100"}, {"dir", dir}}); err != nil {
+		t.Fatal("set failed:", err)
+	}
+	if err := c.Rewrite(); err != nil {
+		t.Fatal("rewrite failed:", err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"# keep me",
+		"port 7000",
+		"",
+		"dbfilename c.rdb",
+		"# Generated by CONFIG REWRITE",
+		"dir " + quoteArg(dir),
+		"save 60 100",
+		"",
+	}, "\n")
+	if string(data) != expected {
+		t.Errorf("unexpected rewritten file\nexpected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	if err := New().Rewrite(); err != ErrNoConfigFile {
+		t.Errorf("expect error without config file, got %v", err)
+	}
+}
+
+func Test_KeyspaceEvents(t *testing.T) {
+	cases := []struct {
+		raw      string
+		expected string
+	}{
+		{"", ""},
+		{"Ex", "xE"},
+		{"KEA", "AKE"},
+		{"g$lshzxetdKE", "AKE"},
+		{"Kml", "lKm"},
+	}
+	for _, c := range cases {
+		normalized, err := normalizeKeyspaceEvents(c.raw)
+		if err != nil {
+			t.Errorf("normalize %q failed: %s", c.raw, err)
+			continue
+		}
+		if normalized != c.expected {
+			t.Errorf("normalize %q: expected %q, got %q", c.raw, c.expected, normalized)
+		}
+	}
+
+	if _, err := normalizeKeyspaceEvents("KEq"); err == nil {
+		t.Error("expect invalid class error")
+	}
+
+	cfg, err := Load([]string{"--notify-keyspace-events", "Kl"})
+	if err != nil {
+		t.Fatal("load failed:", err)
+	}
+	if flags := cfg.KeyspaceEvents(); flags != NotifyKeyspace|NotifyList {
+		t.Errorf("expect the loaded classes, got %d", flags)
+	}
+	if err := cfg.Set([][2]string{{"notify-keyspace-events", "Ex"}}); err != nil {
+		t.Fatal("set failed:", err)
+	}
+	if flags := cfg.KeyspaceEvents(); flags != NotifyKeyevent|NotifyExpired {
+		t.Errorf("expect the classes to follow CONFIG SET, got %d", flags)
+	}
+	// a failed set leaves them unchanged
+	_ = cfg.Set([][2]string{{"notify-keyspace-events", "A"}, {"port", "1"}})
+	if flags := cfg.KeyspaceEvents(); flags != NotifyKeyevent|NotifyExpired {
+		t.Errorf("expect the classes to be unchanged, got %d", flags)
+	}
+}
+
+func Test_ClientOutputBufferLimit(t *testing.T) {
+	cfg := New()
+	expectLimit := func(class string, expected ClientOutputBufferLimit) {
+		t.Helper()
+		if limit := cfg.ClientOutputBufferLimit(class); limit != expected {
+			t.Errorf("%s limit: expected %+v, got %+v", class, expected, limit)
+		}
+	}
+	expectLimit("normal", ClientOutputBufferLimit{})
+	expectLimit("pubsub", ClientOutputBufferLimit{Hard: 32 << 20, Soft: 8 << 20, SoftSeconds: 60})
+
+	// the classes that are not set keep their limit
+	if err := cfg.Set([][2]string{{"client-output-buffer-limit", "SLAVE 1mb 512kb 10 pubsub 1gb 0 0"}}); err != nil {
+		t.Fatal("set failed:", err)
+	}
+	expectLimit("normal", ClientOutputBufferLimit{})
+	expectLimit("replica", ClientOutputBufferLimit{Hard: 1 << 20, Soft: 512 << 10, SoftSeconds: 10})
+	expectLimit("pubsub", ClientOutputBufferLimit{Hard: 1 << 30})
+	if value := cfg.String("client-output-buffer-limit"); value != "normal 0 0 0 replica 1048576 524288 10 pubsub 1073741824 0 0" {
+		t.Errorf("unexpected value %q", value)
+	}
+
+	for _, raw := range []string{"normal 0 0", "master 0 0 0", "pubsub x 0 0", "pubsub 0 0 -1"} {
+		if _, err := normalizeClientOutputBufferLimit(raw); err == nil {
+			t.Errorf("expect %q to be invalid", raw)
+		}
+	}
+}
+
+func Test_Eviction(t *testing.T) {
+	cfg, err := Load([]string{"--maxmemory", "1mb", "--maxmemory-policy", "allkeys-lru"})
+	if err != nil {
+		t.Fatal("load failed:", err)
+	}
+	expected := Eviction{MaxMemory: 1 << 20, Policy: "allkeys-lru", Samples: 5, LFULogFactor: 10, LFUDecayTime: 1}
+	if eviction := cfg.Eviction(); eviction != expected {
+		t.Errorf("expect %+v, got %+v", expected, eviction)
+	}
+	if cfg.Eviction().UsesLFU() {
+		t.Error("expect allkeys-lru not to use LFU")
+	}
+
+	if err := cfg.Set([][2]string{{"maxmemory-policy", "volatile-lfu"}, {"lfu-decay-time", "0"}}); err != nil {
+		t.Fatal("set failed:", err)
+	}
+	expected.Policy, expected.LFUDecayTime = "volatile-lfu", 0
+	if eviction := cfg.Eviction(); eviction != expected {
+		t.Errorf("expect the parameters to follow CONFIG SET, expected %+v, got %+v", expected, eviction)
+	}
+	if !cfg.Eviction().UsesLFU() {
+		t.Error("expect volatile-lfu to use LFU")
+	}
+}
+
+func Test_UnixSocketPerm(t *testing.T) {
+	c, err := Load([]string{"--unixsocketperm", "0770"})
+	if err != nil {
+		t.Fatal("load failed:", err)
+	}
+	if perm := c.String("unixsocketperm"); perm != "770" {
+		t.Errorf("expect 770, got %s", perm)
+	}
+	for _, raw := range []string{"800", "1777", "rwx"} {
+		if _, err := normalizePermissions(raw); err == nil {
+			t.Errorf("expect %q to be invalid", raw)
+		}
+	}
+}
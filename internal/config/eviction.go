@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Eviction holds the parameters of the eviction, which are read on every
+// command and on every access to a key
+type Eviction struct {
+	// MaxMemory is 0 when the memory is not limited
+	MaxMemory    int64
+	Policy       string
+	Samples      int64
+	LFULogFactor int64
+	LFUDecayTime int64
+}
+
+// UsesLFU tells whether the policy evicts the least frequently used keys
+func (e Eviction) UsesLFU() bool {
+	return strings.HasSuffix(e.Policy, "-lfu")
+}
+
+// Eviction returns the parameters of the eviction
+func (c *Config) Eviction() Eviction {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.eviction
+}
+
+func maxmemoryPolicyChanged(c *Config, value string) {
+	c.eviction.Policy = value
+}
+
+// evictionIntChanged returns the changed hook of an integer parameter of the
+// eviction stored in the field returned by field
+func evictionIntChanged(field func(e *Eviction) *int64) func(c *Config, value string) {
+	return func(c *Config, value string) {
+		// the value is validated when set
+		*field(&c.eviction), _ = strconv.ParseInt(value, 10, 64)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Classes of keyspace notifications, enabled by the letters of the
+// notify-keyspace-events parameter
+const (
+	NotifyKeyspace = 1 << iota // K
+	NotifyKeyevent             // E
+	NotifyGeneric              // g
+	NotifyString               // $
+	NotifyList                 // l
+	NotifySet                  // s
+	NotifyHash                 // h
+	NotifyZSet                 // z
+	NotifyExpired              // x
+	NotifyEvicted              // e
+	NotifyStream               // t
+	NotifyKeyMiss              // m
+	NotifyModule               // d
+	NotifyNew                  // n
+
+	// A is an alias for every class except key miss and new key
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash | NotifyZSet |
+		NotifyExpired | NotifyEvicted | NotifyStream | NotifyModule
+)
+
+// ordered like Redis formats them back
+var keyspaceEventLetters = []struct {
+	letter byte
+	flag   int
+}{
+	{'g', NotifyGeneric},
+	{'$', NotifyString},
+	{'l', NotifyList},
+	{'s', NotifySet},
+	{'h', NotifyHash},
+	{'z', NotifyZSet},
+	{'x', NotifyExpired},
+	{'e', NotifyEvicted},
+	{'t', NotifyStream},
+	{'d', NotifyModule},
+	{'K', NotifyKeyspace},
+	{'E', NotifyKeyevent},
+	{'m', NotifyKeyMiss},
+	{'n', NotifyNew},
+}
+
+func ParseKeyspaceEvents(raw string) (int, error) {
+	flags := 0
+	for i := range len(raw) {
+		if raw[i] == 'A' {
+			flags |= NotifyAll
+			continue
+		}
+		found := false
+		for _, l := range keyspaceEventLetters {
+			if l.letter == raw[i] {
+				flags |= l.flag
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("Invalid event class character. Use 'Ag$lshzxeKEtmdn'.")
+		}
+	}
+	return flags, nil
+}
+
+func FormatKeyspaceEvents(flags int) string {
+	var sb strings.Builder
+	if flags&NotifyAll == NotifyAll {
+		sb.WriteByte('A')
+		flags &^= NotifyAll
+	}
+	for _, l := range keyspaceEventLetters {
+		if flags&l.flag != 0 {
+			sb.WriteByte(l.letter)
+		}
+	}
+	return sb.String()
+}
+
+// KeyspaceEvents returns the enabled notification classes
+func (c *Config) KeyspaceEvents() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.keyspaceEvents
+}
+
+func keyspaceEventsChanged(c *Config, value string) {
+	// the value is validated when set
+	c.keyspaceEvents, _ = ParseKeyspaceEvents(value)
+}
+
+func normalizeKeyspaceEvents(raw string) (string, error) {
+	flags, err := ParseKeyspaceEvents(raw)
+	if err != nil {
+		return "", err
+	}
+	return FormatKeyspaceEvents(flags), nil
+}
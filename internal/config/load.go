@@ -0,0 +1,251 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Load builds the config from the command line arguments of the server, which
+// follow redis-server: an optional config file path then `--name value...`
+// directives that take precedence over the file.
+func Load(args []string) (*Config, error) {
+	c := New()
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		c.file = args[0]
+		args = args[1:]
+
+		content, err := os.ReadFile(c.file)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		directives, err := parseDirectives(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", c.file, err)
+		}
+		if err := c.applyDirectives(directives); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", c.file, err)
+		}
+	}
+
+	directives, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.applyDirectives(directives); err != nil {
+		return nil, fmt.Errorf("command line: %w", err)
+	}
+
+	return c, nil
+}
+
+type directive struct {
+	line int
+	name string
+	args []string
+}
+
+func (c *Config) applyDirectives(directives []directive) error {
+	for _, d := range directives {
+		if err := c.apply(d.name, d.args); err != nil {
+			return fmt.Errorf("line %d: '%s': %w", d.line, d.name, err)
+		}
+	}
+	return nil
+}
+
+// parseFlags turns `--port 6380 --save 60 100` into directives
+func parseFlags(args []string) ([]directive, error) {
+	var directives []directive
+	for idx, arg := range args {
+		if name, isFlag := strings.CutPrefix(arg, "--"); isFlag {
+			if name == "" {
+				return nil, fmt.Errorf("invalid empty flag at position %d", idx)
+			}
+			directives = append(directives, directive{line: idx + 1, name: strings.ToLower(name)})
+			continue
+		}
+		if len(directives) == 0 {
+			return nil, fmt.Errorf("value `%s` does not follow a flag", arg)
+		}
+		last := &directives[len(directives)-1]
+		last.args = append(last.args, arg)
+	}
+	return directives, nil
+}
+
+// parseDirectives parses the content of a redis.conf file, one directive per
+// line with `#` comments
+func parseDirectives(content string) ([]directive, error) {
+	var directives []directive
+	for idx, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", idx+1, err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+		directives = append(directives, directive{
+			line: idx + 1,
+			name: strings.ToLower(args[0]),
+			args: args[1:],
+		})
+	}
+	return directives, nil
+}
+
+// splitArgs splits a line into arguments like Redis does: arguments are
+// separated by spaces and can be "double quoted" with escapes or 'single quoted'
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	idx := 0
+	for {
+		for idx < len(line) && isSpace(line[idx]) {
+			idx++
+		}
+		if idx == len(line) {
+			return args, nil
+		}
+
+		var current strings.Builder
+		inDoubleQuotes, inSingleQuotes := false, false
+		done := false
+		for !done {
+			if idx == len(line) {
+				if inDoubleQuotes || inSingleQuotes {
+					return nil, fmt.Errorf("unbalanced quotes")
+				}
+				break
+			}
+			ch := line[idx]
+			switch {
+			case inDoubleQuotes:
+				switch {
+				case ch == '\\' && idx+3 < len(line) && line[idx+1] == 'x' && isHexDigit(line[idx+2]) && isHexDigit(line[idx+3]):
+					current.WriteByte(hexValue(line[idx+2])<<4 | hexValue(line[idx+3]))
+					idx += 3
+				case ch == '\\' && idx+1 < len(line):
+					idx++
+					current.WriteByte(unescape(line[idx]))
+				case ch == '"':
+					// the closing quote must be followed by a space or nothing
+					if idx+1 < len(line) && !isSpace(line[idx+1]) {
+						return nil, fmt.Errorf("unbalanced quotes")
+					}
+					done = true
+				default:
+					current.WriteByte(ch)
+				}
+			case inSingleQuotes:
+				switch {
+				case ch == '\\' && idx+1 < len(line) && line[idx+1] == '\'':
+					idx++
+					current.WriteByte('\'')
+				case ch == '\'':
+					if idx+1 < len(line) && !isSpace(line[idx+1]) {
+						return nil, fmt.Errorf("unbalanced quotes")
+					}
+					done = true
+				default:
+					current.WriteByte(ch)
+				}
+			default:
+				switch {
+				case isSpace(ch):
+					done = true
+				case ch == '"':
+					inDoubleQuotes = true
+				case ch == '\'':
+					inSingleQuotes = true
+				default:
+					current.WriteByte(ch)
+				}
+			}
+			idx++
+		}
+		args = append(args, current.String())
+	}
+}
+
+// quoteArg is the reverse of splitArgs for a single argument
+func quoteArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\r\n\"'\\") {
+		return arg
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := range len(arg) {
+		switch ch := arg[i]; ch {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if ch < 0x20 || ch >= 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, ch)
+			} else {
+				sb.WriteByte(ch)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func hexValue(ch byte) byte {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0'
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
+
+func unescape(ch byte) byte {
+	switch ch {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	default:
+		return ch
+	}
+}
+
+func checkDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("No such file or directory")
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("Not a directory")
+	}
+	return nil
+}
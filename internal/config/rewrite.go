@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNoConfigFile = errors.New("The server is running without a config file")
+
+// Rewrite updates the config file with the current values: lines of known
+// parameters are replaced in place, the comments and unknown lines are kept,
+// and parameters that differ from their default are appended at the end.
+func (c *Config) Rewrite() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.file == "" {
+		return ErrNoConfigFile
+	}
+
+	content, err := os.ReadFile(c.file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var lines []string
+	if len(content) != 0 {
+		lines = strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+
+	written := map[string]bool{}
+	output := make([]string, 0, len(lines))
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			output = append(output, line)
+			continue
+		}
+		args, err := splitArgs(trimmed)
+		if err != nil || len(args) == 0 {
+			output = append(output, line)
+			continue
+		}
+		p, known := findParam(args[0])
+		if !known {
+			output = append(output, line)
+			continue
+		}
+		// a parameter set on several lines only keeps the first one
+		if written[p.name] {
+			continue
+		}
+		written[p.name] = true
+		output = append(output, c.formatLine(p))
+	}
+
+	generated := false
+	for _, p := range params {
+		if written[p.name] || c.values[p.name] == p.defaultValue {
+			continue
+		}
+		if !generated {
+			output = append(output, "# Generated by CONFIG REWRITE")
+			generated = true
+		}
+		output = append(output, c.formatLine(p))
+	}
+
+	return writeFileAtomic(c.file, []byte(strings.Join(output, "\n")+"\n"))
+}
+
+func (c *Config) formatLine(p param) string {
+	value := c.values[p.name]
+	if p.multiArg && value != "" {
+		args := strings.Fields(value)
+		for idx, arg := range args {
+			args[idx] = quoteArg(arg)
+		}
+		return p.name + " " + strings.Join(args, " ")
+	}
+	return p.name + " " + quoteArg(value)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".redis-conf-")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	if _, err := tempFile.Write(data); err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		_ = tempFile.Chmod(info.Mode())
+	}
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+// Package dict is a hash table with incremental rehashing, modeled after the
+// dict of Redis. Unlike Go maps it supports cursor based scans that return
+// every element present for the whole scan, even when the table is resized
+// between two calls, and picking a random element.
+package dict
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+	"math/rand/v2"
+)
+
+const (
+	initialSize = 4
+	// the table shrinks once less than 1/minFill of its buckets are used
+	minFill = 8
+	// a rehash step gives up after visiting this many empty buckets per bucket
+	// to move, so a step has a bounded cost
+	emptyVisitsPerStep = 10
+)
+
+type entry[V any] struct {
+	key   string
+	value V
+	next  *entry[V]
+}
+
+type table[V any] struct {
+	buckets []*entry[V]
+	used    int
+}
+
+func (t *table[V]) mask() uint64 {
+	return uint64(len(t.buckets)) - 1
+}
+
+// Dict maps string keys to values of type V, it is not safe for concurrent use.
+//
+// Resizing allocates a second table and the entries move to it a bucket at a
+// time on each access, so no single operation pays for the whole rehash.
+type Dict[V any] struct {
+	seed   maphash.Seed
+	tables [2]table[V]
+	// next bucket of tables[0] to move to tables[1], -1 when not rehashing
+	rehashIdx int
+}
+
+func New[V any]() *Dict[V] {
+	return &Dict[V]{
+		seed:      maphash.MakeSeed(),
+		rehashIdx: -1,
+	}
+}
+
+func (d *Dict[V]) Len() int {
+	return d.tables[0].used + d.tables[1].used
+}
+
+func (d *Dict[V]) isRehashing() bool {
+	return d.rehashIdx != -1
+}
+
+func (d *Dict[V]) hash(key string) uint64 {
+	return maphash.String(d.seed, key)
+}
+
+func (d *Dict[V]) find(key string) *entry[V] {
+	if d.Len() == 0 {
+		return nil
+	}
+	if d.isRehashing() {
+		d.rehash(1)
+	}
+	h := d.hash(key)
+	for idx := range d.tables {
+		t := &d.tables[idx]
+		if len(t.buckets) == 0 {
+			continue
+		}
+		for e := t.buckets[h&t.mask()]; e != nil; e = e.next {
+			if e.key == key {
+				return e
+			}
+		}
+		if !d.isRehashing() {
+			break
+		}
+	}
+	return nil
+}
+
+func (d *Dict[V]) Get(key string) (V, bool) {
+	if e := d.find(key); e != nil {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Find returns a pointer to the value of key to update it in place, nil when
+// key is missing. The pointer stays valid until key is deleted.
+func (d *Dict[V]) Find(key string) *V {
+	if e := d.find(key); e != nil {
+		return &e.value
+	}
+	return nil
+}
+
+// Set adds or replaces the value of key, it tells whether key was added
+func (d *Dict[V]) Set(key string, value V) bool {
+	if e := d.find(key); e != nil {
+		e.value = value
+		return false
+	}
+
+	d.expandIfNeeded()
+	// new entries go to the new table while rehashing
+	t := &d.tables[0]
+	if d.isRehashing() {
+		t = &d.tables[1]
+	}
+	idx := d.hash(key) & t.mask()
+	t.buckets[idx] = &entry[V]{key: key, value: value, next: t.buckets[idx]}
+	t.used += 1
+	return true
+}
+
+// Delete removes key and returns its value, if it existed
+func (d *Dict[V]) Delete(key string) (V, bool) {
+	var zero V
+	if d.Len() == 0 {
+		return zero, false
+	}
+	if d.isRehashing() {
+		d.rehash(1)
+	}
+
+	h := d.hash(key)
+	for idx := range d.tables {
+		t := &d.tables[idx]
+		if len(t.buckets) == 0 {
+			continue
+		}
+		for prev := &t.buckets[h&t.mask()]; *prev != nil; prev = &(*prev).next {
+			if e := *prev; e.key == key {
+				*prev = e.next
+				t.used -= 1
+				d.shrinkIfNeeded()
+				return e.value, true
+			}
+		}
+		if !d.isRehashing() {
+			break
+		}
+	}
+	return zero, false
+}
+
+// Buckets returns the number of buckets allocated, for memory introspection
+func (d *Dict[V]) Buckets() int {
+	return len(d.tables[0].buckets) + len(d.tables[1].buckets)
+}
+
+// Clear removes every entry and releases the tables
+func (d *Dict[V]) Clear() {
+	d.tables = [2]table[V]{}
+	d.rehashIdx = -1
+}
+
+// All iterates over every entry, d must not be modified during the iteration
+func (d *Dict[V]) All() iter.Seq2[string, V] {
+	return func(yield func(string, V) bool) {
+		for idx := range d.tables {
+			for _, e := range d.tables[idx].buckets {
+				for ; e != nil; e = e.next {
+					if !yield(e.key, e.value) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// RandomKey returns a random entry, entries in long chains are a bit less
+// likely to be picked like in Redis
+func (d *Dict[V]) RandomKey() (string, V, bool) {
+	if d.Len() == 0 {
+		var zero V
+		return "", zero, false
+	}
+	if d.isRehashing() {
+		d.rehash(1)
+	}
+
+	var head *entry[V]
+	if d.isRehashing() {
+		// buckets of tables[0] before rehashIdx are empty
+		size0 := len(d.tables[0].buckets)
+		total := size0 + len(d.tables[1].buckets)
+		for head == nil {
+			idx := d.rehashIdx + rand.IntN(total-d.rehashIdx)
+			if idx >= size0 {
+				head = d.tables[1].buckets[idx-size0]
+			} else {
+				head = d.tables[0].buckets[idx]
+			}
+		}
+	} else {
+		buckets := d.tables[0].buckets
+		for head == nil {
+			head = buckets[rand.IntN(len(buckets))]
+		}
+	}
+
+	length := 0
+	for e := head; e != nil; e = e.next {
+		length += 1
+	}
+	e := head
+	for range rand.IntN(length) {
+		e = e.next
+	}
+	return e.key, e.value, true
+}
+
+// Scan calls fn with the entries of the bucket pointed by cursor and returns
+// the cursor of the next call, 0 once the scan is complete. A full scan starts
+// at 0 and returns every entry present from its start to its end at least once,
+// even if the table grows or shrinks in between.
+//
+// The cursor is incremented on its reversed bits: buckets are visited by their
+// high bits first, so the buckets already visited in a table of one size are
+// also the ones visited in a table twice larger or smaller.
+//
+// fn must not modify d.
+func (d *Dict[V]) Scan(cursor uint64, fn func(key string, value V)) uint64 {
+	if d.Len() == 0 {
+		return 0
+	}
+
+	if !d.isRehashing() {
+		t0 := &d.tables[0]
+		m0 := t0.mask()
+		scanBucket(t0.buckets[cursor&m0], fn)
+		return nextCursor(cursor, m0)
+	}
+
+	t0, t1 := &d.tables[0], &d.tables[1]
+	if len(t0.buckets) > len(t1.buckets) {
+		t0, t1 = t1, t0
+	}
+	m0, m1 := t0.mask(), t1.mask()
+
+	scanBucket(t0.buckets[cursor&m0], fn)
+	// then every bucket of the larger table that the bucket of the smaller
+	// table expands to
+	for {
+		scanBucket(t1.buckets[cursor&m1], fn)
+		cursor = nextCursor(cursor, m1)
+		if cursor&(m0^m1) == 0 {
+			break
+		}
+	}
+	return cursor
+}
+
+// nextCursor increments the bits of cursor covered by mask in reverse order
+func nextCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor += 1
+	return bits.Reverse64(cursor)
+}
+
+func scanBucket[V any](e *entry[V], fn func(key string, value V)) {
+	for ; e != nil; e = e.next {
+		fn(e.key, e.value)
+	}
+}
+
+func (d *Dict[V]) expandIfNeeded() {
+	if d.isRehashing() {
+		return
+	}
+	t0 := &d.tables[0]
+	if len(t0.buckets) == 0 || t0.used >= len(t0.buckets) {
+		d.resize(t0.used + 1)
+	}
+}
+
+func (d *Dict[V]) shrinkIfNeeded() {
+	if d.isRehashing() {
+		return
+	}
+	t0 := &d.tables[0]
+	if size := len(t0.buckets); size > initialSize && t0.used*minFill <= size {
+		d.resize(t0.used)
+	}
+}
+
+// resize starts rehashing to the smallest power of 2 that holds minSize entries
+func (d *Dict[V]) resize(minSize int) {
+	size := initialSize
+	for size < minSize {
+		size *= 2
+	}
+	t0 := &d.tables[0]
+	if size == len(t0.buckets) {
+		return
+	}
+
+	t := table[V]{buckets: make([]*entry[V], size)}
+	// nothing to move
+	if t0.used == 0 {
+		d.tables[0] = t
+		return
+	}
+	d.tables[1] = t
+	d.rehashIdx = 0
+}
+
+// rehash moves n buckets of tables[0] to tables[1]
+func (d *Dict[V]) rehash(n int) {
+	emptyVisits := n * emptyVisitsPerStep
+	t0, t1 := &d.tables[0], &d.tables[1]
+	for ; n > 0 && t0.used != 0; n-- {
+		for t0.buckets[d.rehashIdx] == nil {
+			d.rehashIdx += 1
+			emptyVisits -= 1
+			if emptyVisits == 0 {
+				return
+			}
+		}
+		e := t0.buckets[d.rehashIdx]
+		for e != nil {
+			next := e.next
+			idx := d.hash(e.key) & t1.mask()
+			e.next = t1.buckets[idx]
+			t1.buckets[idx] = e
+			t0.used -= 1
+			t1.used += 1
+			e = next
+		}
+		t0.buckets[d.rehashIdx] = nil
+		d.rehashIdx += 1
+	}
+
+	if t0.used == 0 {
+		d.tables[0] = d.tables[1]
+		d.tables[1] = table[V]{}
+		d.rehashIdx = -1
+	}
+}
@@ -0,0 +1,89 @@
+package dict
+
+import (
+	"strconv"
+	"testing"
+)
+
+func Test_SetGetDelete(t *testing.T) {
+	d := New[int]()
+	for i := range 1000 {
+		if !d.Set(strconv.Itoa(i), i) {
+			t.Fatalf("expect %d to be added", i)
+		}
+	}
+	if d.Set("10", -10) {
+		t.Error("expect existing key to be replaced")
+	}
+	if v, ok := d.Get("10"); !ok || v != -10 {
+		t.Errorf("unexpected value %d %v", v, ok)
+	}
+	for i := range 1000 {
+		if _, ok := d.Delete(strconv.Itoa(i)); !ok {
+			t.Fatalf("expect %d to be deleted", i)
+		}
+	}
+	if d.Len() != 0 {
+		t.Errorf("expect empty dict, got %d entries", d.Len())
+	}
+	if _, _, ok := d.RandomKey(); ok {
+		t.Error("expect no random key in empty dict")
+	}
+}
+
+// every key present for the whole scan is returned, while the table grows then
+// shrinks under the scan
+func Test_ScanAcrossRehash(t *testing.T) {
+	d := New[int]()
+	stable := map[string]bool{}
+	for i := range 100 {
+		key := "stable:" + strconv.Itoa(i)
+		d.Set(key, i)
+		stable[key] = true
+	}
+
+	seen := map[string]bool{}
+	cursor := uint64(0)
+	for step := 0; ; step++ {
+		switch {
+		case step < 50:
+			for i := range 40 {
+				d.Set("grow:"+strconv.Itoa(step*40+i), 0)
+			}
+		case step < 100:
+			for i := range 40 {
+				d.Delete("grow:" + strconv.Itoa((step-50)*40+i))
+			}
+		}
+		cursor = d.Scan(cursor, func(key string, _ int) {
+			seen[key] = true
+		})
+		if cursor == 0 {
+			break
+		}
+	}
+
+	for key := range stable {
+		if !seen[key] {
+			t.Errorf("key %s not returned by scan", key)
+		}
+	}
+}
+
+func Test_RandomKey(t *testing.T) {
+	d := New[int]()
+	for i := range 10 {
+		d.Set(strconv.Itoa(i), i)
+	}
+	picked := map[string]bool{}
+	for range 1000 {
+		key, value, ok := d.RandomKey()
+		if !ok || key != strconv.Itoa(value) {
+			t.Fatalf("unexpected random entry %s %d %v", key, value, ok)
+		}
+		picked[key] = true
+	}
+	if len(picked) != 10 {
+		t.Errorf("expect every key to be picked, got %d", len(picked))
+	}
+}
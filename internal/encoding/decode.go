@@ -41,14 +41,19 @@ func readNumUntilCRLF(bufReader *bufio.Reader) (int64, error) {
 	return size, nil
 }
 
-func parseSymArray(bufReader *bufio.Reader) (types.RawCmd, error) {
+func parseSymArray(bufReader *bufio.Reader, sym types.Sym) (types.RawCmd, error) {
 	size, err := readNumUntilCRLF(bufReader)
 	if err != nil {
 		return types.RawCmd{}, fmt.Errorf("read array size failed: %w", err)
 	}
 
 	var cmd types.RawCmd
-	cmd.Sym = types.SymArray
+	cmd.Sym = sym
+	if size < 0 {
+		// null array of RESP2
+		cmd.Sym = types.SymNull
+		return cmd, nil
+	}
 	cmd.Array = make([]types.RawCmd, 0, size)
 
 	for i := range size {
@@ -62,13 +67,57 @@ func parseSymArray(bufReader *bufio.Reader) (types.RawCmd, error) {
 	return cmd, nil
 }
 
+func parseSymMap(bufReader *bufio.Reader) (types.RawCmd, error) {
+	size, err := readNumUntilCRLF(bufReader)
+	if err != nil {
+		return types.RawCmd{}, fmt.Errorf("read map size failed: %w", err)
+	}
+
+	var cmd types.RawCmd
+	cmd.Sym = types.SymMap
+	cmd.Map = make(map[*types.RawCmd]types.RawCmd, size)
+
+	for i := range size {
+		key, err := parseElement(bufReader)
+		if err != nil {
+			return types.RawCmd{}, fmt.Errorf("parse key at position %d failed: %w", i, err)
+		}
+		value, err := parseElement(bufReader)
+		if err != nil {
+			return types.RawCmd{}, fmt.Errorf("parse value at position %d failed: %w", i, err)
+		}
+		cmd.Map[&key] = value
+	}
+
+	return cmd, nil
+}
+
+func readBulkStringUntilCRLF(bufReader *bufio.Reader) (data string, isNull bool, err error) {
+	size, err := readNumUntilCRLF(bufReader)
+	if err != nil {
+		return "", false, fmt.Errorf("read string length failed: %w", err)
+	}
+	if size < 0 {
+		return "", true, nil
+	}
+	data, err = readStringUntilCRLF(bufReader, size)
+	return data, false, err
+}
+
 func readLengthAndStringUntilCRLF(bufReader *bufio.Reader) (string, error) {
 	size, err := readNumUntilCRLF(bufReader)
 	if err != nil {
 		return "", fmt.Errorf("read string length failed: %w", err)
 	}
+	return readStringUntilCRLF(bufReader, size)
+}
+
+func readStringUntilCRLF(bufReader *bufio.Reader, size int64) (string, error) {
+	if size < 0 {
+		return "", fmt.Errorf("invalid string length %d", size)
+	}
 	buffer := make([]byte, size)
-	_, err = io.ReadFull(bufReader, buffer)
+	_, err := io.ReadFull(bufReader, buffer)
 	if err != nil {
 		return "", fmt.Errorf("read string data failed: %w", err)
 	}
@@ -121,11 +170,21 @@ func parseElement(bufReader *bufio.Reader) (types.RawCmd, error) {
 			return cmd, newErr("read simple error data", err)
 		}
 		cmd.Error = string(data)
+	case types.SymInteger:
+		data, err := readNumUntilCRLF(bufReader)
+		if err != nil {
+			return cmd, newErr("read integer data", err)
+		}
+		cmd.Integer = data
 	case types.SymBulkString:
-		data, err := readLengthAndStringUntilCRLF(bufReader)
+		data, isNull, err := readBulkStringUntilCRLF(bufReader)
 		if err != nil {
 			return cmd, newErr("read bulk string data", err)
 		}
+		if isNull {
+			// null bulk string of RESP2
+			cmd.Sym = types.SymNull
+		}
 		cmd.BulkString = data
 	case types.SymBulkError:
 		data, err := readLengthAndStringUntilCRLF(bufReader)
@@ -133,8 +192,13 @@ func parseElement(bufReader *bufio.Reader) (types.RawCmd, error) {
 			return cmd, newErr("read bulk error data", err)
 		}
 		cmd.BulkError = data
-	case types.SymArray:
-		cmd, err = parseSymArray(bufReader)
+	case types.SymArray, types.SymPush:
+		cmd, err = parseSymArray(bufReader, sym)
+		if err != nil {
+			return cmd, err
+		}
+	case types.SymMap:
+		cmd, err = parseSymMap(bufReader)
 		if err != nil {
 			return cmd, err
 		}
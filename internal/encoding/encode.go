@@ -7,16 +7,29 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/pkg/types"
 )
 
+// Protocol is the RESP version negotiated with HELLO, it decides how RESP3 only
+// types are written.
+type Protocol int
+
+const (
+	ProtocolRESP2 Protocol = 2
+	ProtocolRESP3 Protocol = 3
+)
+
 func MarshalCommand(cmd types.RawCmd) ([]byte, error) {
+	return MarshalCommandProtocol(cmd, ProtocolRESP2)
+}
+
+func MarshalCommandProtocol(cmd types.RawCmd, protocol Protocol) ([]byte, error) {
 	var buffer bytes.Buffer
-	err := buildRESPBytes(cmd, &buffer)
+	err := buildRESPBytes(cmd, &buffer, protocol)
 	if err != nil {
 		return nil, err
 	}
 	return buffer.Bytes(), nil
 }
 
-func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer) error {
+func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer, protocol Protocol) error {
 	newErr := func(step string, inner error) *EncodingError {
 		return newMarshalError(cmd, step, inner)
 	}
@@ -24,7 +37,7 @@ func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer) error {
 	var err error
 
 	// Trick to handle Null on RESP2
-	if cmd.Sym == types.SymNull {
+	if cmd.Sym == types.SymNull && protocol == ProtocolRESP2 {
 		err = buffer.WriteByte(byte(types.SymBulkString))
 		if err != nil {
 			return newErr("write symbol", err)
@@ -38,7 +51,16 @@ func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer) error {
 		return nil
 	}
 
-	err = buffer.WriteByte(byte(cmd.Sym))
+	sym := cmd.Sym
+	if protocol == ProtocolRESP2 {
+		// RESP2 has no map and push types, they are sent as flat arrays
+		switch sym {
+		case types.SymMap, types.SymPush:
+			sym = types.SymArray
+		}
+	}
+
+	err = buffer.WriteByte(byte(sym))
 	if err != nil {
 		return newErr("write symbol", err)
 	}
@@ -83,7 +105,7 @@ func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer) error {
 		if _, err = buffer.Write(CRLF); err != nil {
 			return newErr("write CRLF", err)
 		}
-	case types.SymArray:
+	case types.SymArray, types.SymPush:
 		if _, err = fmt.Fprint(buffer, len(cmd.Array)); err != nil {
 			return newErr("write length", err)
 		}
@@ -91,10 +113,29 @@ func buildRESPBytes(cmd types.RawCmd, buffer *bytes.Buffer) error {
 			return newErr("write length CRLF", err)
 		}
 		for _, elem := range cmd.Array {
-			if err = buildRESPBytes(elem, buffer); err != nil {
+			if err = buildRESPBytes(elem, buffer, protocol); err != nil {
 				return newErr("write element", err)
 			}
 		}
+	case types.SymMap:
+		length := len(cmd.Map)
+		if sym == types.SymArray {
+			length *= 2
+		}
+		if _, err = fmt.Fprint(buffer, length); err != nil {
+			return newErr("write length", err)
+		}
+		if _, err = buffer.Write(CRLF); err != nil {
+			return newErr("write length CRLF", err)
+		}
+		for _, key := range types.SortedMapKeys(cmd.Map) {
+			if err = buildRESPBytes(*key, buffer, protocol); err != nil {
+				return newErr("write key", err)
+			}
+			if err = buildRESPBytes(cmd.Map[key], buffer, protocol); err != nil {
+				return newErr("write value", err)
+			}
+		}
 	default:
 		panic(fmt.Sprintf("unknown symbol type %c", cmd.Sym))
 	}
@@ -0,0 +1,123 @@
+package glob
+
+// Match reports whether s matches the glob-style pattern, with the same rules
+// as Redis:
+//   - `*` matches any sequence of characters, including none
+//   - `?` matches exactly one character
+//   - `[abc]`, `[a-z]` and `[^a]` match one character of (or not of) a set
+//   - `\` escapes the next character
+func Match(pattern, s string) bool {
+	return match(pattern, s, false)
+}
+
+// MatchNoCase is like Match but ignores ASCII case
+func MatchNoCase(pattern, s string) bool {
+	return match(pattern, s, true)
+}
+
+func match(pattern, s string, nocase bool) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// consecutive stars are the same as one
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := range len(s) + 1 {
+				if match(pattern[1:], s[i:], nocase) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchSet(pattern[1:], s[0], nocase)
+			if !matched {
+				return false
+			}
+			s = s[1:]
+			// pattern already points after the closing bracket
+			continue
+		case '\\':
+			if len(pattern) >= 2 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || !equalByte(pattern[0], s[0], nocase) {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(s) == 0
+}
+
+// matchSet matches c against the set starting right after `[`, it returns the
+// rest of the pattern after the closing bracket
+func matchSet(pattern string, c byte, nocase bool) (bool, string) {
+	not := len(pattern) > 0 && pattern[0] == '^'
+	if not {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if equalByte(pattern[1], c, nocase) {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			if nocase {
+				start, end, c = toLower(start), toLower(end), toLower(c)
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if equalByte(pattern[0], c, nocase) {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	// an unterminated set is closed by the end of the pattern
+	if len(pattern) > 0 {
+		pattern = pattern[1:]
+	}
+
+	return matched != not, pattern
+}
+
+func equalByte(a, b byte, nocase bool) bool {
+	if nocase {
+		return toLower(a) == toLower(b)
+	}
+	return a == b
+}
+
+func toLower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,51 @@
+package glob
+
+import "testing"
+
+func Test_Match(t *testing.T) {
+	cases := []struct {
+		pattern string
+		s       string
+		matched bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hllo", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{`[\]]`, "]", true},
+		{"user:*:name", "user:1000:name", true},
+		{"user:*:name", "user:1000:age", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"[abc", "a", true},
+		{"maxmemory*", "maxmemory-policy", true},
+	}
+	for _, c := range cases {
+		if matched := Match(c.pattern, c.s); matched != c.matched {
+			t.Errorf("Match(%q, %q) = %v, expected %v", c.pattern, c.s, matched, c.matched)
+		}
+	}
+}
+
+func Test_MatchNoCase(t *testing.T) {
+	if !MatchNoCase("MAXMEMORY*", "maxmemory-policy") {
+		t.Error("expect case insensitive match")
+	}
+	if !MatchNoCase("[A-C]at", "bat") {
+		t.Error("expect case insensitive range match")
+	}
+	if Match("MAXMEMORY*", "maxmemory-policy") {
+		t.Error("expect case sensitive mismatch")
+	}
+}
@@ -0,0 +1,121 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+const version = 11
+
+const (
+	opcodeAux          byte = 0xFA
+	opcodeResizeDB     byte = 0xFB
+	opcodeExpireTimeMs byte = 0xFC
+	opcodeSelectDB     byte = 0xFE
+	opcodeEOF          byte = 0xFF
+
+	typeString byte = 0
+	typeList   byte = 1
+)
+
+// Writer writes a snapshot in the RDB format understood by Redis
+type Writer struct {
+	w   io.Writer
+	crc uint64
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// WriteHeader writes the magic string, the version and the aux fields
+func (w *Writer) WriteHeader(aux map[string]string) error {
+	w.write([]byte(fmt.Sprintf("REDIS%04d", version)))
+	for key, value := range aux {
+		w.write([]byte{opcodeAux})
+		w.writeString(key)
+		w.writeString(value)
+	}
+	return w.err
+}
+
+func (w *Writer) WriteSelectDB(db, size, expiresSize int) error {
+	w.write([]byte{opcodeSelectDB})
+	w.writeLength(uint64(db))
+	w.write([]byte{opcodeResizeDB})
+	w.writeLength(uint64(size))
+	w.writeLength(uint64(expiresSize))
+	return w.err
+}
+
+// WriteString writes a string key, a zero expireAt means the key does not expire
+func (w *Writer) WriteString(key, value string, expireAt time.Time) error {
+	w.writeKey(typeString, key, expireAt)
+	w.writeString(value)
+	return w.err
+}
+
+// WriteList writes a list key, a zero expireAt means the key does not expire
+func (w *Writer) WriteList(key string, values []string, expireAt time.Time) error {
+	w.writeKey(typeList, key, expireAt)
+	w.writeLength(uint64(len(values)))
+	for _, value := range values {
+		w.writeString(value)
+	}
+	return w.err
+}
+
+// WriteEnd writes the end of file marker followed by the checksum
+func (w *Writer) WriteEnd() error {
+	w.write([]byte{opcodeEOF})
+	var checksum [8]byte
+	binary.LittleEndian.PutUint64(checksum[:], w.crc)
+	w.write(checksum[:])
+	return w.err
+}
+
+func (w *Writer) writeKey(valueType byte, key string, expireAt time.Time) {
+	if !expireAt.IsZero() {
+		var ms [8]byte
+		binary.LittleEndian.PutUint64(ms[:], uint64(expireAt.UnixMilli()))
+		w.write([]byte{opcodeExpireTimeMs})
+		w.write(ms[:])
+	}
+	w.write([]byte{valueType})
+	w.writeString(key)
+}
+
+func (w *Writer) writeLength(length uint64) {
+	switch {
+	case length < 1<<6:
+		w.write([]byte{byte(length)})
+	case length < 1<<14:
+		w.write([]byte{0x40 | byte(length>>8), byte(length)})
+	case length <= 0xFFFFFFFF:
+		var b [5]byte
+		b[0] = 0x80
+		binary.BigEndian.PutUint32(b[1:], uint32(length))
+		w.write(b[:])
+	default:
+		var b [9]byte
+		b[0] = 0x81
+		binary.BigEndian.PutUint64(b[1:], length)
+		w.write(b[:])
+	}
+}
+
+func (w *Writer) writeString(s string) {
+	w.writeLength(uint64(len(s)))
+	w.write([]byte(s))
+}
+
+func (w *Writer) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	w.crc = crc64Update(w.crc, data)
+	_, w.err = w.w.Write(data)
+}
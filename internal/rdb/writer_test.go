@@ -0,0 +1,61 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func Test_crc64Update(t *testing.T) {
+	// test vector from the Redis source
+	if crc := crc64Update(0, []byte("123456789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Errorf("unexpected checksum %x", crc)
+	}
+}
+
+func Test_writeLength(t *testing.T) {
+	cases := []struct {
+		length   uint64
+		expected []byte
+	}{
+		{10, []byte{0x0A}},
+		{700, []byte{0x42, 0xBC}},
+		{17000, []byte{0x80, 0x00, 0x00, 0x42, 0x68}},
+		{1 << 33, []byte{0x81, 0, 0, 0, 0x02, 0, 0, 0, 0}},
+	}
+	for _, c := range cases {
+		var buffer bytes.Buffer
+		w := NewWriter(&buffer)
+		w.writeLength(c.length)
+		if !bytes.Equal(c.expected, buffer.Bytes()) {
+			t.Errorf("length %d: expected %x, got %x", c.length, c.expected, buffer.Bytes())
+		}
+	}
+}
+
+func Test_Writer(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewWriter(&buffer)
+	_ = w.WriteHeader(nil)
+	_ = w.WriteSelectDB(0, 2, 1)
+	_ = w.WriteString("k", "v", time.UnixMilli(0x0102))
+	_ = w.WriteList("l", []string{"a"}, time.Time{})
+	if err := w.WriteEnd(); err != nil {
+		t.Fatal("write failed:", err)
+	}
+
+	expected := []byte("REDIS0011")
+	expected = append(expected, 0xFE, 0x00, 0xFB, 0x02, 0x01)
+	expected = append(expected, 0xFC, 0x02, 0x01, 0, 0, 0, 0, 0, 0, 0x00, 0x01, 'k', 0x01, 'v')
+	expected = append(expected, 0x01, 0x01, 'l', 0x01, 0x01, 'a')
+	expected = append(expected, 0xFF)
+
+	data := buffer.Bytes()
+	if !bytes.Equal(expected, data[:len(data)-8]) {
+		t.Errorf("expected %x\n     got %x", expected, data[:len(data)-8])
+	}
+	if crc, written := crc64Update(0, data[:len(data)-8]), binary.LittleEndian.Uint64(data[len(data)-8:]); crc != written {
+		t.Errorf("expected checksum %x, got %x", crc, written)
+	}
+}
@@ -7,3 +7,119 @@ type PING struct {
 type ECHO struct {
 	Message string `arg:"pos:1"`
 }
+
+type SELECT struct {
+	Index int `arg:"pos:1"`
+}
+
+type HELLO struct {
+	Protover *int `arg:"pos:1,optional"`
+
+	AUTH *struct {
+		Username string `arg:"pos:1"`
+		Password string `arg:"pos:2"`
+	}
+	SETNAME *string `arg:",display:clientname"`
+}
+
+type CLIENT_TRACKING struct {
+	Status string `arg:"pos:1"`
+
+	REDIRECT *int64 `arg:",display:client-id"`
+	PREFIX   []string
+	BCAST    bool
+	OPTIN    bool
+	OPTOUT   bool
+	NOLOOP   bool
+}
+
+type CLIENT_CACHING struct {
+	Mode string `arg:"pos:1"`
+}
+
+type CLIENT_GETREDIR struct {
+}
+
+type CLIENT_ID struct {
+}
+
+type CLIENT_SETNAME struct {
+	Name string `arg:"pos:1"`
+}
+
+type CLIENT_GETNAME struct {
+}
+
+type CLIENT_LIST struct {
+	TYPE *string `arg:",display:client-type"`
+	ID   []int64 `arg:",variadic,display:client-id"`
+}
+
+type CLIENT_INFO struct {
+}
+
+type CLIENT_KILL struct {
+	ID     *int64
+	ADDR   *string
+	LADDR  *string
+	USER   *string
+	SKIPME *string
+	MAXAGE *int64
+	TYPE   *string
+}
+
+type CLIENT_PAUSE struct {
+	TimeoutMillisecond int64 `arg:"pos:1"`
+
+	Mode struct {
+		Key   string `arg:"enum-key"`
+		WRITE bool
+		ALL   bool
+	} `arg:"enum"`
+}
+
+type CLIENT_UNPAUSE struct {
+}
+
+type CLIENT_NOEVICT struct {
+	Mode string `arg:"pos:1"`
+}
+
+type CLIENT_REPLY struct {
+	Mode string `arg:"pos:1"`
+}
+
+type CLIENT_UNBLOCK struct {
+	ID int64 `arg:"pos:1"`
+
+	Mode struct {
+		Key     string `arg:"enum-key"`
+		TIMEOUT bool
+		ERROR   bool
+	} `arg:"enum"`
+}
+
+type AUTH struct {
+	// the password of the default user when alone
+	UsernameOrPassword string  `arg:"pos:1"`
+	Password           *string `arg:"pos:2,optional"`
+}
+
+// CLIENT leaves the arguments of KILL to its handler
+type CLIENT struct {
+	TRACKING *CLIENT_TRACKING `arg:"sub"`
+	CACHING  *CLIENT_CACHING  `arg:"sub"`
+	GETREDIR *CLIENT_GETREDIR `arg:"sub"`
+	ID       *CLIENT_ID       `arg:"sub"`
+	SETNAME  *CLIENT_SETNAME  `arg:"sub"`
+	GETNAME  *CLIENT_GETNAME  `arg:"sub"`
+	LIST     *CLIENT_LIST     `arg:"sub"`
+	INFO     *CLIENT_INFO     `arg:"sub"`
+	KILL     []string         `arg:"sub"`
+	PAUSE    *CLIENT_PAUSE    `arg:"sub"`
+	UNPAUSE  *CLIENT_UNPAUSE  `arg:"sub"`
+	UNBLOCK  *CLIENT_UNBLOCK  `arg:"sub"`
+	NOEVICT  *CLIENT_NOEVICT  `arg:"sub:NO-EVICT"`
+	REPLY    *CLIENT_REPLY    `arg:"sub"`
+	HELP     *HELP            `arg:"sub"`
+}
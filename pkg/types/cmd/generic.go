@@ -3,3 +3,169 @@ package cmd
 type TYPE struct {
 	Key string `arg:"pos:1"`
 }
+
+type EXPIRE struct {
+	Key     string `arg:"pos:1"`
+	Seconds int64  `arg:"pos:2"`
+
+	NX bool
+	XX bool
+	GT bool
+	LT bool
+}
+
+type PEXPIRE struct {
+	Key          string `arg:"pos:1"`
+	Milliseconds int64  `arg:"pos:2"`
+
+	NX bool
+	XX bool
+	GT bool
+	LT bool
+}
+
+type EXPIREAT struct {
+	Key             string `arg:"pos:1"`
+	UnixTimeSeconds int64  `arg:"pos:2"`
+
+	NX bool
+	XX bool
+	GT bool
+	LT bool
+}
+
+type PEXPIREAT struct {
+	Key                  string `arg:"pos:1"`
+	UnixTimeMilliseconds int64  `arg:"pos:2"`
+
+	NX bool
+	XX bool
+	GT bool
+	LT bool
+}
+
+type TTL struct {
+	Key string `arg:"pos:1"`
+}
+
+type PTTL struct {
+	Key string `arg:"pos:1"`
+}
+
+type EXPIRETIME struct {
+	Key string `arg:"pos:1"`
+}
+
+type PEXPIRETIME struct {
+	Key string `arg:"pos:1"`
+}
+
+type PERSIST struct {
+	Key string `arg:"pos:1"`
+}
+
+type DEL struct {
+	Key     string   `arg:"pos:1"`
+	KeyRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type UNLINK struct {
+	Key     string   `arg:"pos:1"`
+	KeyRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type EXISTS struct {
+	Key     string   `arg:"pos:1"`
+	KeyRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type TOUCH struct {
+	Key     string   `arg:"pos:1"`
+	KeyRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type KEYS struct {
+	Pattern string `arg:"pos:1"`
+}
+
+type RENAME struct {
+	Key    string `arg:"pos:1"`
+	NewKey string `arg:"pos:2"`
+}
+
+type RENAMENX struct {
+	Key    string `arg:"pos:1"`
+	NewKey string `arg:"pos:2"`
+}
+
+type COPY struct {
+	Source      string `arg:"pos:1"`
+	Destination string `arg:"pos:2"`
+
+	DB      *int `arg:",display:destination-db"`
+	REPLACE bool
+}
+
+type MOVE struct {
+	Key string `arg:"pos:1"`
+	DB  int    `arg:"pos:2"`
+}
+
+type SWAPDB struct {
+	Index1 int `arg:"pos:1"`
+	Index2 int `arg:"pos:2"`
+}
+
+type RANDOMKEY struct {
+}
+
+type DBSIZE struct {
+}
+
+type FLUSHDB struct {
+	Mode struct {
+		Key   string `arg:"enum-key"`
+		ASYNC bool
+		SYNC  bool
+	} `arg:"enum"`
+}
+
+type FLUSHALL struct {
+	Mode struct {
+		Key   string `arg:"enum-key"`
+		ASYNC bool
+		SYNC  bool
+	} `arg:"enum"`
+}
+
+type SCAN struct {
+	Cursor string `arg:"pos:1"`
+
+	MATCH *string `arg:",display:pattern"`
+	COUNT *int
+	TYPE  *string
+}
+
+type OBJECT_ENCODING struct {
+	Key string `arg:"pos:1"`
+}
+
+type OBJECT_IDLETIME struct {
+	Key string `arg:"pos:1"`
+}
+
+type OBJECT_FREQ struct {
+	Key string `arg:"pos:1"`
+}
+
+type OBJECT_REFCOUNT struct {
+	Key string `arg:"pos:1"`
+}
+
+type OBJECT struct {
+	ENCODING *OBJECT_ENCODING `arg:"sub"`
+	IDLETIME *OBJECT_IDLETIME `arg:"sub"`
+	FREQ     *OBJECT_FREQ     `arg:"sub"`
+	REFCOUNT *OBJECT_REFCOUNT `arg:"sub"`
+	HELP     *HELP            `arg:"sub"`
+}
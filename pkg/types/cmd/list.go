@@ -1,5 +1,7 @@
 package cmd
 
+import "github.com/codecrafters-io/redis-starter-go/internal/argsparser"
+
 type LLEN struct {
 	Key string `arg:"pos:1"`
 }
@@ -31,7 +33,7 @@ type RPOP struct {
 }
 
 type BLPOP struct {
-	Key           string   `arg:"pos:1"`
-	KeyRest       []string `arg:"pos:2,variadic"`
-	TimeoutSecond float64  `arg:"pos:3"`
+	Key     string             `arg:"pos:1"`
+	KeyRest []string           `arg:"pos:2,variadic,optional"`
+	Timeout argsparser.Timeout `arg:"pos:3"`
 }
@@ -0,0 +1,15 @@
+package cmd
+
+type SUBSCRIBE struct {
+	Channel     string   `arg:"pos:1"`
+	ChannelRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type UNSUBSCRIBE struct {
+	Channels []string `arg:"pos:1,variadic,optional"`
+}
+
+type PUBLISH struct {
+	Channel string `arg:"pos:1"`
+	Message string `arg:"pos:2"`
+}
@@ -0,0 +1,166 @@
+package cmd
+
+type SHUTDOWN struct {
+	SaveMode struct {
+		Key    string `arg:"enum-key"`
+		NOSAVE bool
+		SAVE   bool
+	} `arg:"enum"`
+
+	NOW   bool
+	FORCE bool
+	ABORT bool
+}
+
+type CONFIG_GET struct {
+	Pattern     string   `arg:"pos:1"`
+	PatternRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type CONFIG_SET struct {
+	Pairs []struct {
+		Parameter string `arg:"pos:1"`
+		Value     string `arg:"pos:2"`
+	} `arg:"pos:1,variadic"`
+}
+
+type CONFIG_REWRITE struct {
+}
+
+type CONFIG_RESETSTAT struct {
+}
+
+type MEMORY_USAGE struct {
+	Key string `arg:"pos:1"`
+
+	SAMPLES *int
+}
+
+type MEMORY_STATS struct {
+}
+
+type MEMORY_DOCTOR struct {
+}
+
+type MEMORY_PURGE struct {
+}
+
+type INFO struct {
+	Sections []string `arg:"pos:1,variadic,optional"`
+}
+
+type ACL_SETUSER struct {
+	Username string   `arg:"pos:1"`
+	Rules    []string `arg:"pos:2,variadic,optional"`
+}
+
+type ACL_GETUSER struct {
+	Username string `arg:"pos:1"`
+}
+
+type ACL_DELUSER struct {
+	Username     string   `arg:"pos:1"`
+	UsernameRest []string `arg:"pos:2,variadic,optional"`
+}
+
+type ACL_LIST struct {
+}
+
+type ACL_USERS struct {
+}
+
+type ACL_WHOAMI struct {
+}
+
+type ACL_CAT struct {
+	Category *string `arg:"pos:1,optional"`
+}
+
+type ACL_GENPASS struct {
+	Bits int `arg:"pos:1,default:256,optional"`
+}
+
+type ACL_LOG struct {
+	// a count of entries or RESET
+	CountOrReset *string `arg:"pos:1,optional"`
+}
+
+type ACL_DRYRUN struct {
+	Username string   `arg:"pos:1"`
+	Command  string   `arg:"pos:2"`
+	Args     []string `arg:"pos:3,variadic,optional"`
+}
+
+type ACL_LOAD struct {
+}
+
+type ACL_SAVE struct {
+}
+
+type COMMAND_COUNT struct {
+}
+
+type COMMAND_LIST struct {
+	FILTERBY *struct {
+		Filter string `arg:"pos:1"`
+		Value  string `arg:"pos:2"`
+	}
+}
+
+type COMMAND_INFO struct {
+	CommandNames []string `arg:"pos:1,variadic,optional"`
+}
+
+type COMMAND_DOCS struct {
+	CommandNames []string `arg:"pos:1,variadic,optional"`
+}
+
+type COMMAND_GETKEYS struct {
+	Command string   `arg:"pos:1"`
+	Args    []string `arg:"pos:2,variadic,optional"`
+}
+
+// HELP is the subcommand of container commands listing their subcommands
+type HELP struct {
+}
+
+type CONFIG struct {
+	GET       *CONFIG_GET       `arg:"sub"`
+	SET       *CONFIG_SET       `arg:"sub"`
+	REWRITE   *CONFIG_REWRITE   `arg:"sub"`
+	RESETSTAT *CONFIG_RESETSTAT `arg:"sub"`
+	HELP      *HELP             `arg:"sub"`
+}
+
+type MEMORY struct {
+	USAGE  *MEMORY_USAGE  `arg:"sub"`
+	STATS  *MEMORY_STATS  `arg:"sub"`
+	DOCTOR *MEMORY_DOCTOR `arg:"sub"`
+	PURGE  *MEMORY_PURGE  `arg:"sub"`
+	HELP   *HELP          `arg:"sub"`
+}
+
+type ACL struct {
+	SETUSER *ACL_SETUSER `arg:"sub"`
+	GETUSER *ACL_GETUSER `arg:"sub"`
+	DELUSER *ACL_DELUSER `arg:"sub"`
+	LIST    *ACL_LIST    `arg:"sub"`
+	USERS   *ACL_USERS   `arg:"sub"`
+	WHOAMI  *ACL_WHOAMI  `arg:"sub"`
+	CAT     *ACL_CAT     `arg:"sub"`
+	GENPASS *ACL_GENPASS `arg:"sub"`
+	LOG     *ACL_LOG     `arg:"sub"`
+	DRYRUN  *ACL_DRYRUN  `arg:"sub"`
+	LOAD    *ACL_LOAD    `arg:"sub"`
+	SAVE    *ACL_SAVE    `arg:"sub"`
+	HELP    *HELP        `arg:"sub"`
+}
+
+type COMMAND struct {
+	COUNT   *COMMAND_COUNT   `arg:"sub"`
+	LIST    *COMMAND_LIST    `arg:"sub"`
+	INFO    *COMMAND_INFO    `arg:"sub"`
+	DOCS    *COMMAND_DOCS    `arg:"sub"`
+	GETKEYS *COMMAND_GETKEYS `arg:"sub"`
+	HELP    *HELP            `arg:"sub"`
+}
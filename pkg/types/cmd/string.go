@@ -15,16 +15,16 @@ type SET struct {
 		Key string `arg:"enum-key"`
 		NX  bool
 		XX  bool
-	} `arg:"enum"`
+	} `arg:"enum,display:condition"`
 
 	Expire struct {
 		Key     string `arg:"enum-key"`
-		EX      int
-		PX      int
-		EXAT    int
-		PXAT    int
+		EX      int    `arg:",display:seconds"`
+		PX      int    `arg:",display:milliseconds"`
+		EXAT    int    `arg:",display:unix-time-seconds"`
+		PXAT    int    `arg:",display:unix-time-milliseconds"`
 		KEEPTTL bool
-	} `arg:"enum"`
+	} `arg:"enum,display:expiration"`
 }
 
 type GET struct {
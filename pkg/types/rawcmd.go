@@ -1,5 +1,10 @@
 package types
 
+import (
+	"slices"
+	"strings"
+)
+
 type RawCmd struct {
 	Sym Sym
 
@@ -17,7 +22,8 @@ type RawCmd struct {
 	Attribute map[*RawCmd]RawCmd
 	Set       map[*RawCmd]bool
 
-	// TODO: VerbatimStrings, Pushes, BigNumber
+	// TODO: VerbatimStrings, BigNumber
+	// Pushes reuse Array to store their elements
 }
 
 func NewNullRawCmd() RawCmd {
@@ -69,3 +75,43 @@ func NewBulkArrayBulkString(values []string) RawCmd {
 		Array: array,
 	}
 }
+
+func NewArrayRawCmd(values ...RawCmd) RawCmd {
+	return RawCmd{
+		Sym:   SymArray,
+		Array: values,
+	}
+}
+
+func NewPushRawCmd(values ...RawCmd) RawCmd {
+	return RawCmd{
+		Sym:   SymPush,
+		Array: values,
+	}
+}
+
+// NewMapRawCmd creates a map reply whose keys are bulk strings
+func NewMapRawCmd(values map[string]RawCmd) RawCmd {
+	m := make(map[*RawCmd]RawCmd, len(values))
+	for key, value := range values {
+		k := NewBulkStringRawCmd(key)
+		m[&k] = value
+	}
+	return RawCmd{
+		Sym: SymMap,
+		Map: m,
+	}
+}
+
+// SortedMapKeys returns the keys of a map reply ordered by their string content
+// so the map is always written in the same order.
+func SortedMapKeys(m map[*RawCmd]RawCmd) []*RawCmd {
+	keys := make([]*RawCmd, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b *RawCmd) int {
+		return strings.Compare(a.String+a.BulkString, b.String+b.BulkString)
+	})
+	return keys
+}